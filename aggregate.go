@@ -0,0 +1,107 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+// The aggregates below reduce a slice of *Decimal to one value in a single
+// pass. Each rounds only its final result, with c's precision and rounding,
+// and returns the conditions of the whole computation, checked against
+// c.Traps once. A nil element is an error.
+
+// Sum sets d to the sum of the elements of x, or to 0 if x is empty. It is
+// like SumSlice: the sum is exact until it is rounded to d.
+func (c *Context) Sum(d *Decimal, x []*Decimal) (Condition, error) {
+	return c.sumRounded(d, len(x), func(i int) *Decimal { return x[i] })
+}
+
+// Mean sets d to the arithmetic mean of the elements of x. The exact sum is
+// divided by len(x) with one rounding. The mean of an empty slice is NaN,
+// as is 0/0, with the DivisionUndefined condition.
+func (c *Context) Mean(d *Decimal, x []*Decimal) (Condition, error) {
+	sum := getDecimal()
+	defer putDecimal(sum)
+	res, err := c.sumExact(sum, len(x), func(i int) *Decimal { return x[i] })
+	if err != nil {
+		return 0, err
+	}
+	var n Decimal
+	n.SetInt64(int64(len(x)))
+	r, err := c.batchContext().Quo(d, sum, &n)
+	if err != nil {
+		return 0, err
+	}
+	return c.goError(res | r)
+}
+
+// Min sets d to the least element of x, rounded to c. Quiet NaNs are
+// ignored unless every element is a NaN, and a signaling NaN sets d to NaN
+// with the InvalidOperation condition, as in the GDA min operation. Of
+// elements that compare equal, such as 1.0 and 1.00, the one with the
+// smaller exponent is chosen, and -0 is chosen over 0. The minimum of an
+// empty slice is NaN with the InvalidOperation condition.
+func (c *Context) Min(d *Decimal, x []*Decimal) (Condition, error) {
+	return c.extremum(d, x, -1)
+}
+
+// Max sets d to the greatest element of x, rounded to c. It handles NaNs
+// as Min does. Of elements that compare equal, the one with the larger
+// exponent is chosen, and 0 is chosen over -0. The maximum of an empty
+// slice is NaN with the InvalidOperation condition.
+func (c *Context) Max(d *Decimal, x []*Decimal) (Condition, error) {
+	return c.extremum(d, x, 1)
+}
+
+// extremum implements Min (for sign -1) and Max (for sign 1).
+func (c *Context) extremum(d *Decimal, x []*Decimal, sign int) (Condition, error) {
+	var best, nan *Decimal
+	for i, e := range x {
+		if e == nil {
+			return 0, errNilElement(i)
+		}
+		switch e.Form {
+		case NaNSignaling:
+			_, res, err := c.setIfNaN(d, e)
+			return res, err
+		case NaN:
+			if nan == nil {
+				nan = e
+			}
+			continue
+		}
+		if best == nil {
+			best = e
+			continue
+		}
+		cmp := e.Cmp(best)
+		if cmp == 0 {
+			cmp = e.CmpTotal(best)
+		}
+		if cmp == sign {
+			best = e
+		}
+	}
+	switch {
+	case best != nil:
+		if best.Form != Finite {
+			d.Set(best)
+			return 0, nil
+		}
+		return c.goError(c.round(d, best))
+	case nan != nil:
+		d.Set(nan)
+		return 0, nil
+	}
+	d.Set(decimalNaN)
+	return c.goError(InvalidOperation)
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestAggregates(t *testing.T) {
+	ptrs := func(ss ...string) []*Decimal {
+		ds := make([]*Decimal, len(ss))
+		for i, s := range ss {
+			ds[i] = newDecimal(t, testCtx, s)
+		}
+		return ds
+	}
+	tests := []struct {
+		x                      []*Decimal
+		sum, mean              string
+		min, max               string
+		sumRes, minRes, maxRes Condition
+	}{
+		{
+			x:   nil,
+			sum: "0", mean: "NaN", min: "NaN", max: "NaN",
+			minRes: InvalidOperation, maxRes: InvalidOperation,
+		},
+		{
+			x:   ptrs("1", "2", "3", "4"),
+			sum: "10", mean: "2.5", min: "1", max: "4",
+		},
+		{
+			// A loop of Add calls at precision 5 loses the small terms.
+			x:   ptrs("10000", "0.4", "0.4", "0.4"),
+			sum: "10001", mean: "2500.3", min: "0.4", max: "10000",
+			sumRes: Inexact | Rounded,
+		},
+		{
+			x:   ptrs("1.0", "1.00", "-0", "0", "1"),
+			sum: "3.00", mean: "0.60", min: "-0", max: "1",
+		},
+		{
+			x:   ptrs("123456", "7"),
+			sum: "1.2346E+5", mean: "61732", min: "7", max: "1.2346E+5",
+			sumRes: Inexact | Rounded, maxRes: Inexact | Rounded,
+		},
+		{
+			x:   ptrs("NaN", "2", "NaN", "-Infinity"),
+			sum: "NaN", mean: "NaN", min: "-Infinity", max: "2",
+		},
+		{
+			x:   ptrs("NaN", "NaN"),
+			sum: "NaN", mean: "NaN", min: "NaN", max: "NaN",
+		},
+		{
+			x:   ptrs("1", "sNaN", "2"),
+			sum: "NaN", mean: "NaN", min: "NaN", max: "NaN",
+			sumRes: InvalidOperation, minRes: InvalidOperation, maxRes: InvalidOperation,
+		},
+		{
+			x:   ptrs("Infinity", "-Infinity"),
+			sum: "NaN", mean: "NaN", min: "-Infinity", max: "Infinity",
+			sumRes: InvalidOperation,
+		},
+	}
+	c := BaseContext.WithPrecision(5)
+	c.Traps = 0
+	for i, tc := range tests {
+		for _, op := range []struct {
+			name   string
+			f      func(d *Decimal, x []*Decimal) (Condition, error)
+			expect string
+			res    Condition
+		}{
+			{"Sum", c.Sum, tc.sum, tc.sumRes},
+			{"Min", c.Min, tc.min, tc.minRes},
+			{"Max", c.Max, tc.max, tc.maxRes},
+			{"Mean", c.Mean, tc.mean, ^Condition(0)},
+		} {
+			d := new(Decimal)
+			res, err := op.f(d, tc.x)
+			if err != nil {
+				t.Fatalf("%d: %s: %+v", i, op.name, err)
+			}
+			if s := d.String(); s != op.expect {
+				t.Errorf("%d: %s: expected %s, got %s", i, op.name, op.expect, s)
+			}
+			if op.res != ^Condition(0) && res != op.res {
+				t.Errorf("%d: %s: expected %s, got %s", i, op.name, op.res, res)
+			}
+		}
+	}
+}
+
+func TestAggregatesErrors(t *testing.T) {
+	c := BaseContext.WithPrecision(5)
+	x := []*Decimal{New(1, 0), nil}
+	for _, f := range []func(d *Decimal, x []*Decimal) (Condition, error){c.Sum, c.Mean, c.Min, c.Max} {
+		if _, err := f(new(Decimal), x); err == nil {
+			t.Error("expected error for nil element")
+		}
+	}
+
+	// Conditions are checked against Traps once, for the final result.
+	c.Traps |= Inexact
+	d := new(Decimal)
+	if _, err := c.Mean(d, []*Decimal{New(1, 0), New(1, 0), New(2, 0)}); err == nil {
+		t.Error("expected Inexact to be trapped")
+	}
+	if _, err := c.Mean(d, nil); err == nil {
+		t.Error("expected DivisionUndefined to be trapped")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// AvroDecimal describes an Avro decimal logical type, which stores a value
+// as a big-endian two's complement unscaled integer U in a bytes or fixed
+// field. The value is U × 10**-Scale and U is limited to Precision digits.
+type AvroDecimal struct {
+	Precision int32
+	Scale     int32
+}
+
+// validate returns an error if s is not a valid decimal schema with at most
+// maxPrecision digits.
+func (s AvroDecimal) validate(maxPrecision int32) error {
+	if s.Precision < 1 {
+		return errors.Errorf("avro: invalid precision %d", s.Precision)
+	}
+	if s.Scale < 0 || s.Scale > s.Precision {
+		return errors.Errorf("avro: invalid scale %d for precision %d", s.Scale, s.Precision)
+	}
+	if s.Precision > maxPrecision {
+		return errors.Errorf("avro: precision %d exceeds maximum %d of fixed size", s.Precision, maxPrecision)
+	}
+	return nil
+}
+
+// avroUnscaled sets b to the unscaled integer of x quantized to s's scale using
+// the rounding mode and traps of c.
+func (c *Context) avroUnscaled(b *big.Int, x *Decimal, s AvroDecimal) (Condition, error) {
+	d := new(Decimal)
+	res, err := c.WithPrecision(uint32(s.Precision)).Quantize(d, x, -s.Scale)
+	if err != nil {
+		return res, errors.Wrap(err, "avro")
+	}
+	if d.Form != Finite {
+		return res, errors.Errorf("avro: %s does not fit precision %d and scale %d", x, s.Precision, s.Scale)
+	}
+	if err := unscaledValue(b, d, s.Scale); err != nil {
+		return res, errors.Wrap(err, "avro")
+	}
+	return res, nil
+}
+
+// EncodeAvroBytes returns x encoded as the value of an Avro bytes field with
+// decimal schema s. x is first rescaled to s's scale using the rounding mode
+// of c; the returned Condition reports whether rounding occurred and c's
+// traps are applied to it. An error is returned if x is not finite or needs
+// more than s.Precision digits at that scale.
+func (c *Context) EncodeAvroBytes(x *Decimal, s AvroDecimal) ([]byte, Condition, error) {
+	if err := s.validate(s.Precision); err != nil {
+		return nil, 0, err
+	}
+	var b big.Int
+	res, err := c.avroUnscaled(&b, x, s)
+	if err != nil {
+		return nil, res, err
+	}
+	buf, err := twosComplement(&b, 0)
+	return buf, res, err
+}
+
+// EncodeAvroFixed is like EncodeAvroBytes but encodes the value of an Avro
+// fixed field of size bytes. An error is returned if s's precision cannot
+// always be held in size bytes.
+func (c *Context) EncodeAvroFixed(x *Decimal, s AvroDecimal, size int) ([]byte, Condition, error) {
+	if size < 1 {
+		return nil, 0, errors.Errorf("avro: invalid fixed size %d", size)
+	}
+	if err := s.validate(fixedLenPrecision(size)); err != nil {
+		return nil, 0, err
+	}
+	var b big.Int
+	res, err := c.avroUnscaled(&b, x, s)
+	if err != nil {
+		return nil, res, err
+	}
+	buf, err := twosComplement(&b, size)
+	return buf, res, err
+}
+
+// DecodeAvro sets d to the value of the bytes or fixed field buf with
+// decimal schema s, and returns d. Decoding is exact: d has exponent
+// -s.Scale. An error is returned if the value needs more than s.Precision
+// digits.
+func DecodeAvro(d *Decimal, buf []byte, s AvroDecimal) (*Decimal, error) {
+	if err := s.validate(s.Precision); err != nil {
+		return nil, err
+	}
+	b := setTwosComplement(new(big.Int), buf)
+	if NumDigits(b) > int64(s.Precision) {
+		return nil, errors.Errorf("avro: unscaled value %s exceeds precision %d", b, s.Precision)
+	}
+	d.Form = Finite
+	d.Negative = b.Sign() < 0
+	d.Coeff.Abs(b)
+	d.Exponent = -s.Scale
+	return d, nil
+}
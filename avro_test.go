@@ -0,0 +1,98 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestAvroDecimal(t *testing.T) {
+	tests := []struct {
+		x        string
+		rounding string
+		bytes    string
+		fixed    string
+		decoded  string
+		flags    Condition
+		err      bool
+	}{
+		{x: "0", bytes: "00", fixed: "0000", decoded: "0.00"},
+		{x: "1.23", bytes: "7b", fixed: "007b", decoded: "1.23"},
+		{x: "-1", bytes: "9c", fixed: "ff9c", decoded: "-1.00"},
+		{x: "1.28", bytes: "0080", fixed: "0080", decoded: "1.28"},
+		{x: "99.99", bytes: "270f", fixed: "270f", decoded: "99.99"},
+		{x: "1.235", rounding: RoundHalfEven, bytes: "7c", fixed: "007c", decoded: "1.24", flags: Inexact | Rounded},
+		{x: "-1.235", rounding: RoundDown, bytes: "85", fixed: "ff85", decoded: "-1.23", flags: Inexact | Rounded},
+		{x: "99.995", rounding: RoundHalfUp, err: true},
+		{x: "100", err: true},
+		{x: "Infinity", err: true},
+		{x: "NaN", err: true},
+	}
+	s := AvroDecimal{Precision: 4, Scale: 2}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			c := BaseContext.WithPrecision(0)
+			c.Rounding = tc.rounding
+			b, res, err := c.EncodeAvroBytes(x, s)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(b); h != tc.bytes {
+				t.Errorf("bytes: expected %s, got %s", tc.bytes, h)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+			f, _, err := c.EncodeAvroFixed(x, s, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := hex.EncodeToString(f); h != tc.fixed {
+				t.Errorf("fixed: expected %s, got %s", tc.fixed, h)
+			}
+			for _, buf := range [][]byte{b, f} {
+				d, err := DecodeAvro(new(Decimal), buf, s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d.String() != tc.decoded {
+					t.Errorf("expected decoded %s, got %s", tc.decoded, d)
+				}
+			}
+		})
+	}
+}
+
+func TestAvroDecimalValidation(t *testing.T) {
+	c := BaseContext.WithPrecision(0)
+	x := New(1, 0)
+	if _, _, err := c.EncodeAvroFixed(x, AvroDecimal{Precision: 5}, 2); err == nil {
+		t.Error("expected error for precision exceeding fixed size")
+	}
+	if _, _, err := c.EncodeAvroBytes(x, AvroDecimal{Precision: 2, Scale: 3}); err == nil {
+		t.Error("expected error for scale exceeding precision")
+	}
+	if _, err := DecodeAvro(new(Decimal), []byte{0x27, 0x10}, AvroDecimal{Precision: 4}); err == nil {
+		t.Error("expected error decoding value exceeding precision")
+	}
+}
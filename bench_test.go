@@ -103,3 +103,19 @@ func BenchmarkLn(b *testing.B) {
 		},
 	)
 }
+
+// BenchmarkLnAGM covers the precisions around lnAGMPrecision, where Ln
+// switches from Halley's iteration to the AGM.
+func BenchmarkLnAGM(b *testing.B) {
+	precision := []int{40, 75, 100, 150, 200}
+	scale := []int{-2, 2}
+	digits := []int{10}
+	runBenches(
+		b, precision, scale, digits,
+		func(b *testing.B, ctx *Context, x *Decimal) {
+			if _, err := ctx.Ln(&Decimal{}, x); err != nil {
+				b.Fatal(err)
+			}
+		},
+	)
+}
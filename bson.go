@@ -0,0 +1,83 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// BSON stores decimal values as IEEE 754-2008 decimal128 in the binary
+// integer decimal encoding. The functions below convert to the forms used
+// by the MongoDB Go driver without depending on it: the high and low 64
+// bits taken by primitive.NewDecimal128 and returned by
+// primitive.Decimal128.GetBytes, and the 16 byte little-endian value used
+// by bson.ValueMarshaler and bson.ValueUnmarshaler. A type embedding Decimal
+// can implement those interfaces with:
+//
+//     func (d *MyDecimal) MarshalBSONValue() (bsontype.Type, []byte, error) {
+//         b, _, err := apd.BaseContext.EncodeBSONDecimal128Value(&d.Decimal)
+//         return bsontype.Decimal128, b, err
+//     }
+//
+//     func (d *MyDecimal) UnmarshalBSONValue(t bsontype.Type, b []byte) error {
+//         if t != bsontype.Decimal128 {
+//             return fmt.Errorf("cannot decode %s into a decimal", t)
+//         }
+//         _, err := apd.DecodeBSONDecimal128Value(&d.Decimal, b)
+//         return err
+//     }
+
+// EncodeBSONDecimal128 returns the high and low 64 bits of x encoded as a
+// BSON decimal128. x is rounded to 34 digits using the rounding mode of c,
+// and its exponent is clamped to the decimal128 range as described in
+// EncodeBID128.
+func (c *Context) EncodeBSONDecimal128(x *Decimal) (hi, lo uint64, res Condition, err error) {
+	v, res, err := decimal128Format.encodeBID(c, x)
+	lo = v.Uint64()
+	hi = new(big.Int).Rsh(v, 64).Uint64()
+	return hi, lo, res, err
+}
+
+// DecodeBSONDecimal128 returns the value of the BSON decimal128 with the
+// given high and low 64 bits.
+func DecodeBSONDecimal128(hi, lo uint64) *Decimal {
+	v := new(big.Int).SetUint64(hi)
+	v.Lsh(v, 64)
+	v.Or(v, new(big.Int).SetUint64(lo))
+	return decimal128Format.decodeBID(new(Decimal), v)
+}
+
+// EncodeBSONDecimal128Value is like EncodeBSONDecimal128 but returns the
+// 16 byte little-endian value stored in a BSON document.
+func (c *Context) EncodeBSONDecimal128Value(x *Decimal) ([]byte, Condition, error) {
+	hi, lo, res, err := c.EncodeBSONDecimal128(x)
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, lo)
+	binary.LittleEndian.PutUint64(b[8:], hi)
+	return b, res, err
+}
+
+// DecodeBSONDecimal128Value sets d to the value of the 16 byte
+// little-endian BSON decimal128 value b, and returns d.
+func DecodeBSONDecimal128Value(d *Decimal, b []byte) (*Decimal, error) {
+	if len(b) != 16 {
+		return nil, errors.Errorf("bson: decimal128 value has %d bytes, expected 16", len(b))
+	}
+	d.Set(DecodeBSONDecimal128(binary.LittleEndian.Uint64(b[8:]), binary.LittleEndian.Uint64(b)))
+	return d, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBSONDecimal128(t *testing.T) {
+	tests := []struct {
+		x      string
+		hi, lo uint64
+		value  string
+		r      string
+		flags  Condition
+	}{
+		{x: "1", hi: 0x3040000000000000, lo: 1, value: "01000000000000000000000000004030"},
+		{x: "-1", hi: 0xb040000000000000, lo: 1, value: "010000000000000000000000000040b0"},
+		{x: "0.001234", hi: 0x3034000000000000, lo: 0x4d2, value: "d2040000000000000000000000003430"},
+		{x: "-0E-10", hi: 0xb02c000000000000, lo: 0, value: "00000000000000000000000000002cb0"},
+		{x: "1E+6112", hi: 0x5ffe000000000000, lo: 10, value: "0a00000000000000000000000000fe5f", r: "1.0E+6112", flags: Clamped},
+		{x: "1234567890123456789012345678901234567", hi: 0x30463cde6fff9732, lo: 0xde825cd07e96aff3, value: "f3af967ed05c82de3297ff6fde3c4630", r: "1.234567890123456789012345678901235E+36", flags: Inexact | Rounded},
+		{x: "Infinity", hi: 0x7800000000000000, lo: 0, value: "00000000000000000000000000000078"},
+		{x: "NaN", hi: 0x7c00000000000000, lo: 0, value: "0000000000000000000000000000007c"},
+	}
+	c := BaseContext.WithPrecision(0)
+	c.Rounding = RoundHalfEven
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			hi, lo, res, err := c.EncodeBSONDecimal128(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hi != tc.hi || lo != tc.lo {
+				t.Errorf("expected %#x %#x, got %#x %#x", tc.hi, tc.lo, hi, lo)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+			r := tc.r
+			if r == "" {
+				r = tc.x
+			}
+			if s := DecodeBSONDecimal128(hi, lo).String(); s != r {
+				t.Errorf("expected decoded %s, got %s", r, s)
+			}
+
+			b, _, err := c.EncodeBSONDecimal128Value(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b); s != tc.value {
+				t.Errorf("expected value %s, got %s", tc.value, s)
+			}
+			d, err := DecodeBSONDecimal128Value(new(Decimal), b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != r {
+				t.Errorf("expected decoded value %s, got %s", r, s)
+			}
+		})
+	}
+	if _, err := DecodeBSONDecimal128Value(new(Decimal), make([]byte, 15)); err == nil {
+		t.Fatal("expected error")
+	}
+}
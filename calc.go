@@ -0,0 +1,114 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+// Calc chains operations on a single running value, so that a formula reads
+// left to right:
+//
+//	total, err := c.Calc(price).Mul(qty).Mul(rate).Add(fee).Quantize(-2).Value()
+//
+// Each method applies its operation to the running value with the Calc's
+// Context, exactly as the Context method of the same name would, and
+// returns the Calc. Errors are handled as by ErrDecimal: once an operation
+// fails or raises a trapped condition, the remaining operations are
+// skipped and Value returns the error. The operands are never modified.
+type Calc struct {
+	ed ErrDecimal
+	d  Decimal
+}
+
+// Calc returns a Calc whose running value is a copy of x.
+func (c *Context) Calc(x *Decimal) *Calc {
+	k := &Calc{ed: MakeErrDecimal(c)}
+	k.d.Set(x)
+	return k
+}
+
+// Add adds y to the running value.
+func (k *Calc) Add(y *Decimal) *Calc {
+	k.ed.Add(&k.d, &k.d, y)
+	return k
+}
+
+// Sub subtracts y from the running value.
+func (k *Calc) Sub(y *Decimal) *Calc {
+	k.ed.Sub(&k.d, &k.d, y)
+	return k
+}
+
+// Mul multiplies the running value by y.
+func (k *Calc) Mul(y *Decimal) *Calc {
+	k.ed.Mul(&k.d, &k.d, y)
+	return k
+}
+
+// Quo divides the running value by y.
+func (k *Calc) Quo(y *Decimal) *Calc {
+	k.ed.Quo(&k.d, &k.d, y)
+	return k
+}
+
+// Pow raises the running value to the power y.
+func (k *Calc) Pow(y *Decimal) *Calc {
+	k.ed.Pow(&k.d, &k.d, y)
+	return k
+}
+
+// Neg negates the running value.
+func (k *Calc) Neg() *Calc {
+	k.ed.Neg(&k.d, &k.d)
+	return k
+}
+
+// Abs sets the running value to its absolute value.
+func (k *Calc) Abs() *Calc {
+	k.ed.Abs(&k.d, &k.d)
+	return k
+}
+
+// Sqrt sets the running value to its square root.
+func (k *Calc) Sqrt() *Calc {
+	k.ed.Sqrt(&k.d, &k.d)
+	return k
+}
+
+// Round rounds the running value to the Context's precision.
+func (k *Calc) Round() *Calc {
+	k.ed.Round(&k.d, &k.d)
+	return k
+}
+
+// Quantize sets the exponent of the running value to exp, as
+// Context.Quantize does; Quantize(-2) rounds to cents.
+func (k *Calc) Quantize(exp int32) *Calc {
+	k.ed.Quantize(&k.d, &k.d, exp)
+	return k
+}
+
+// Flags returns the conditions raised by the operations so far.
+func (k *Calc) Flags() Condition {
+	return k.ed.Flags
+}
+
+// Err returns the error that stopped the chain, if any.
+func (k *Calc) Err() error {
+	return k.ed.Err()
+}
+
+// Value returns a copy of the running value, and the error that stopped the
+// chain, if any, in which case the value is that of the failed operation.
+func (k *Calc) Value() (*Decimal, error) {
+	return new(Decimal).Set(&k.d), k.ed.Err()
+}
@@ -0,0 +1,68 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestCalc(t *testing.T) {
+	price := New(1999, -2)
+	qty := New(3, 0)
+	rate := New(1075, -3)
+	fee := New(25, -2)
+	c := BaseContext.WithPrecision(16)
+
+	k := c.Calc(price).Mul(qty).Mul(rate).Add(fee).Quantize(-2)
+	d, err := k.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "64.72" {
+		t.Fatalf("expected 64.72, got %s", s)
+	}
+	if k.Flags() != Inexact|Rounded {
+		t.Fatalf("expected inexact, rounded, got %s", k.Flags())
+	}
+	if price.String() != "19.99" || qty.String() != "3" {
+		t.Fatal("operands changed")
+	}
+
+	// The same formula through ErrDecimal.
+	ed := MakeErrDecimal(c)
+	expect := new(Decimal)
+	ed.Mul(expect, price, qty)
+	ed.Mul(expect, expect, rate)
+	ed.Add(expect, expect, fee)
+	ed.Quantize(expect, expect, -2)
+	if ed.Err() != nil || expect.CmpTotal(d) != 0 {
+		t.Fatalf("expected %s, got %s", expect, d)
+	}
+
+	// An error stops the chain.
+	k = c.Calc(price).Quo(New(0, 0)).Add(fee)
+	if _, err := k.Value(); err == nil || k.Err() == nil {
+		t.Fatal("expected division by zero error")
+	}
+	if !k.Flags().DivisionByZero() {
+		t.Fatalf("expected division by zero, got %s", k.Flags())
+	}
+
+	d, err = c.Calc(New(-16, 0)).Abs().Sqrt().Pow(New(3, 0)).Neg().Sub(New(1, 0)).Round().Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "-65" {
+		t.Fatalf("expected -65, got %s", s)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// CBOR major types and tags used by the decimal fraction encoding. See RFC
+// 8949, sections 3.1 and 3.4.
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborArray    = 4
+	cborTag      = 6
+	cborSimple   = 7
+
+	cborTagPositiveBignum  = 2
+	cborTagNegativeBignum  = 3
+	cborTagDecimalFraction = 4
+)
+
+// cborHalfNaN, cborHalfInf and cborHalfNegInf are the half-precision floats
+// used to encode the non-finite forms.
+const (
+	cborHalfNaN    = 0x7e00
+	cborHalfInf    = 0x7c00
+	cborHalfNegInf = 0xfc00
+)
+
+// MarshalCBOR implements the cbor.Marshaler interface used by CBOR libraries
+// such as github.com/fxamacker/cbor. A finite d is encoded as a decimal
+// fraction (tag 4): an array of its exponent and its coefficient, which is a
+// bignum if it does not fit in 64 bits. The exponent is preserved exactly,
+// but the sign of a zero is not. NaN and infinities, which decimal fractions
+// cannot represent, are encoded as half-precision floats; signaling NaNs
+// become quiet.
+func (d *Decimal) MarshalCBOR() ([]byte, error) {
+	switch d.Form {
+	case NaN, NaNSignaling:
+		return []byte{cborSimple<<5 | 25, cborHalfNaN >> 8, cborHalfNaN & 0xff}, nil
+	case Infinite:
+		if d.Negative {
+			return []byte{cborSimple<<5 | 25, cborHalfNegInf >> 8, cborHalfNegInf & 0xff}, nil
+		}
+		return []byte{cborSimple<<5 | 25, cborHalfInf >> 8, cborHalfInf & 0xff}, nil
+	}
+	b := appendCBORHead(nil, cborTag, cborTagDecimalFraction)
+	b = appendCBORHead(b, cborArray, 2)
+	if d.Exponent < 0 {
+		b = appendCBORHead(b, cborNegative, uint64(-(int64(d.Exponent) + 1)))
+	} else {
+		b = appendCBORHead(b, cborUnsigned, uint64(d.Exponent))
+	}
+	// A negative coefficient n is encoded as -1-n, that is |n|-1.
+	m := &d.Coeff
+	major := uint64(cborUnsigned)
+	if d.Negative && d.Coeff.Sign() != 0 {
+		m = new(big.Int).Sub(m, bigOne)
+		major = cborNegative
+	}
+	if m.IsUint64() {
+		return appendCBORHead(b, major, m.Uint64()), nil
+	}
+	b = appendCBORHead(b, cborTag, cborTagPositiveBignum+major)
+	buf := m.Bytes()
+	b = appendCBORHead(b, cborBytes, uint64(len(buf)))
+	return append(b, buf...), nil
+}
+
+// UnmarshalCBOR implements the cbor.Unmarshaler interface. It accepts the
+// encodings produced by MarshalCBOR, as well as integers and bignums.
+func (d *Decimal) UnmarshalCBOR(b []byte) error {
+	r := cborReader{b: b}
+	major, arg, err := r.head()
+	if err != nil {
+		return err
+	}
+	switch {
+	case major == cborSimple:
+		return d.setCBORFloat(arg, len(b))
+	case major == cborTag && arg == cborTagDecimalFraction:
+		major, arg, err = r.head()
+		if err != nil {
+			return err
+		}
+		if major != cborArray || arg != 2 {
+			return errors.New("cbor: decimal fraction is not an array of two integers")
+		}
+		var e big.Int
+		if err := r.integer(&e, false); err != nil {
+			return err
+		}
+		if !e.IsInt64() || e.Int64() > math.MaxInt32 || e.Int64() < math.MinInt32 {
+			return errors.Errorf("cbor: exponent %s out of range", &e)
+		}
+		d.Exponent = int32(e.Int64())
+	default:
+		d.Exponent = 0
+		r = cborReader{b: b}
+	}
+	if err := r.integer(&d.Coeff, true); err != nil {
+		return err
+	}
+	if len(r.b) != 0 {
+		return errors.New("cbor: unexpected trailing data")
+	}
+	d.Form = Finite
+	d.Negative = d.Coeff.Sign() < 0
+	d.Coeff.Abs(&d.Coeff)
+	return nil
+}
+
+// setCBORFloat sets d to the non-finite value of the CBOR float with bits
+// arg encoded in n bytes.
+func (d *Decimal) setCBORFloat(arg uint64, n int) error {
+	var f float64
+	switch n {
+	case 3:
+		switch arg {
+		case cborHalfInf:
+			f = math.Inf(1)
+		case cborHalfNegInf:
+			f = math.Inf(-1)
+		default:
+			// Any other half-precision float with an all-ones exponent is a
+			// NaN.
+			if arg&0x7c00 == 0x7c00 {
+				f = math.NaN()
+			}
+		}
+	case 5:
+		f = float64(math.Float32frombits(uint32(arg)))
+	case 9:
+		f = math.Float64frombits(arg)
+	}
+	switch {
+	case math.IsNaN(f):
+		d.Form = NaN
+		d.Negative = false
+	case math.IsInf(f, 0):
+		d.Form = Infinite
+		d.Negative = f < 0
+	default:
+		return errors.New("cbor: unsupported simple value or finite float")
+	}
+	d.Exponent = 0
+	d.Coeff.SetInt64(0)
+	return nil
+}
+
+// appendCBORHead appends the head of a CBOR data item with the given major
+// type and argument to b.
+func appendCBORHead(b []byte, major, arg uint64) []byte {
+	m := byte(major << 5)
+	switch {
+	case arg < 24:
+		return append(b, m|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, m|24, byte(arg))
+	case arg <= math.MaxUint16:
+		b = append(b, m|25, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(arg))
+	case arg <= math.MaxUint32:
+		b = append(b, m|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(arg))
+	default:
+		b = append(b, m|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], arg)
+	}
+	return b
+}
+
+// cborReader decodes CBOR data items from b.
+type cborReader struct {
+	b []byte
+}
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// head reads the head of a data item, returning its major type and
+// argument.
+func (r *cborReader) head() (major, arg uint64, err error) {
+	if len(r.b) == 0 {
+		return 0, 0, errCBORTruncated
+	}
+	major, info := uint64(r.b[0]>>5), r.b[0]&0x1f
+	r.b = r.b[1:]
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		n = 1 << (info - 24)
+	default:
+		return 0, 0, errors.Errorf("cbor: unsupported additional information %d", info)
+	}
+	if len(r.b) < n {
+		return 0, 0, errCBORTruncated
+	}
+	for _, c := range r.b[:n] {
+		arg = arg<<8 | uint64(c)
+	}
+	r.b = r.b[n:]
+	return major, arg, nil
+}
+
+// integer reads an integer into z. Bignums are accepted if bignum is true.
+func (r *cborReader) integer(z *big.Int, bignum bool) error {
+	major, arg, err := r.head()
+	if err != nil {
+		return err
+	}
+	switch {
+	case major == cborUnsigned || major == cborNegative:
+		z.SetUint64(arg)
+	case bignum && major == cborTag && (arg == cborTagPositiveBignum || arg == cborTagNegativeBignum):
+		major = arg - cborTagPositiveBignum
+		bm, n, err := r.head()
+		if err != nil {
+			return err
+		}
+		if bm != cborBytes {
+			return errors.New("cbor: bignum is not a byte string")
+		}
+		if uint64(len(r.b)) < n {
+			return errCBORTruncated
+		}
+		z.SetBytes(r.b[:n])
+		r.b = r.b[n:]
+	default:
+		return errors.New("cbor: expected integer")
+	}
+	if major == cborNegative {
+		// The encoded value is -1-n.
+		z.Add(z, bigOne)
+		z.Neg(z)
+	}
+	return nil
+}
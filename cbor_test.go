@@ -0,0 +1,114 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestCBOR(t *testing.T) {
+	tests := []struct {
+		x    string
+		cbor string
+		r    string
+	}{
+		// Example from RFC 8949, section 3.4.4.
+		{x: "273.15", cbor: "c48221196ab3"},
+		{x: "0", cbor: "c4820000"},
+		{x: "-0", cbor: "c4820000", r: "0"},
+		{x: "1.0", cbor: "c482200a"},
+		{x: "-1.0", cbor: "c4822029"},
+		{x: "1E+3", cbor: "c4820301"},
+		{x: "-1E-300", cbor: "c48239012b20"},
+		{x: "18446744073709551615", cbor: "c482001bffffffffffffffff"},
+		{x: "18446744073709551616", cbor: "c48200c249010000000000000000"},
+		{x: "-18446744073709551616", cbor: "c482003bffffffffffffffff"},
+		{x: "-18446744073709551617", cbor: "c48200c349010000000000000000"},
+		{x: "NaN", cbor: "f97e00"},
+		{x: "-sNaN", cbor: "f97e00", r: "NaN"},
+		{x: "Infinity", cbor: "f97c00"},
+		{x: "-Infinity", cbor: "f9fc00"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			b, err := x.MarshalCBOR()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b); s != tc.cbor {
+				t.Fatalf("expected %s, got %s", tc.cbor, s)
+			}
+			var d Decimal
+			if err := d.UnmarshalCBOR(b); err != nil {
+				t.Fatal(err)
+			}
+			r := tc.r
+			if r == "" {
+				r = tc.x
+			}
+			if s := d.String(); s != r {
+				t.Fatalf("expected %s, got %s", r, s)
+			}
+		})
+	}
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	tests := []struct {
+		cbor string
+		r    string
+		err  bool
+	}{
+		{cbor: "17", r: "23"},
+		{cbor: "3863", r: "-100"},
+		{cbor: "c249010000000000000000", r: "18446744073709551616"},
+		{cbor: "fa7f800000", r: "Infinity"},
+		{cbor: "fb7ff8000000000000", r: "NaN"},
+		{cbor: "f93c00", err: true},
+		{cbor: "f5", err: true},
+		{cbor: "c48301020304", err: true},
+		{cbor: "c4820101", r: "1E+1"},
+		{cbor: "c482c2410101", err: true},
+		{cbor: "c4821b000000010000000001", err: true},
+		{cbor: "c48221", err: true},
+		{cbor: "c4820101ff", err: true},
+		{cbor: "6161", err: true},
+		{cbor: "", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.cbor, func(t *testing.T) {
+			b, err := hex.DecodeString(tc.cbor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var d Decimal
+			err = d.UnmarshalCBOR(b)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", &d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Fatalf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+}
@@ -54,6 +54,9 @@ const (
 	// Clamped is raised when the exponent of a result has been altered or
 	// constrained in order to fit the constraints of the Decimal representation.
 	Clamped
+	// DigitLimit is raised when the coefficient of a result, or of an exact
+	// intermediate value, would have more digits than Context.MaxDigits.
+	DigitLimit
 )
 
 // Any returns true if any flag is true.
@@ -95,6 +98,9 @@ func (r Condition) InvalidOperation() bool { return r&InvalidOperation != 0 }
 // Clamped returns true if the Clamped flag is set.
 func (r Condition) Clamped() bool { return r&Clamped != 0 }
 
+// DigitLimit returns true if the DigitLimit flag is set.
+func (r Condition) DigitLimit() bool { return r&DigitLimit != 0 }
+
 // GoError converts r to an error based on the given traps and returns
 // r. Traps are the conditions which will trigger an error result if the
 // corresponding Flag condition occurred.
@@ -142,6 +148,8 @@ func (r Condition) String() string {
 			s = "invalid operation"
 		case Clamped:
 			s = "clamped"
+		case DigitLimit:
+			s = "digit limit"
 		default:
 			panic(errors.Errorf("unknown condition %d", i))
 		}
@@ -16,6 +16,39 @@ package apd
 
 import "testing"
 
+func TestExportedConsts(t *testing.T) {
+	consts := []struct {
+		f      *Frozen
+		expect string
+	}{
+		{Zero, "0"},
+		{One, "1"},
+		{Two, "2"},
+		{Ten, "10"},
+		{OneHundred, "100"},
+	}
+	c := BaseContext.WithPrecision(10)
+	c.Traps = 0
+	d := new(Decimal)
+	for _, tc := range consts {
+		x := tc.f.Decimal()
+		for _, op := range []func(d, x, y *Decimal) (Condition, error){c.Add, c.Sub, c.Mul, c.Pow} {
+			if _, err := op(d, x, x); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if s := tc.f.String(); s != tc.expect || x.Exponent != 0 {
+			t.Errorf("expected %s, got %s", tc.expect, tc.f)
+		}
+	}
+	if _, err := c.Quo(d, New(12345, 0), OneHundred.Decimal()); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "123.45" {
+		t.Fatalf("expected 123.45, got %s", s)
+	}
+}
+
 func TestConstWithPrecision(t *testing.T) {
 	c := makeConstWithPrecision("123.456789")
 	expected := []string{
@@ -32,3 +65,62 @@ func TestConstWithPrecision(t *testing.T) {
 		}
 	}
 }
+
+func TestE(t *testing.T) {
+	const digits = "2.71828182845904523536028747135266249775724709369995957496696762772407663035354759457138217852516642742746"
+	for _, p := range []uint32{1, 2, 9, 16, 50, 100, 20, 3} {
+		c := BaseContext.WithPrecision(p)
+		d := new(Decimal)
+		res, err := c.E(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Inexact() {
+			t.Errorf("%d: expected inexact", p)
+		}
+		expect := newDecimal(t, c, digits)
+		if d.CmpTotal(expect) != 0 {
+			t.Errorf("%d: expected %s, got %s", p, expect, d)
+		}
+		// Compare against Exp(1).
+		exp := new(Decimal)
+		if _, err := c.Exp(exp, decimalOne); err != nil {
+			t.Fatal(err)
+		}
+		if d.Cmp(exp) != 0 {
+			t.Errorf("%d: Exp(1) = %s, E = %s", p, exp, d)
+		}
+	}
+}
+
+func TestComputePi(t *testing.T) {
+	const digits = "3.14159265358979323846264338327950288419716939937510582097494459230781640628620899862803482534211706798"
+	for _, p := range []uint32{1, 5, 30, 100} {
+		d := computePi(p)
+		if nd := d.NumDigits(); nd != int64(p) {
+			t.Errorf("%d: expected %d digits, got %d", p, p, nd)
+		}
+		c := BaseContext.WithPrecision(p)
+		c.Rounding = RoundDown
+		expect := newDecimal(t, c, digits)
+		if d.Cmp(expect) != 0 {
+			t.Errorf("%d: expected %s, got %s", p, expect, d)
+		}
+	}
+}
+
+func TestComputeLn2(t *testing.T) {
+	const digits = "0.69314718055994530941723212145817656807550013436025525412068000949339362196969471560586332699641868754200148102"
+	for _, p := range []uint32{1, 5, 30, 100} {
+		d := computeLn2(p)
+		if nd := d.NumDigits(); nd != int64(p) {
+			t.Errorf("%d: expected %d digits, got %d", p, p, nd)
+		}
+		c := BaseContext.WithPrecision(p)
+		c.Rounding = RoundDown
+		expect := newDecimal(t, c, digits)
+		if d.Cmp(expect) != 0 {
+			t.Errorf("%d: expected %s, got %s", p, expect, d)
+		}
+	}
+}
@@ -27,7 +27,11 @@ import (
 type Context struct {
 	// Precision is the number of places to round during rounding; this is
 	// effectively the total number of digits (before and after the decimal
-	// point).
+	// point). A Precision of 0 disables rounding: operations that can be
+	// exact, such as Add, Sub, Mul, QuoInteger, Rem, Quantize and Pow with a
+	// non-negative integer exponent, produce exact results of any length,
+	// and operations whose results are inherently inexact, such as Quo,
+	// Sqrt, Ln and Exp, return an error.
 	Precision uint32
 	// MaxExponent specifies the largest effective exponent. The
 	// effective exponent is the value of the Decimal in scientific notation. That
@@ -43,6 +47,56 @@ type Context struct {
 	// Rounding specifies the Rounder to use during rounding. RoundHalfUp is used if
 	// empty or not present in Roundings.
 	Rounding string
+	// Clamp, if true, limits the exponent of finite results to
+	// MaxExponent-(Precision-1), as IEEE 754 interchange formats require.
+	// A larger exponent is reduced to that limit by appending zeros to the
+	// coefficient (the "fold-down"), which leaves the value unchanged, and
+	// the Clamped condition is raised. Clamp has no effect when Precision
+	// is 0.
+	Clamp bool
+	// Quiet, if true, makes a string that cannot be parsed by SetString and
+	// the other parsing methods produce NaN with InvalidOperation, the GDA
+	// spec's conversion syntax condition, instead of a Go error. The parse
+	// error is still returned if InvalidOperation is trapped. Untrapped
+	// conditions of arithmetic operations, such as DivisionByZero, always
+	// produce their specified results.
+	Quiet bool
+	// MaxDigits, if not 0, limits the number of digits in the coefficients
+	// that operations compute, to bound their memory use when precision or
+	// exponents come from untrusted input. The limit applies to exact
+	// intermediate values as well as results: Add and Sub align their
+	// operands to the smaller exponent, and Mul forms the full product
+	// before rounding, so MaxDigits should be well above twice Precision.
+	// An operation that would exceed it sets its result to NaN and raises
+	// DigitLimit without allocating the coefficient.
+	MaxDigits uint32
+	// GuardDigits adjusts the number of extra digits of precision that Exp,
+	// Ln, Log10 and Pow carry in their intermediate results, relative to
+	// their defaults of 2 for Exp, Ln and Log10 and 10 for Pow. Positive
+	// values make correctly rounded results more likely at the cost of
+	// speed, which helps at high precision or for arguments close to 1.
+	// Negative values trade accuracy in the last place for speed; at least
+	// one guard digit is always used.
+	GuardDigits int32
+	// Done, if not nil, cancels long-running operations such as Exp, Ln and
+	// Pow at high precision when it is closed: they stop at the next
+	// iteration and return ErrCanceled. It is typically set to the Done
+	// channel of a context.Context.
+	Done <-chan struct{}
+	// Allocator, if not nil, supplies the temporary Decimals of Exp, Ln,
+	// Pow and the operations built on them in place of the package's pool.
+	// Each temporary is released to it when the operation that requested
+	// it returns. It is shared by copies of the Context and by the working
+	// contexts of nested operations, so it must be safe for concurrent use
+	// if the Context is.
+	Allocator Allocator
+	// Flags, if not nil, accumulates the conditions raised by operations
+	// on this Context, like the status flags of the GDA spec. Conditions are
+	// ORed into *Flags whether or not they are trapped, and stay set until
+	// cleared with ClearFlags. Contexts copied from this one, such as by
+	// WithPrecision, share the same Flags. Accumulation is not synchronized:
+	// a Context with Flags must not be used by multiple goroutines at once.
+	Flags *Condition
 }
 
 const (
@@ -55,11 +109,15 @@ const (
 		DivisionUndefined |
 		DivisionByZero |
 		DivisionImpossible |
-		InvalidOperation
+		InvalidOperation |
+		DigitLimit
 
 	errZeroPrecisionStr = "Context may not have 0 Precision for this operation"
 )
 
+// ErrCanceled is returned by operations canceled through Context.Done.
+var ErrCanceled = errors.New("operation canceled")
+
 // BaseContext is a useful default Context. Should not be mutated.
 var BaseContext = Context{
 	// Disable rounding.
@@ -71,6 +129,73 @@ var BaseContext = Context{
 	Traps: DefaultTraps,
 }
 
+// exactContext computes without rounding, within the package's exponent
+// limits. It traps nothing, so that callers can examine the conditions.
+var exactContext = Context{
+	MaxExponent: MaxExponent,
+	MinExponent: MinExponent,
+}
+
+// BasicContext is the basic default context of the GDA spec and decNumber:
+// precision 9, round-half-up, and traps on the error conditions division by
+// zero, division impossible, division undefined, invalid operation, overflow
+// and underflow. Its exponents are limited only by the package's limits.
+// Should not be mutated.
+var BasicContext = Context{
+	Precision:   9,
+	MaxExponent: MaxExponent,
+	MinExponent: MinExponent,
+	Traps: SystemOverflow |
+		SystemUnderflow |
+		Overflow |
+		Underflow |
+		DivisionUndefined |
+		DivisionByZero |
+		DivisionImpossible |
+		InvalidOperation,
+	Rounding: RoundHalfUp,
+}
+
+// ExtendedDefaultContext is the extended default context of the GDA spec,
+// like Python's decimal.ExtendedContext: precision 9, round-half-even and no
+// traps. Its exponents are limited only by the package's limits. Should not
+// be mutated.
+var ExtendedDefaultContext = Context{
+	Precision:   9,
+	MaxExponent: MaxExponent,
+	MinExponent: MinExponent,
+	Rounding:    RoundHalfEven,
+}
+
+// Decimal32Context, Decimal64Context and Decimal128Context are the
+// contexts of the IEEE 754 decimal32, decimal64 and decimal128 interchange
+// formats: their precision and exponent limits, exponent clamping,
+// round-half-even rounding and, as in the GDA extended default contexts, no
+// traps. They should not be mutated.
+var (
+	Decimal32Context = Context{
+		Precision:   decimal32Format.precision,
+		MaxExponent: decimal32Format.emax,
+		MinExponent: 1 - decimal32Format.emax,
+		Rounding:    RoundHalfEven,
+		Clamp:       true,
+	}
+	Decimal64Context = Context{
+		Precision:   decimal64Format.precision,
+		MaxExponent: decimal64Format.emax,
+		MinExponent: 1 - decimal64Format.emax,
+		Rounding:    RoundHalfEven,
+		Clamp:       true,
+	}
+	Decimal128Context = Context{
+		Precision:   decimal128Format.precision,
+		MaxExponent: decimal128Format.emax,
+		MinExponent: 1 - decimal128Format.emax,
+		Rounding:    RoundHalfEven,
+		Clamp:       true,
+	}
+)
+
 // WithPrecision returns a copy of c but with the specified precision.
 func (c *Context) WithPrecision(p uint32) *Context {
 	r := *c
@@ -78,11 +203,99 @@ func (c *Context) WithPrecision(p uint32) *Context {
 	return &r
 }
 
+// baseContext returns a copy of BaseContext with precision p for computing
+// intermediate results, which is canceled along with c.
+func (c *Context) baseContext(p uint32) *Context {
+	r := BaseContext
+	r.Precision = p
+	r.MaxDigits = c.MaxDigits
+	r.GuardDigits = c.GuardDigits
+	r.Done = c.Done
+	r.Allocator = c.Allocator
+	return &r
+}
+
+// guardDigits returns n, the default number of guard digits of an
+// operation, adjusted by c.GuardDigits.
+func (c *Context) guardDigits(n int32) uint32 {
+	g := int64(n) + int64(c.GuardDigits)
+	if g < 1 {
+		return 1
+	}
+	return uint32(g)
+}
+
+// exceedsDigits returns true if a coefficient with n digits is over
+// c.MaxDigits.
+func (c *Context) exceedsDigits(n int64) bool {
+	return c.MaxDigits > 0 && n > int64(c.MaxDigits)
+}
+
+// digitLimit sets d to NaN and raises DigitLimit.
+func (c *Context) digitLimit(d *Decimal) (Condition, error) {
+	d.Set(decimalNaN)
+	return c.goError(DigitLimit)
+}
+
+// alignedDigits returns the number of digits in the coefficient of x or y,
+// whichever is larger, after both are scaled to the smaller exponent.
+func alignedDigits(x, y *Decimal) int64 {
+	e := x.Exponent
+	if y.Exponent < e {
+		e = y.Exponent
+	}
+	nx := x.NumDigits() + int64(x.Exponent) - int64(e)
+	ny := y.NumDigits() + int64(y.Exponent) - int64(e)
+	if ny > nx {
+		return ny
+	}
+	return nx
+}
+
+// canceled returns ErrCanceled if c.Done is closed.
+func (c *Context) canceled() error {
+	if c.Done == nil {
+		return nil
+	}
+	select {
+	case <-c.Done:
+		return ErrCanceled
+	default:
+		return nil
+	}
+}
+
+// workingContext returns a copy of c with precision p for computing
+// intermediate results, whose conditions are not accumulated into c.Flags.
+func (c *Context) workingContext(p uint32) *Context {
+	r := *c
+	r.Precision = p
+	r.Flags = nil
+	return &r
+}
+
 // goError converts flags into an error based on c.Traps.
 func (c *Context) goError(flags Condition) (Condition, error) {
+	if c.Flags != nil {
+		*c.Flags |= flags
+	}
 	return flags.GoError(c.Traps)
 }
 
+// ClearFlags clears the conditions accumulated in c.Flags.
+func (c *Context) ClearFlags() {
+	if c.Flags != nil {
+		*c.Flags = 0
+	}
+}
+
+// TestFlags reports whether any of the conditions in flags have been
+// accumulated in c.Flags since it was last cleared. It returns false if
+// c.Flags is nil.
+func (c *Context) TestFlags(flags Condition) bool {
+	return c.Flags != nil && *c.Flags&flags != 0
+}
+
 // etiny returns the smallest value an Exponent can contain.
 func (c *Context) etiny() int32 {
 	return c.MinExponent - int32(c.Precision) + 1
@@ -119,6 +332,14 @@ Loop:
 }
 
 func (c *Context) add(d, x, y *Decimal, subtract bool) (Condition, error) {
+	tmp := getBigInt()
+	defer putBigInt(tmp)
+	return c.addTmp(d, x, y, subtract, tmp)
+}
+
+// addTmp is add with the scratch space for aligning the operands supplied by
+// the caller, so that batch operations can share it between elements.
+func (c *Context) addTmp(d, x, y *Decimal, subtract bool, tmp *big.Int) (Condition, error) {
 	if set, res, err := c.setIfNaN(d, x, y); set {
 		return res, err
 	}
@@ -136,7 +357,13 @@ func (c *Context) add(d, x, y *Decimal, subtract bool) (Condition, error) {
 		}
 		return 0, nil
 	}
-	a, b, s, err := upscale(x, y)
+	if c.exceedsDigits(alignedDigits(x, y) + 1) {
+		return c.digitLimit(d)
+	}
+	if c.addUint64(d, x, y, subtract) {
+		return c.Round(d, d)
+	}
+	a, b, s, err := upscaleTmp(x, y, tmp)
 	if err != nil {
 		return 0, errors.Wrap(err, "add")
 	}
@@ -202,8 +429,13 @@ func (c *Context) Mul(d, x, y *Decimal) (Condition, error) {
 		d.Negative = neg
 		return 0, nil
 	}
+	if c.exceedsDigits(x.NumDigits() + y.NumDigits()) {
+		return c.digitLimit(d)
+	}
 
-	d.Coeff.Mul(&x.Coeff, &y.Coeff)
+	if !mulUint64(d, x, y) {
+		d.Coeff.Mul(&x.Coeff, &y.Coeff)
+	}
 	d.Negative = neg
 	d.Form = Finite
 	res := d.setExponent(c, 0, int64(x.Exponent), int64(y.Exponent))
@@ -251,12 +483,6 @@ func (c *Context) quoSpecials(d, x, y *Decimal, canClamp bool) (bool, Condition,
 		return true, res, err
 	}
 
-	if c.Precision == 0 {
-		// 0 precision is disallowed because we compute the required number of digits
-		// during the 10**x calculation using the precision.
-		return true, 0, errors.New(errZeroPrecisionStr)
-	}
-
 	return false, 0, nil
 }
 
@@ -268,6 +494,12 @@ func (c *Context) Quo(d, x, y *Decimal) (Condition, error) {
 		return res, err
 	}
 
+	if c.Precision == 0 {
+		// 0 precision is disallowed because we compute the required number of digits
+		// during the 10**x calculation using the precision.
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
 	if c.Precision > 5000 {
 		// High precision could result in a large number of iterations. Arbitrarily
 		// limit the precision to prevent runaway processes. This limit was chosen
@@ -283,12 +515,16 @@ func (c *Context) Quo(d, x, y *Decimal) (Condition, error) {
 	// An integer variable, adjust, is initialized to 0.
 	var adjust int64
 	// The result coefficient is initialized to 0.
-	quo := new(Decimal)
+	quo := getDecimal()
+	defer putDecimal(quo)
+	quo.Negative = neg
 	var res Condition
 	var diff int64
 	if !x.IsZero() {
-		dividend := new(big.Int).Abs(&x.Coeff)
-		divisor := new(big.Int).Abs(&y.Coeff)
+		dividend, divisor, tmp := getBigInt(), getBigInt(), getBigInt()
+		defer putBigInt(dividend, divisor, tmp)
+		dividend.Abs(&x.Coeff)
+		divisor.Abs(&y.Coeff)
 
 		// The operand coefficients are adjusted so that the coefficient of the
 		// dividend is greater than or equal to the coefficient of the divisor and
@@ -304,7 +540,7 @@ func (c *Context) Quo(d, x, y *Decimal) (Condition, error) {
 		// While the coefficient of the dividend is greater than or equal to ten
 		// times the coefficient of the divisor the coefficient of the divisor is
 		// multiplied by 10 and adjust is decremented by 1.
-		for tmp := new(big.Int); ; {
+		for {
 			tmp.Mul(divisor, bigTen)
 			if dividend.Cmp(tmp) < 0 {
 				break
@@ -344,7 +580,11 @@ func (c *Context) Quo(d, x, y *Decimal) (Condition, error) {
 		adj := int64(x.Exponent) + int64(-y.Exponent) - adjust + quo.NumDigits() - 1
 		// Any remainder (the final coefficient of the dividend) is recorded and
 		// taken into account for rounding.
-		if dividend.Sign() != 0 && adj >= int64(c.MinExponent) {
+		if dividend.Sign() == 0 && adjust < 0 {
+			// The quotient is exact, but the division stopped at c.Precision
+			// digits before reaching the ideal exponent, discarding zeros.
+			res |= Rounded
+		} else if dividend.Sign() != 0 && adj >= int64(c.MinExponent) {
 			res |= Inexact | Rounded
 			dividend.Mul(dividend, bigTwo)
 			half := dividend.Cmp(divisor)
@@ -359,7 +599,6 @@ func (c *Context) Quo(d, x, y *Decimal) (Condition, error) {
 	// original exponent of the divisor and the value of adjust at the end of
 	// the coefficient calculation from the original exponent of the dividend.
 	res |= quo.setExponent(c, res, int64(x.Exponent), int64(-y.Exponent), -adjust, diff)
-	quo.Negative = neg
 	d.Set(quo)
 	return c.goError(res)
 }
@@ -375,13 +614,16 @@ func (c *Context) QuoInteger(d, x, y *Decimal) (Condition, error) {
 	neg := x.Negative != y.Negative
 	var res Condition
 
+	if c.exceedsDigits(alignedDigits(x, y)) {
+		return c.digitLimit(d)
+	}
 	a, b, _, err := upscale(x, y)
 	if err != nil {
 		return 0, errors.Wrap(err, "QuoInteger")
 	}
 	d.Coeff.Quo(a, b)
 	d.Form = Finite
-	if d.NumDigits() > int64(c.Precision) {
+	if c.Precision > 0 && d.NumDigits() > int64(c.Precision) {
 		d.Set(decimalNaN)
 		res |= DivisionImpossible
 	}
@@ -390,9 +632,148 @@ func (c *Context) QuoInteger(d, x, y *Decimal) (Condition, error) {
 	return c.goError(res)
 }
 
+// QuoScale sets d to the quotient x/y rounded to scale digits after the
+// decimal point, so that d has an exponent of -scale, as in SQL division
+// to a column's scale. Unlike Quo followed by Quantize, the quotient is
+// rounded only once, directly to the target scale, so it cannot suffer
+// from double rounding: a quotient of 0.4999999999 rounded to 9 digits is
+// 0.500000000, which RoundHalfUp then takes to 1 rather than 0 at scale 0.
+// rounder decides the rounding;
+// if it is nil, c.Rounding is used. As with Quantize, InvalidOperation is
+// raised if -scale is outside c's exponent range or the result has more
+// than c.Precision digits. Dividing a finite number by an infinity gives a
+// zero with an exponent of -scale.
+func (c *Context) QuoScale(d, x, y *Decimal, scale int32, rounder Rounder) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x, y); set {
+		return res, err
+	}
+	exp := -int64(scale)
+	if exp < int64(c.etiny()) || exp > int64(c.MaxExponent) {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if set, res, err := c.quoSpecials(d, x, y, false); set {
+		if d.Form == Finite {
+			d.Exponent = int32(exp)
+		}
+		return res, err
+	}
+	if rounder == nil {
+		rounder = c.rounding()
+	}
+
+	// x/y = x.Coeff/y.Coeff × 10**(x.Exponent-y.Exponent), so the
+	// coefficient of the result is x.Coeff × 10**shift / y.Coeff, scaled by
+	// 10**shift on whichever side keeps it an integer.
+	shift := int64(x.Exponent) - int64(y.Exponent) - exp
+	nx, ny := x.NumDigits(), y.NumDigits()
+	if shift > 0 {
+		nx += shift
+	} else {
+		ny -= shift
+	}
+	if c.exceedsDigits(nx) || c.exceedsDigits(ny) {
+		return c.digitLimit(d)
+	}
+	num, den, r := getBigInt(), getBigInt(), getBigInt()
+	defer putBigInt(num, den, r)
+	num.Abs(&x.Coeff)
+	den.Abs(&y.Coeff)
+	if shift > 0 {
+		num.Mul(num, tableExp10(shift, nil))
+	} else if shift < 0 {
+		den.Mul(den, tableExp10(-shift, nil))
+	}
+
+	neg := x.Negative != y.Negative
+	var res Condition
+	d.Coeff.QuoRem(num, den, r)
+	if r.Sign() != 0 {
+		res |= Inexact | Rounded
+		half := r.Lsh(r, 1).Cmp(den)
+		if rounder(&d.Coeff, neg, half) {
+			d.Coeff.Add(&d.Coeff, bigOne)
+		}
+	}
+	d.Exponent = int32(exp)
+	d.Form = Finite
+	d.Negative = neg
+	if c.Precision > 0 && d.NumDigits() > int64(c.Precision) {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	res |= c.round(d, d)
+	if res.Overflow() || res.Underflow() {
+		res = InvalidOperation
+		d.Set(decimalNaN)
+	}
+	return c.goError(res)
+}
+
 // Rem sets d to the remainder part of the quotient x/y. If
 // the integer part cannot fit in d.Precision digits, an error is returned.
 func (c *Context) Rem(d, x, y *Decimal) (Condition, error) {
+	return c.rem(d, x, y, "Rem", remTruncated)
+}
+
+// Mod sets d to the Euclidean modulus of x and y: the remainder of the
+// division of x by y, where the quotient is rounded toward -Infinity if y is
+// positive and toward +Infinity if it is negative, so that the result is
+// never negative: -7 mod 3 and -7 mod -3 are both 2, whereas Rem gives -1.
+// This is the convention of big.Int's Mod, suited to bucketing and calendar
+// arithmetic. It is otherwise like Rem, except that x mod ±Infinity is an
+// InvalidOperation for a negative x, since the result would be infinite.
+func (c *Context) Mod(d, x, y *Decimal) (Condition, error) {
+	return c.rem(d, x, y, "Mod", remEuclidean)
+}
+
+// ModFloor sets d to the floored modulus of x and y: the remainder of the
+// division of x by y with the quotient rounded toward -Infinity, so that the
+// result has the sign of y: -7 mod 3 is 2 and 7 mod -3 is -2. This is the
+// convention of the % operator of Python. It is otherwise like Rem, except
+// that x mod ±Infinity is an InvalidOperation if x and y have different
+// signs, since the result would be infinite.
+func (c *Context) ModFloor(d, x, y *Decimal) (Condition, error) {
+	return c.rem(d, x, y, "ModFloor", remFloored)
+}
+
+// remKind selects the sign convention of rem.
+type remKind int
+
+const (
+	// remTruncated gives the remainder the sign of the dividend.
+	remTruncated remKind = iota
+	// remFloored gives the remainder the sign of the divisor.
+	remFloored
+	// remEuclidean gives a remainder that is never negative.
+	remEuclidean
+)
+
+// adjust reports whether a nonzero truncated remainder of x and y, which
+// has the sign of x, has the wrong sign for k and must be moved by |y|.
+func (k remKind) adjust(x, y *Decimal) bool {
+	switch k {
+	case remFloored:
+		return x.Negative != y.Negative
+	case remEuclidean:
+		return x.Negative
+	}
+	return false
+}
+
+// sign returns the sign of a remainder of x and y, zero or not.
+func (k remKind) sign(x, y *Decimal) bool {
+	switch k {
+	case remFloored:
+		return y.Negative
+	case remEuclidean:
+		return false
+	}
+	return x.Negative
+}
+
+// rem implements Rem, Mod and ModFloor; op names the operation in errors.
+func (c *Context) rem(d, x, y *Decimal, op string, kind remKind) (Condition, error) {
 	if set, res, err := c.setIfNaN(d, x, y); set {
 		return res, err
 	}
@@ -402,7 +783,13 @@ func (c *Context) Rem(d, x, y *Decimal) (Condition, error) {
 		return c.goError(InvalidOperation)
 	}
 	if y.Form == Infinite {
+		if !x.IsZero() && kind.adjust(x, y) {
+			d.Set(decimalNaN)
+			return c.goError(InvalidOperation)
+		}
+		neg := kind.sign(x, y)
 		d.Set(x)
+		d.Negative = neg
 		return 0, nil
 	}
 
@@ -416,20 +803,30 @@ func (c *Context) Rem(d, x, y *Decimal) (Condition, error) {
 		d.Set(decimalNaN)
 		return c.goError(res)
 	}
+	if c.exceedsDigits(alignedDigits(x, y)) {
+		return c.digitLimit(d)
+	}
 	a, b, s, err := upscale(x, y)
 	if err != nil {
-		return 0, errors.Wrap(err, "Rem")
+		return 0, errors.Wrap(err, op)
 	}
-	tmp := new(big.Int)
-	tmp.QuoRem(a, b, &d.Coeff)
-	if NumDigits(tmp) > int64(c.Precision) {
+	neg := kind.sign(x, y)
+	adjust := kind.adjust(x, y)
+	q, r := new(big.Int), new(big.Int)
+	q.QuoRem(a, b, r)
+	if c.Precision > 0 && NumDigits(q) > int64(c.Precision) {
 		d.Set(decimalNaN)
 		return c.goError(DivisionImpossible)
 	}
+	if adjust && r.Sign() != 0 {
+		// b may be d.Coeff, so it is read before d.Coeff is written.
+		d.Coeff.Sub(b, r)
+	} else {
+		d.Coeff.Set(r)
+	}
 	d.Form = Finite
 	d.Exponent = s
-	// The sign of the result is sign if the dividend.
-	d.Negative = x.Negative
+	d.Negative = neg
 	res |= c.round(d, d)
 	return c.goError(res)
 }
@@ -456,98 +853,88 @@ func (c *Context) rootSpecials(d, x *Decimal, factor int32) (bool, Condition, er
 			return true, res, err
 		}
 	case 0:
+		// The ideal exponent is x's divided by factor, rounded toward
+		// -Infinity, and may need clamping.
 		d.Set(x)
-		d.Exponent /= factor
-		return true, 0, nil
+		e := d.Exponent / factor
+		if d.Exponent%factor < 0 {
+			e--
+		}
+		d.Exponent = e
+		res, err := c.goError(c.round(d, d))
+		return true, res, err
+	}
+	if c.Precision == 0 {
+		return true, 0, errors.New(errZeroPrecisionStr)
 	}
 	return false, 0, nil
 }
 
-// Sqrt sets d to the square root of x. Sqrt uses the Babylonian method
-// for computing the square root, which uses O(log p) steps for p digits
-// of precision.
+// Sqrt sets d to the square root of x. The coefficient of x is scaled by an
+// even power of ten so that its integer square root, computed by Newton's
+// method on big.Ints, has one more digit than the precision; that root is
+// then rounded. Sqrt always uses RoundHalfEven.
 func (c *Context) Sqrt(d, x *Decimal) (Condition, error) {
-	// See: Properly Rounded Variable Precision Square Root by T. E. Hull
-	// and A. Abrham, ACM Transactions on Mathematical Software, Vol 11 #3,
-	// pp229–237, ACM, September 1985.
-
 	if set, res, err := c.rootSpecials(d, x, 2); set {
 		return res, err
 	}
 
-	// workp is the number of digits of precision used. We use the same precision
-	// as in decNumber.
-	workp := c.Precision + 1
-	if nd := uint32(x.NumDigits()); workp < nd {
-		workp = nd
-	}
-	if workp < 7 {
-		workp = 7
-	}
-
-	f := new(Decimal).Set(x)
+	// The result is n * 10^e. c2 is x's coefficient with an even exponent, and
+	// l is the number of digits its root would have.
+	prec := int64(c.Precision) + 1
+	c2, n, tmp := getBigInt(), getBigInt(), getBigInt()
+	defer putBigInt(c2, n, tmp)
+	c2.Set(&x.Coeff)
 	nd := x.NumDigits()
-	e := nd + int64(x.Exponent)
-	f.Exponent = int32(-nd)
-	nc := c.WithPrecision(workp)
-	nc.Rounding = RoundHalfEven
-	ed := MakeErrDecimal(nc)
-	// Set approx to the first guess, based on whether e (the exponent part of x)
-	// is odd or even.
-	approx := new(Decimal)
-	if e%2 == 0 {
-		approx.SetFinite(819, -3)
-		ed.Mul(approx, approx, f)
-		ed.Add(approx, approx, New(259, -3))
+	e := int64(x.Exponent)
+	l := (nd + 1) / 2
+	if e%2 != 0 {
+		c2.Mul(c2, bigTen)
+		e--
+		l = nd/2 + 1
+	}
+	e /= 2
+
+	// Rescale c2 so that its root has exactly prec digits.
+	shift := prec - l
+	exact := true
+	if shift >= 0 {
+		c2.Mul(c2, tableExp10(2*shift, tmp))
 	} else {
-		f.Exponent--
-		e++
-		approx.SetFinite(259, -2)
-		ed.Mul(approx, approx, f)
-		ed.Add(approx, approx, New(819, -4))
+		c2.QuoRem(c2, tableExp10(-2*shift, tmp), tmp)
+		exact = tmp.Sign() == 0
 	}
+	e -= shift
 
-	// Now we repeatedly improve approx. Our precision improves quadratically,
-	// which we keep track of in p.
-	p := uint32(3)
-	tmp := new(Decimal)
-
-	// The algorithm in the paper says to use c.Precision + 2. decNumber uses
-	// workp + 2. But we use workp + 5 to make the tests pass. This means it is
-	// possible there are inputs we don't compute correctly and could be 1ulp off.
-	for maxp := workp + 5; p != maxp; {
-		p = 2*p - 2
-		if p > maxp {
-			p = maxp
+	n.Sqrt(c2)
+	exact = exact && tmp.Mul(n, n).Cmp(c2) == 0
+	if exact {
+		// Use the ideal exponent, half of x's.
+		if shift >= 0 {
+			n.Quo(n, tableExp10(shift, tmp))
+		} else {
+			n.Mul(n, tableExp10(-shift, tmp))
 		}
-		nc.Precision = p
-		// tmp = f / approx
-		ed.Quo(tmp, f, approx)
-		// tmp = approx + f / approx
-		ed.Add(tmp, tmp, approx)
-		// approx = 0.5 * (approx + f / approx)
-		ed.Mul(approx, tmp, decimalHalf)
-	}
-
-	// At this point the paper says: "approx is now within 1 ulp of the properly
-	// rounded square root off; to ensure proper rounding, compare squares of
-	// (approx - l/2 ulp) and (approx + l/2 ulp) with f." We originally implemented
-	// the proceeding algorithm from the paper. However none of the tests take
-	// any of the branches that modify approx. Our best guess as to why is that
-	// since we use workp + 5 instead of the + 2 as described in the paper,
-	// we are more accurate than this section needed to account for. Thus,
-	// we have removed the block from this implementation.
-
-	if err := ed.Err(); err != nil {
-		return 0, err
+		e += shift
+	} else if tmp.Rem(n, bigFive).Sign() == 0 {
+		// n is truncated, so a final digit of 0 or 5 would make it look
+		// exact or exactly halfway when rounded. Nudge it up.
+		n.Add(n, bigOne)
 	}
 
-	d.Set(approx)
-	d.Exponent += int32(e / 2)
-	nc.Precision = c.Precision
+	d.Coeff.Set(n)
+	d.Negative = false
+	d.Form = Finite
+	if e > MaxExponent {
+		return c.goError(SystemOverflow | Overflow)
+	}
+	if e < MinExponent {
+		return c.goError(SystemUnderflow | Underflow)
+	}
+	d.Exponent = int32(e)
+	nc := c.workingContext(c.Precision)
 	nc.Rounding = RoundHalfEven
-	d.Reduce(d) // Remove trailing zeros.
-	return nc.Round(d, d)
+	return c.goError(nc.round(d, d))
 }
 
 // Cbrt sets d to the cube root of x.
@@ -567,7 +954,7 @@ func (c *Context) Cbrt(d, x *Decimal) (Condition, error) {
 		ax = new(Decimal).Abs(x)
 	}
 	z := new(Decimal).Set(ax)
-	nc := BaseContext.WithPrecision(c.Precision*2 + 2)
+	nc := c.baseContext(c.Precision*2 + 2)
 	ed := MakeErrDecimal(nc)
 	exp8 := 0
 
@@ -578,12 +965,12 @@ func (c *Context) Cbrt(d, x *Decimal) (Condition, error) {
 	// Computing the cube root of any number is reduced to computing
 	// the cube root of a number between 0.125 and 1. After the next loops,
 	// x = z * 8^exp8 will hold.
-	for z.Cmp(decimalOneEighth) < 0 {
+	for z.Cmp(decimalOneEighth) < 0 && ed.Err() == nil {
 		exp8--
 		ed.Mul(z, z, decimalEight)
 	}
 
-	for z.Cmp(decimalOne) > 0 {
+	for z.Cmp(decimalOne) > 0 && ed.Err() == nil {
 		exp8++
 		ed.Mul(z, z, decimalOneEighth)
 	}
@@ -627,7 +1014,7 @@ func (c *Context) Cbrt(d, x *Decimal) (Condition, error) {
 	}
 
 	z0.Set(x)
-	res, err := c.Round(d, z)
+	res := c.round(d, z)
 	d.Negative = neg
 
 	// Set z = d^3 to check for exactness.
@@ -642,7 +1029,186 @@ func (c *Context) Cbrt(d, x *Decimal) (Condition, error) {
 	if z0.Cmp(z) == 0 {
 		return 0, nil
 	}
-	return res, err
+	return c.goError(res)
+}
+
+// hypotGuardDigits is the number of extra digits of precision used while
+// computing Hypot.
+const hypotGuardDigits = 3
+
+// Hypot sets d to sqrt(x**2 + y**2). Unlike composing Mul, Add and Sqrt,
+// Hypot can't overflow or underflow in its intermediate results: the
+// operands are scaled by a power of ten, which is exact, before squaring.
+// The result is correctly rounded with c.Rounding.
+func (c *Context) Hypot(d, x, y *Decimal) (Condition, error) {
+	// As in IEEE 754, an infinite operand produces Infinity even if the other
+	// operand is a quiet NaN.
+	if (x.Form == Infinite && y.Form != NaNSignaling) || (y.Form == Infinite && x.Form != NaNSignaling) {
+		d.Set(decimalInfinity)
+		return 0, nil
+	}
+	if set, res, err := c.setIfNaN(d, x, y); set {
+		return res, err
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	ax := new(Decimal).Abs(x)
+	ay := new(Decimal).Abs(y)
+	if ax.Cmp(ay) < 0 {
+		ax, ay = ay, ax
+	}
+	if ay.IsZero() {
+		return c.Round(d, ax)
+	}
+
+	// Scale so that ax is in [1, 10). Squares can then neither overflow nor
+	// underflow.
+	scale := adjustedExponent(ax)
+	ax.Exponent -= int32(scale)
+	ay.Exponent -= int32(scale)
+
+	// The root is computed with a sticky last digit at workp digits and then
+	// rounded once with c. At workp > 2*c.Precision no square of a rounding
+	// boundary of the result lies strictly between x**2 + y**2 and the
+	// squares of the values that round the same way.
+	workp := 2*c.Precision + hypotGuardDigits
+	if nd := 2*uint32(ax.NumDigits()) + 1; workp < nd {
+		workp = nd
+	}
+	if adjustedExponent(ay) < -int64(workp)-1 {
+		// ay**2 < 10**-(2*workp) is below every digit of ax**2 and of the
+		// squared boundaries, so any smaller positive ay gives the same
+		// result. Clamping it keeps the exact sum below short.
+		ay.SetFinite(1, -int32(workp)-1)
+	}
+
+	// The squares and their sum are exact.
+	sp := 3 - 2*int64(ax.Exponent)
+	if p := 3 - 2*int64(ay.Exponent); sp < p {
+		sp = p
+	}
+	nc := c.baseContext(uint32(sp))
+	ed := MakeErrDecimal(nc)
+	z := new(Decimal)
+	ed.Mul(ax, ax, ax)
+	ed.Mul(ay, ay, ay)
+	ed.Add(z, ax, ay)
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+
+	nc.Precision = workp
+	r := new(Decimal)
+	res, err := nc.Sqrt(r, z)
+	if err != nil {
+		return 0, err
+	}
+	if res.Inexact() {
+		// r is within half a unit of the root. Move it by a hundredth of a
+		// unit toward the root, which keeps it on the same side of every
+		// boundary the final rounding can use.
+		sq := new(Decimal)
+		sq.Coeff.Mul(&r.Coeff, &r.Coeff)
+		sq.Exponent = 2 * r.Exponent
+		r.Coeff.Mul(&r.Coeff, tableExp10(2, nil))
+		r.Exponent -= 2
+		if sq.Cmp(z) > 0 {
+			r.Coeff.Sub(&r.Coeff, bigOne)
+		} else {
+			r.Coeff.Add(&r.Coeff, bigOne)
+		}
+	}
+	r.Exponent += int32(scale)
+	res |= c.round(d, r)
+	return c.goError(res)
+}
+
+// agmGuardDigits is the number of extra digits of precision used while
+// computing AGM.
+const agmGuardDigits = 3
+
+// AGM sets d to the arithmetic-geometric mean of x and y, the common limit
+// of the sequences
+//
+//   a' = (a + g) / 2
+//   g' = sqrt(a * g)
+//
+// starting from a = x and g = y. The sequences converge quadratically, so
+// the number of correct digits roughly doubles with each iteration. x and y
+// must not be negative.
+func (c *Context) AGM(d, x, y *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x, y); set {
+		return res, err
+	}
+	if x.Sign() < 0 || y.Sign() < 0 {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if x.Form == Infinite || y.Form == Infinite {
+		// AGM(Inf, 0) is indeterminate.
+		if x.IsZero() || y.IsZero() {
+			d.Set(decimalNaN)
+			return c.goError(InvalidOperation)
+		}
+		d.Set(decimalInfinity)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+	if x.IsZero() || y.IsZero() {
+		d.SetFinite(0, 0)
+		return 0, nil
+	}
+	if x.Cmp(y) == 0 {
+		return c.Round(d, x)
+	}
+
+	a := new(Decimal).Set(x)
+	g := new(Decimal).Set(y)
+	nc := c.baseContext(c.Precision + agmGuardDigits)
+	nc.Rounding = RoundHalfEven
+	ed := MakeErrDecimal(nc)
+	sum := new(Decimal)
+	tmp := new(Decimal)
+	for loop := nc.newLoop("agm", x, c.Precision+1, 1); ; {
+		// The operands may initially be too far apart to add directly, in
+		// which case the smaller can't affect the sum at this precision.
+		switch gap := adjustedExponent(a) - adjustedExponent(g); {
+		case gap > int64(nc.Precision):
+			sum.Set(a)
+		case gap < -int64(nc.Precision):
+			sum.Set(g)
+		default:
+			ed.Add(sum, a, g)
+		}
+		// Likewise, take the square roots separately if the product would
+		// overflow or underflow.
+		if e := adjustedExponent(a) + adjustedExponent(g); e < int64(MinExponent)+1 || e > int64(MaxExponent)-1 {
+			ed.Sqrt(tmp, a)
+			ed.Sqrt(g, g)
+			ed.Mul(g, g, tmp)
+		} else {
+			ed.Mul(g, a, g)
+			ed.Sqrt(g, g)
+		}
+		ed.Quo(a, sum, decimalTwo)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		done, err := loop.done(a)
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			break
+		}
+	}
+	res := c.round(d, a)
+	res |= Inexact | Rounded
+	return c.goError(res)
 }
 
 func (c *Context) logSpecials(d, x *Decimal) (bool, Condition, error) {
@@ -667,6 +1233,9 @@ func (c *Context) logSpecials(d, x *Decimal) (bool, Condition, error) {
 		d.Set(decimalZero)
 		return true, 0, nil
 	}
+	if c.Precision == 0 {
+		return true, 0, errors.New(errZeroPrecisionStr)
+	}
 
 	return false, 0, nil
 }
@@ -683,18 +1252,16 @@ func (c *Context) Ln(d, x *Decimal) (Condition, error) {
 
 	// The internal precision needs to be a few digits higher because errors in
 	// series/iterations add up.
-	p := c.Precision + 2
+	p := c.Precision + c.guardDigits(2)
 
-	nc := c.WithPrecision(p)
+	nc := c.workingContext(p)
 	nc.Rounding = RoundHalfEven
 	ed := MakeErrDecimal(nc)
 
-	tmp1 := new(Decimal)
-	tmp2 := new(Decimal)
-	tmp3 := new(Decimal)
-	tmp4 := new(Decimal)
-
-	z := new(Decimal).Set(x)
+	tmp1, tmp2, tmp3, tmp4 := c.getDecimal(), c.getDecimal(), c.getDecimal(), c.getDecimal()
+	z := c.getDecimal().Set(x)
+	resAdjust := c.getDecimal()
+	defer c.putDecimal(tmp1, tmp2, tmp3, tmp4, z, resAdjust)
 
 	// To get an initial estimate, we first reduce the input range to the interval
 	// [0.1, 1) by changing the exponent, and later adjust the result by a
@@ -718,17 +1285,18 @@ func (c *Context) Ln(d, x *Decimal) (Condition, error) {
 	// precision. So for z close to 1 (before scaling) we use a power series
 	// instead (which converges very rapidly in this range).
 
-	resAdjust := new(Decimal)
-
 	// tmp1 = z - 1
 	ed.Sub(tmp1, z, decimalOne)
 	// tmp3 = 0.1
 	tmp3.SetFinite(1, -1)
 
-	usePowerSeries := false
+	usePowerSeries, useAGM := false, false
 
 	if tmp2.Abs(tmp1).Cmp(tmp3) <= 0 {
 		usePowerSeries = true
+	} else if p >= lnAGMPrecision {
+		// The AGM needs no initial estimate or range reduction.
+		useAGM = true
 	} else {
 		// Reduce input to range [0.1, 1).
 		expDelta := int32(z.NumDigits()) + z.Exponent
@@ -790,11 +1358,18 @@ func (c *Context) Ln(d, x *Decimal) (Condition, error) {
 			ed.Quo(tmp4, tmp3, tmp4)
 
 			ed.Add(tmp1, tmp1, tmp4)
+			if err := ed.Err(); err != nil {
+				return 0, err
+			}
 
 			if tmp4.Abs(tmp4).Cmp(&eps) <= 0 {
 				break
 			}
 		}
+	} else if useAGM {
+		if err := nc.lnAGM(tmp1, z); err != nil {
+			return 0, err
+		}
 	} else {
 		// Use Halley's Iteration.
 		// We use a bit more precision than the context asks for in newLoop because
@@ -838,20 +1413,79 @@ func (c *Context) Ln(d, x *Decimal) (Condition, error) {
 		return 0, err
 	}
 	res := c.round(d, tmp1)
-	res |= Inexact
+	res |= Inexact | Rounded
 	return c.goError(res)
 }
 
+// lnAGMPrecision is the working precision at and above which Ln uses the
+// arithmetic-geometric mean instead of Halley's iteration.
+const lnAGMPrecision = 50
+
+// lnAGM sets d to ln(x) for x > 0 not close to 1, using
+//
+//	ln(s) ~= pi / (2 * AGM(1, 4/s))
+//
+// which is accurate to p digits when s > 10^(p/2). x (or 1/x, so that the
+// terms below don't cancel) is scaled to such an s by a power of two 2^m,
+// and m*ln(2) is subtracted from the result. The precision of c is the
+// working precision. See: Brent, Fast Multiple-Precision Evaluation of
+// Elementary Functions, Journal of the ACM, Vol 23 #2, pp242-251, 1976.
+func (c *Context) lnAGM(d, x *Decimal) error {
+	// ln(s) exceeds ln(x) by about wp digits' worth of m*ln(2), so that many
+	// more digits of the difference are lost.
+	wp := c.Precision + 2*uint32(math.Log10(float64(c.Precision))) + 10
+	nc := c.workingContext(wp)
+	ed := MakeErrDecimal(nc)
+
+	s, a, t := c.getDecimal(), c.getDecimal(), c.getDecimal()
+	defer c.putDecimal(s, a, t)
+	neg := x.Cmp(decimalOne) < 0
+	if neg {
+		ed.Quo(s, decimalOne, x)
+	} else {
+		s.Set(x)
+	}
+	// s >= 10^adj, so 2^m makes it at least 10^(wp/2+3).
+	m := int64(math.Ceil(float64(int64(wp/2)+3-adjustedExponent(s)) * digitsToBitsRatio))
+	if m < 0 {
+		m = 0
+	}
+	t.Coeff.Lsh(bigOne, uint(m))
+	ed.Mul(s, s, t)
+	ed.Quo(t, t.SetFinite(4, 0), s)
+	if err := ed.Err(); err != nil {
+		return err
+	}
+	if _, err := nc.AGM(a, decimalOne, t); err != nil {
+		return err
+	}
+
+	// d = pi / (2a) - m*ln(2)
+	ed.Add(a, a, a)
+	ed.Quo(d, decimalPi.get(wp), a)
+	ed.Mul(t, decimalLn2.get(wp), t.SetFinite(m, 0))
+	ed.Sub(d, d, t)
+	if neg {
+		d.Neg(d)
+	}
+	return ed.Err()
+}
+
 // Log10 sets d to the base 10 log of x.
 func (c *Context) Log10(d, x *Decimal) (Condition, error) {
 	if set, res, err := c.logSpecials(d, x); set {
 		return res, err
 	}
 
-	// TODO(mjibson): This is exact under some conditions.
-	res := Inexact
+	// The result is exact if x is a power of ten.
+	if nd := x.NumDigits(); x.Coeff.Cmp(tableExp10(nd-1, nil)) == 0 {
+		d.SetFinite(adjustedExponent(x), 0)
+		return c.Round(d, d)
+	}
+	res := Inexact | Rounded
 
-	nc := BaseContext.WithPrecision(c.Precision + 2)
+	g := c.guardDigits(2)
+	nc := c.baseContext(c.Precision + g)
 	nc.Rounding = RoundHalfEven
 	z := new(Decimal)
 	_, err := nc.Ln(z, x)
@@ -860,7 +1494,7 @@ func (c *Context) Log10(d, x *Decimal) (Condition, error) {
 	}
 	nc.Precision = c.Precision
 
-	qr, err := nc.Mul(d, z, decimalInvLn10.get(c.Precision+2))
+	qr, err := nc.Mul(d, z, decimalInvLn10.get(c.Precision+g))
 	if err != nil {
 		return 0, err
 	}
@@ -868,6 +1502,18 @@ func (c *Context) Log10(d, x *Decimal) (Condition, error) {
 	return c.goError(res)
 }
 
+// E sets d to e, the base of the natural logarithm. The digits of e are
+// computed on first use and cached, so this is much cheaper than computing
+// Exp(1) on every call. c.Precision must be > 0.
+func (c *Context) E(d *Decimal) (Condition, error) {
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+	res := c.round(d, decimalE.get(c.Precision))
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
 // Exp sets d = e**x.
 func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 	// See: Variable Precision Exponential Function, T. E. Hull and A. Abrham, ACM
@@ -898,7 +1544,9 @@ func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 
 	// Stage 1
 	cp := c.Precision
-	tmp1 := new(Decimal).Abs(x)
+	tmp1, tmp2 := c.getDecimal(), c.getDecimal()
+	defer c.putDecimal(tmp1, tmp2)
+	tmp1.Abs(x)
 	if f, err := tmp1.Float64(); err == nil {
 		// This algorithm doesn't work if currentprecision*23 < |x|. Attempt to
 		// increase the working precision if needed as long as it isn't too large. If
@@ -907,7 +1555,7 @@ func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 			cp = uint32(math.Ceil(ncp))
 		}
 	}
-	tmp2 := New(int64(cp)*23, 0)
+	tmp2.SetFinite(int64(cp)*23, 0)
 	// if abs(x) > 23*currentprecision; assert false
 	if tmp1.Cmp(tmp2) > 0 {
 		res |= Overflow
@@ -933,15 +1581,16 @@ func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 	if t < 0 {
 		t = 0
 	}
-	k := New(1, t)
-	r := new(Decimal)
-	nc := c.WithPrecision(cp)
+	k, r, ra, sum := c.getDecimal(), c.getDecimal(), c.getDecimal(), c.getDecimal()
+	defer c.putDecimal(k, r, ra, sum)
+	k.SetFinite(1, t)
+	nc := c.workingContext(cp)
 	nc.Rounding = RoundHalfEven
 	if _, err := nc.Quo(r, x, k); err != nil {
 		return 0, errors.Wrap(err, "Quo")
 	}
-	ra := new(Decimal).Abs(r)
-	p := int64(cp) + int64(t) + 2
+	ra.Abs(r)
+	p := int64(cp) + int64(t) + int64(c.guardDigits(2))
 
 	// Stage 3
 	rf, err := ra.Float64()
@@ -950,7 +1599,7 @@ func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 	}
 	pf := float64(p)
 	nf := math.Ceil((1.435*pf - 1.182) / math.Log10(pf/rf))
-	if nf > 1000 || math.IsNaN(nf) {
+	if nf > maxExpTerms || math.IsNaN(nf) {
 		return 0, errors.New("too many iterations")
 	}
 	n := int64(nf)
@@ -958,18 +1607,24 @@ func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 	// Stage 4
 	nc.Precision = uint32(p)
 	ed := MakeErrDecimal(nc)
-	sum := New(1, 0)
-	tmp2.Exponent = 0
-	for i := n - 1; i > 0; i-- {
-		tmp2.setCoefficient(i)
-		// tmp1 = r / i
-		ed.Quo(tmp1, r, tmp2)
-		// sum = sum * r / i
-		ed.Mul(sum, tmp1, sum)
-		// sum = sum + 1
-		ed.Add(sum, sum, decimalOne)
-	}
-	if err != ed.Err() {
+	sum.SetFinite(1, 0)
+	if n > 1 {
+		// sum = 1 + num/den, where num/den is the exact sum of terms 1
+		// through n-1.
+		a := new(big.Int).Set(&r.Coeff)
+		if r.Negative {
+			a.Neg(a)
+		}
+		den, num := expSplit(a, tableExp10(int64(-r.Exponent), nil), 1, n)
+		tmp1.SetFinite(0, 0)
+		tmp1.Coeff.Abs(num)
+		tmp1.Negative = num.Sign() < 0
+		tmp2.SetFinite(0, 0)
+		tmp2.Coeff.Set(den)
+		ed.Quo(tmp1, tmp1, tmp2)
+		ed.Add(sum, sum, tmp1)
+	}
+	if err := ed.Err(); err != nil {
 		return 0, err
 	}
 
@@ -988,17 +1643,51 @@ func (c *Context) Exp(d, x *Decimal) (Condition, error) {
 	return c.goError(res)
 }
 
+// maxExpTerms bounds the number of Taylor series terms summed by Exp.
+const maxExpTerms = 100000
+
+// expSplit sums the terms lo through hi-1 of the Taylor series of e^(a/b),
+// (a/b)^k/k!, by binary splitting and returns the sum as the exact fraction
+// t/q. The integers involved grow in balanced products instead of once per
+// term, so large precisions can use fast multiplication. b must be positive
+// and hi must be greater than lo. See: Haible and Papanikolaou, Fast
+// multiprecision evaluation of series of rational numbers, 1997.
+func expSplit(a, b *big.Int, lo, hi int64) (q, t *big.Int) {
+	q, t, _ = expSplitRange(a, b, lo, hi)
+	return q, t
+}
+
+func expSplitRange(a, b *big.Int, lo, hi int64) (q, t, p *big.Int) {
+	if hi-lo == 1 {
+		q = new(big.Int).SetInt64(lo)
+		q.Mul(q, b)
+		return q, new(big.Int).Set(a), a
+	}
+	mid := lo + (hi-lo)/2
+	q1, t1, p1 := expSplitRange(a, b, lo, mid)
+	q2, t2, p2 := expSplitRange(a, b, mid, hi)
+	// t = t1*q2 + p1*t2
+	t1.Mul(t1, q2)
+	t2.Mul(t2, p1)
+	t1.Add(t1, t2)
+	q1.Mul(q1, q2)
+	return q1, t1, new(big.Int).Mul(p1, p2)
+}
+
 // integerPower sets d = x**y. d and x must not point to the same Decimal.
 func (c *Context) integerPower(d, x *Decimal, y *big.Int) (Condition, error) {
 	// See: https://en.wikipedia.org/wiki/Exponentiation_by_squaring.
 
-	b := new(big.Int).Set(y)
+	b := getBigInt()
+	defer putBigInt(b)
+	b.Set(y)
 	neg := b.Sign() < 0
 	if neg {
 		b.Abs(b)
 	}
 
-	n, z := new(Decimal), d
+	n, z := c.getDecimal(), d
+	defer c.putDecimal(n)
 	n.Set(x)
 	z.Set(decimalOne)
 	ed := MakeErrDecimal(c)
@@ -1034,7 +1723,8 @@ func (c *Context) Pow(d, x, y *Decimal) (Condition, error) {
 		return res, err
 	}
 
-	integ, frac := new(Decimal), new(Decimal)
+	integ, frac, tmp := c.getDecimal(), c.getDecimal(), c.getDecimal()
+	defer c.putDecimal(integ, frac, tmp)
 	y.Modf(integ, frac)
 	yIsInt := frac.IsZero()
 	neg := x.Negative && y.Form == Finite && yIsInt && integ.Coeff.Bit(0) == 1 && integ.Exponent == 0
@@ -1055,9 +1745,6 @@ func (c *Context) Pow(d, x, y *Decimal) (Condition, error) {
 		return c.goError(res)
 	}
 
-	// Check if y is of type int.
-	tmp := new(Decimal).Abs(y)
-
 	xs := x.Sign()
 	ys := y.Sign()
 
@@ -1086,23 +1773,41 @@ func (c *Context) Pow(d, x, y *Decimal) (Condition, error) {
 	}
 
 	// decNumber sets the precision to be max(x digits, c.Precision) +
-	// len(exponent) + 4. 6 is used as the exponent maximum length.
+	// len(exponent) + 4. 6 is used as the exponent maximum length. With 0
+	// precision only non-negative integer powers, which are computed
+	// exactly, are allowed.
 	p := c.Precision
-	if nd := uint32(x.NumDigits()); p < nd {
-		p = nd
+	if p == 0 {
+		if !yIsInt || y.Negative {
+			return 0, errors.New(errZeroPrecisionStr)
+		}
+	} else {
+		if nd := uint32(x.NumDigits()); p < nd {
+			p = nd
+		}
+		p += c.guardDigits(4 + 6)
 	}
-	p += 4 + 6
 
-	nc := BaseContext.WithPrecision(p)
+	nc := c.baseContext(p)
 
 	z := d
 	if z == x {
-		z = new(Decimal)
+		z = c.getDecimal()
+		defer c.putDecimal(z)
 	}
 
 	// If integ.Exponent > 0, we need to add trailing 0s to integ.Coeff.
+	if c.exceedsDigits(integ.NumDigits() + int64(integ.Exponent)) {
+		return c.digitLimit(d)
+	}
 	res := c.quantize(integ, integ, 0)
 	nres, err := nc.integerPower(z, x, integ.setBig(&integ.Coeff))
+	if !nres.Inexact() {
+		// Only zeros were discarded at the working precision, so z is exact
+		// and rounding it to c's precision below decides whether the result
+		// is Rounded.
+		nres &^= Rounded
+	}
 	res |= nres
 	if err != nil {
 		d.Set(decimalNaN)
@@ -1130,7 +1835,72 @@ func (c *Context) Pow(d, x, y *Decimal) (Condition, error) {
 	}
 	res |= c.round(d, tmp)
 	d.Negative = neg
-	res |= Inexact
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// Compound sets d to (1 + rate)**periods, the growth factor of compound
+// interest. Integer periods are handled without logarithms: the result is
+// computed exactly when it fits in c.Precision digits, so, unlike Pow,
+// Inexact is only set if the result was actually rounded. Non-integer
+// periods are delegated to Pow.
+func (c *Context) Compound(d, rate, periods *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, rate, periods); set {
+		return res, err
+	}
+	base := new(Decimal)
+	if _, err := BaseContext.Add(base, decimalOne, rate); err != nil {
+		return 0, err
+	}
+	n, ok := integerArg(periods)
+	if !ok || base.Form != Finite || base.IsZero() || n == math.MaxInt64 {
+		return c.Pow(d, base, periods)
+	}
+	if n == 0 {
+		d.Set(decimalOne)
+		return 0, nil
+	}
+	base.Reduce(base)
+
+	// base's coefficient has no trailing zeros, so neither does its n-th
+	// power, which therefore has at least this many significant digits.
+	var minDigits float64
+	if nd := base.NumDigits(); nd == 1 {
+		minDigits = float64(n)*math.Log10(float64(base.Coeff.Int64())) - 1
+	} else {
+		minDigits = float64(n) * float64(nd-1)
+	}
+	z := new(Decimal)
+	if (c.Precision == 0 || minDigits <= float64(c.Precision)) && n <= MaxExponent {
+		// The result is small enough to compute exactly.
+		z.Coeff.Exp(&base.Coeff, big.NewInt(n), nil)
+		z.Form = Finite
+		z.Negative = base.Negative && n%2 == 1
+		if res := z.setExponent(c, 0, int64(base.Exponent)*n); res&(SystemOverflow|SystemUnderflow) == 0 {
+			if periods.Negative {
+				qres, err := c.Quo(d, decimalOne, z)
+				return res | qres, err
+			}
+			res |= c.round(d, z)
+			return c.goError(res)
+		}
+	}
+
+	// The result is inexact or out of range. Exponentiation by squaring
+	// loses about one digit of precision for each digit of n.
+	nc := c.baseContext(c.Precision + 2 + uint32(NumDigits(big.NewInt(n))))
+	nc.Rounding = RoundHalfEven
+	y := big.NewInt(n)
+	if periods.Negative {
+		y.Neg(y)
+	}
+	res, err := nc.integerPower(z, base, y)
+	if err != nil {
+		d.Set(decimalNaN)
+		return res, err
+	}
+	res |= c.round(d, z)
+	res |= Inexact | Rounded
 	return c.goError(res)
 }
 
@@ -1144,8 +1914,11 @@ func (c *Context) Quantize(d, x *Decimal, exp int32) (Condition, error) {
 		d.Set(decimalNaN)
 		return c.goError(InvalidOperation)
 	}
+	if exp < x.Exponent && c.exceedsDigits(x.NumDigits()+int64(x.Exponent)-int64(exp)) {
+		return c.digitLimit(d)
+	}
 	res := c.quantize(d, x, exp)
-	if nd := d.NumDigits(); nd > int64(c.Precision) || exp > c.MaxExponent {
+	if nd := d.NumDigits(); (c.Precision > 0 && nd > int64(c.Precision)) || exp > c.MaxExponent {
 		res = InvalidOperation
 		d.Set(decimalNaN)
 	} else {
@@ -1158,7 +1931,56 @@ func (c *Context) Quantize(d, x *Decimal, exp int32) (Condition, error) {
 	return c.goError(res)
 }
 
+// RoundToMultiple sets d to x rounded to a multiple of increment, such as
+// 0.25, 0.125 or 15 minutes expressed as 0.25 of an hour. The sign of
+// increment is ignored. Ties and inexact results are resolved by c.Rounding
+// as if the multiples of increment were the integers, so RoundHalfEven
+// breaks a tie toward the even multiple and RoundDown truncates toward
+// zero. The result has the exponent of increment, so rounding 1.3 to 0.125
+// gives 1.250, and is then rounded to c's precision. Inexact and Rounded
+// are raised if x was not already a multiple of increment. A zero or
+// infinite increment is an InvalidOperation.
+func (c *Context) RoundToMultiple(d, x, increment *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x, increment); set {
+		return res, err
+	}
+	if increment.Form != Finite || increment.IsZero() {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if x.Form == Infinite {
+		d.Set(x)
+		return 0, nil
+	}
+	if c.exceedsDigits(alignedDigits(x, increment)) {
+		return c.digitLimit(d)
+	}
+	a, b, _, err := upscale(x, increment)
+	if err != nil {
+		return 0, errors.Wrap(err, "upscale")
+	}
+	neg := x.Negative
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	var res Condition
+	if r.Sign() != 0 {
+		res |= Inexact | Rounded
+		half := r.Lsh(r, 1).Cmp(b)
+		if c.rounding()(q, neg, half) {
+			q.Add(q, bigOne)
+		}
+	}
+	d.Coeff.Mul(q, &increment.Coeff)
+	d.Exponent = increment.Exponent
+	d.Form = Finite
+	d.Negative = neg
+	res |= c.round(d, d)
+	return c.goError(res)
+}
+
 func (c *Context) quantize(d, v *Decimal, exp int32) Condition {
+	if res, ok := c.quantizeUint64(d, v, exp); ok {
+		return res
+	}
 	diff := exp - v.Exponent
 	d.Set(v)
 	var res Condition
@@ -1167,15 +1989,19 @@ func (c *Context) quantize(d, v *Decimal, exp int32) Condition {
 			return SystemUnderflow | Underflow
 		}
 		d.Coeff.Mul(&d.Coeff, tableExp10(-int64(diff), nil))
-	} else if diff > 0 {
+	} else if diff > 0 && !d.IsZero() {
+		// A zero coefficient has no digits to discard, so it is not Rounded.
 		p := int32(d.NumDigits()) - diff
 		if p < 0 {
-			if !d.IsZero() {
-				d.Coeff.SetInt64(0)
-				res = Inexact | Rounded
+			// Every digit is discarded, and together they are less than
+			// half of the last place kept, which is 0 before rounding.
+			d.Coeff.SetInt64(0)
+			if c.rounding()(&d.Coeff, d.Negative, -1) {
+				d.Coeff.SetInt64(1)
 			}
+			res = Inexact | Rounded
 		} else {
-			nc := c.WithPrecision(uint32(p))
+			nc := c.workingContext(uint32(p))
 
 			// The idea here is that the resulting d.Exponent after rounding will be 0. We
 			// have a number of, say, 5 digits, but p (our precision) above is set at, say,
@@ -1266,13 +2092,23 @@ func (c *Context) Reduce(d, x *Decimal) (int, Condition, error) {
 	if set, res, err := c.setIfNaN(d, x); set {
 		return 0, res, err
 	}
+	// x is first rounded, as by Plus, and then its trailing zeros removed.
 	neg := x.Negative
-	_, n := d.Reduce(x)
+	res := c.round(d, x)
+	_, n := d.Reduce(d)
 	d.Negative = neg
-	res, err := c.Round(d, d)
+	res |= c.round(d, d)
+	res, err := c.goError(res)
 	return n, res, err
 }
 
+// IsSubnormal returns true if x is a nonzero finite number whose adjusted
+// exponent is below c.MinExponent, as the GDA is-subnormal operation. Such
+// a number has fewer than c.Precision digits available to it.
+func (c *Context) IsSubnormal(x *Decimal) bool {
+	return x.Form == Finite && !x.IsZero() && adjustedExponent(x) < int64(c.MinExponent)
+}
+
 // exp10 returns x, 10^x. An error is returned if x is too large.
 func exp10(x int64) (exp *big.Int, err error) {
 	if x > MaxExponent || x < MinExponent {
@@ -16,8 +16,10 @@ package apd
 
 import (
 	"database/sql/driver"
+	"encoding/binary"
 	"math"
 	"math/big"
+	"math/bits"
 	"strconv"
 	"strings"
 
@@ -30,11 +32,32 @@ import (
 //
 // Coeff must be positive. If it is negative results may be incorrect and
 // apd may panic.
+//
+// The zero value of a Decimal is 0 and is ready to use as an operand or a
+// result. A nil *Decimal is not: the formatting methods such as String
+// return "<nil>" for it, and the comparison methods such as Sign and Cmp
+// panic with a *NilDecimalError. MarshalText, MarshalJSON and AppendText
+// have value receivers so that non-addressable Decimal values are encoded
+// as text; calling them on a nil *Decimal panics, but encoding/json and
+// similar packages encode a nil *Decimal without calling them.
 type Decimal struct {
 	Form     Form
 	Negative bool
 	Exponent int32
-	Coeff    big.Int
+	// cache is placed before Coeff so that it takes no space when empty.
+	cache stringCache
+	Coeff big.Int
+}
+
+// NilDecimalError is the value passed to panic when a method that reads a
+// *Decimal is called with a nil one.
+type NilDecimalError struct {
+	// Method is the name of the method that was called, such as "Cmp".
+	Method string
+}
+
+func (e *NilDecimalError) Error() string {
+	return "nil *Decimal passed to " + e.Method
 }
 
 // Form specifies the form of a Decimal.
@@ -100,41 +123,143 @@ func NewWithBigInt(coeff *big.Int, exponent int32) *Decimal {
 	return d
 }
 
-func consumePrefix(s, prefix string) (string, bool) {
-	if strings.HasPrefix(s, prefix) {
-		return s[len(prefix):], true
+// NewFromComponents creates a new finite decimal from its sign, coefficient
+// and exponent, so that NewFromComponents(true, big.NewInt(150), -2) is
+// -1.50. Unlike setting the fields of a Decimal directly, it validates
+// them: an error is returned if coeff is nil or negative, since the sign is
+// given by negative, or if the value is out of the package's exponent range.
+// coeff is copied. It has no restrictions on precision.
+func NewFromComponents(negative bool, coeff *big.Int, exponent int32) (*Decimal, Condition, error) {
+	return BaseContext.NewFromComponents(negative, coeff, exponent)
+}
+
+// NewFromComponents is like the package function of the same name, but the
+// returned Decimal has its exponents restricted by the context and its value
+// rounded if it contains more digits than the context's precision.
+func (c *Context) NewFromComponents(negative bool, coeff *big.Int, exponent int32) (*Decimal, Condition, error) {
+	if coeff == nil {
+		return nil, 0, errors.New("nil coefficient")
+	}
+	if coeff.Sign() < 0 {
+		return nil, 0, errors.Errorf("negative coefficient %s", coeff)
+	}
+	d := &Decimal{
+		Negative: negative,
+		Exponent: exponent,
+	}
+	if c.exceedsDigits(NumDigits(coeff)) {
+		res, err := c.digitLimit(d)
+		return d, res, err
 	}
-	return s, false
+	d.Coeff.Set(coeff)
+	res := c.round(d, d)
+	_, err := c.goError(res)
+	return d, res, err
+}
+
+// NewFromFloat creates a new decimal from f using the shortest decimal
+// representation that converts back to f, so NewFromFloat(0.1) is 0.1. As in
+// JavaScript, integers below 1e21 get exponent 0, so NewFromFloat(100) is 100
+// rather than 1E+2. Use SetFloat64 for the exact value of f instead.
+func NewFromFloat(f float64) *Decimal {
+	return new(Decimal).setFloatShortest(f, 64)
 }
 
+// setFloatShortest sets d to the shortest decimal representation of f that
+// converts back to f as a float of bitSize bits, 32 or 64, and returns d.
+func (d *Decimal) setFloatShortest(f float64, bitSize int) *Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		d.SetFloat64(f)
+		return d
+	}
+	// strconv produces d[.ddd]e±dd with at most 17 significant digits.
+	var buf [32]byte
+	b := strconv.AppendFloat(buf[:0], math.Abs(f), 'e', -1, bitSize)
+	i := strings.IndexByte(string(b), 'e')
+	exp, _ := strconv.Atoi(string(b[i+1:]))
+	var coeff uint64
+	nd := 0
+	for _, c := range b[:i] {
+		if c != '.' {
+			coeff = coeff*10 + uint64(c-'0')
+			nd++
+		}
+	}
+	d.Form = Finite
+	d.Negative = math.Signbit(f)
+	d.Coeff.SetUint64(coeff)
+	d.Exponent = int32(exp - (nd - 1))
+	if d.Exponent > 0 && exp < 21 {
+		d.Coeff.Mul(&d.Coeff, tableExp10(int64(d.Exponent), nil))
+		d.Exponent = 0
+	}
+	return d
+}
+
+// wordDigits is the number of decimal digits that always fit in a big.Word.
+const wordDigits = 9 + 10*(bits.UintSize/64)
+
+// hasPrefixFold reports whether s begins with prefix, ignoring ASCII case.
+// prefix must be lower case.
+func hasPrefixFold(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if s[i]|0x20 != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mulAddWords sets z to z*m + a, where z is a little-endian magnitude as
+// returned by big.Int.Bits, and returns z. It appends to z only if the
+// result needs another word.
+func mulAddWords(z []big.Word, m, a big.Word) []big.Word {
+	carry := uint(a)
+	for i, w := range z {
+		hi, lo := bits.Mul(uint(w), uint(m))
+		lo, cc := bits.Add(lo, carry, 0)
+		z[i] = big.Word(lo)
+		carry = hi + cc
+	}
+	if carry != 0 {
+		z = append(z, big.Word(carry))
+	}
+	return z
+}
+
+// setString parses s into d in a single pass. Digits are accumulated a word
+// at a time directly into the storage of d.Coeff, so at most one allocation
+// is made for the coefficient and none when d already has enough capacity.
 func (d *Decimal) setString(c *Context, s string) (Condition, error) {
 	orig := s
-	s, d.Negative = consumePrefix(s, "-")
-	if !d.Negative {
-		s, _ = consumePrefix(s, "+")
+	d.Negative = false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		d.Negative = s[0] == '-'
+		s = s[1:]
 	}
-	s = strings.ToLower(s)
 	d.Exponent = 0
 	d.Coeff.SetInt64(0)
 	// Until there are no parse errors, leave as NaN.
 	d.Form = NaN
-	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
 		return 0, errors.Errorf("could not parse: %s", orig)
 	}
-	switch s {
-	case "infinity", "inf":
+	if len(s) == len("inf") && hasPrefixFold(s, "inf") ||
+		len(s) == len("infinity") && hasPrefixFold(s, "infinity") {
 		d.Form = Infinite
 		return 0, nil
 	}
 	isNaN := false
-	s, consumed := consumePrefix(s, "nan")
-	if consumed {
+	if hasPrefixFold(s, "nan") {
 		isNaN = true
-	}
-	s, consumed = consumePrefix(s, "snan")
-	if consumed {
+		s = s[len("nan"):]
+	} else if hasPrefixFold(s, "snan") {
 		isNaN = true
 		d.Form = NaNSignaling
+		s = s[len("snan"):]
 	}
 	if isNaN {
 		if s != "" {
@@ -147,26 +272,77 @@ func (d *Decimal) setString(c *Context, s string) (Condition, error) {
 		return 0, nil
 	}
 
-	var exps []int64
-	if i := strings.IndexByte(s, 'e'); i >= 0 {
-		exp, err := strconv.ParseInt(s[i+1:], 10, 32)
+	words := d.Coeff.Bits()[:0]
+	// Reserve enough words for every byte being a digit, which is the only
+	// time the coefficient storage is allocated.
+	if n := int(float64(len(s))*digitsToBitsRatio/bits.UintSize) + 1; n > cap(words) {
+		words = make([]big.Word, 0, n)
+	}
+	var (
+		chunk       big.Word
+		chunkDigits int
+		digits      int
+		fracDigits  int
+		seenDot     bool
+		i           int
+	)
+scan:
+	for ; i < len(s); i++ {
+		switch ch := s[i]; {
+		case '0' <= ch && ch <= '9':
+			chunk = chunk*10 + big.Word(ch-'0')
+			chunkDigits++
+			if chunkDigits == wordDigits {
+				words = mulAddWords(words, big.Word(pow10Uint64[wordDigits]), chunk)
+				chunk, chunkDigits = 0, 0
+			}
+			digits++
+			if seenDot {
+				fracDigits++
+			}
+		case ch == '.' && !seenDot:
+			seenDot = true
+		default:
+			break scan
+		}
+	}
+	var exp int64
+	if i < len(s) && s[i]|0x20 == 'e' && digits > 0 {
+		var err error
+		exp, err = strconv.ParseInt(s[i+1:], 10, 32)
 		if err != nil {
 			return 0, errors.Wrapf(err, "parse exponent: %s", s[i+1:])
 		}
-		exps = append(exps, exp)
-		s = s[:i]
-	}
-	if i := strings.IndexByte(s, '.'); i >= 0 {
-		exp := int64(len(s) - i - 1)
-		exps = append(exps, -exp)
-		s = s[:i] + s[i+1:]
-	}
-	if _, ok := d.Coeff.SetString(s, 10); !ok {
+	} else if i < len(s) || digits == 0 {
 		return 0, errors.Errorf("parse mantissa: %s", s)
 	}
+	if chunkDigits > 0 {
+		words = mulAddWords(words, big.Word(pow10Uint64[chunkDigits]), chunk)
+	}
+	d.Coeff.SetBits(words)
 	// No parse errors, can now flag as finite.
 	d.Form = Finite
-	return c.goError(d.setExponent(c, 0, exps...))
+	return c.goError(d.setExponent(c, 0, exp, -int64(fracDigits)))
+}
+
+// stringDigits returns the number of digits that the coefficient parsed
+// from s by setString will have: the digits of the mantissa, not counting
+// leading zeros. It does not validate s.
+func stringDigits(s string) int64 {
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	var n int64
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '0' && n == 0, ch == '.':
+		case '0' <= ch && ch <= '9':
+			n++
+		default:
+			return n
+		}
+	}
+	return n
 }
 
 // NewFromString creates a new decimal from s. It has no restrictions on
@@ -193,7 +369,63 @@ func (c *Context) NewFromString(s string) (*Decimal, Condition, error) {
 // restricted by the context and its value rounded if it contains more digits
 // than the context's precision.
 func (c *Context) SetString(d *Decimal, s string) (*Decimal, Condition, error) {
+	// Check the limit before setString allocates the coefficient.
+	if c.exceedsDigits(stringDigits(s)) {
+		res, err := c.digitLimit(d)
+		return d, res, err
+	}
 	res, err := d.setString(c, s)
+	if err != nil {
+		return c.conversionSyntax(d, err)
+	}
+	res |= c.round(d, d)
+	_, err = c.goError(res)
+	return d, res, err
+}
+
+// conversionSyntax returns err, the error from parsing a string into d,
+// unless c.Quiet is set, in which case d is set to NaN and InvalidOperation
+// is raised instead.
+func (c *Context) conversionSyntax(d *Decimal, err error) (*Decimal, Condition, error) {
+	if !c.Quiet {
+		return nil, 0, err
+	}
+	d.Set(decimalNaN)
+	res, terr := c.goError(InvalidOperation)
+	if terr != nil {
+		return nil, res, err
+	}
+	return d, res, nil
+}
+
+// NewFromBytes is like NewFromString, but parses b without converting it to
+// a string first.
+func NewFromBytes(b []byte) (*Decimal, Condition, error) {
+	return BaseContext.NewFromBytes(b)
+}
+
+// SetBytes is like SetString, but parses b without converting it to a string
+// first.
+func (d *Decimal) SetBytes(b []byte) (*Decimal, Condition, error) {
+	return BaseContext.SetBytes(d, b)
+}
+
+// NewFromBytes is like NewFromString, but parses b without converting it to
+// a string first.
+func (c *Context) NewFromBytes(b []byte) (*Decimal, Condition, error) {
+	d := new(Decimal)
+	return c.SetBytes(d, b)
+}
+
+// SetBytes is like SetString, but parses b without converting it to a string
+// first. Numbers with at most 19 digits in the coefficient are parsed without
+// allocating when d's coefficient already has room for them.
+func (c *Context) SetBytes(d *Decimal, b []byte) (*Decimal, Condition, error) {
+	exp, ok := d.setSmallBytes(b)
+	if !ok {
+		return c.SetString(d, string(b))
+	}
+	res, err := c.goError(d.setExponent(c, 0, exp))
 	if err != nil {
 		return nil, 0, err
 	}
@@ -202,6 +434,173 @@ func (c *Context) SetString(d *Decimal, s string) (*Decimal, Condition, error) {
 	return d, res, err
 }
 
+// setSmallBytes sets d's form, sign and coefficient from b if b is a finite
+// number whose coefficient fits in a uint64, and returns its exponent. It
+// returns false for anything else, leaving the full parse to setString.
+func (d *Decimal) setSmallBytes(b []byte) (exp int64, ok bool) {
+	neg := false
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		neg = b[0] == '-'
+		b = b[1:]
+	}
+	var coeff uint64
+	nd, frac := 0, -1
+	i := 0
+	for ; i < len(b); i++ {
+		if c := b[i]; '0' <= c && c <= '9' {
+			if nd == 19 {
+				return 0, false
+			}
+			coeff = coeff*10 + uint64(c-'0')
+			nd++
+			if frac >= 0 {
+				frac++
+			}
+		} else if c == '.' && frac < 0 {
+			frac = 0
+		} else {
+			break
+		}
+	}
+	if nd == 0 {
+		return 0, false
+	}
+	if i < len(b) {
+		if b[i] != 'e' && b[i] != 'E' {
+			return 0, false
+		}
+		e := b[i+1:]
+		eneg := false
+		if len(e) > 0 && (e[0] == '-' || e[0] == '+') {
+			eneg = e[0] == '-'
+			e = e[1:]
+		}
+		if len(e) == 0 || len(e) > 9 {
+			return 0, false
+		}
+		for _, c := range e {
+			if c < '0' || c > '9' {
+				return 0, false
+			}
+			exp = exp*10 + int64(c-'0')
+		}
+		if eneg {
+			exp = -exp
+		}
+	}
+	if frac > 0 {
+		exp -= int64(frac)
+	}
+	d.Form = Finite
+	d.Negative = neg
+	d.Coeff.SetUint64(coeff)
+	d.Exponent = 0
+	return exp, true
+}
+
+// NewFromFraction is like NewFromString, but s may also be a fraction "a/b"
+// where a and b are any strings accepted by NewFromString, optionally
+// surrounded by spaces. The quotient is computed as by SetRat: exactly if it
+// has a finite decimal expansion, otherwise rounded to the context's
+// precision with the Inexact and Rounded conditions.
+func (c *Context) NewFromFraction(s string) (*Decimal, Condition, error) {
+	d := new(Decimal)
+	return c.SetFraction(d, s)
+}
+
+// SetFraction sets d to the value of the string or fraction s as described
+// by NewFromFraction and returns d.
+func (c *Context) SetFraction(d *Decimal, s string) (*Decimal, Condition, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return c.SetString(d, s)
+	}
+	var num, den Decimal
+	if _, err := num.setString(&BaseContext, strings.TrimSpace(s[:i])); err != nil {
+		return c.conversionSyntax(d, errors.Wrap(err, "parse numerator"))
+	}
+	if _, err := den.setString(&BaseContext, strings.TrimSpace(s[i+1:])); err != nil {
+		return c.conversionSyntax(d, errors.Wrap(err, "parse denominator"))
+	}
+	if num.Form != Finite || den.Form != Finite || den.IsZero() {
+		// Let Quo handle the special values and division by zero.
+		res, err := c.Quo(d, &num, &den)
+		if err != nil {
+			return nil, res, err
+		}
+		return d, res, nil
+	}
+	rn, err := num.Rat()
+	if err != nil {
+		return nil, 0, err
+	}
+	rd, err := den.Rat()
+	if err != nil {
+		return nil, 0, err
+	}
+	return c.SetRat(d, rn.Quo(rn, rd))
+}
+
+// SetFloat64 sets d to the exact value of f and returns d. The returned
+// Decimal has its exponents restricted by the context and its value rounded
+// if it contains more digits than the context's precision.
+func (c *Context) SetFloat64(d *Decimal, f float64) (*Decimal, Condition, error) {
+	d.SetFloat64(f)
+	res := c.round(d, d)
+	_, err := c.goError(res)
+	return d, res, err
+}
+
+// SetRat sets d to the value of r and returns d. It has no restrictions on
+// exponents or precision, so an error is returned if r has no finite decimal
+// expansion, that is, if its denominator has a prime factor other than 2 or
+// 5. Use Context.SetRat to round such values.
+func (d *Decimal) SetRat(r *big.Rat) (*Decimal, Condition, error) {
+	return BaseContext.SetRat(d, r)
+}
+
+// SetRat sets d to the value of r and returns d. The value is exact if the
+// denominator of r is of the form 2**a * 5**b; otherwise it is rounded to the
+// context's precision.
+func (c *Context) SetRat(d *Decimal, r *big.Rat) (*Decimal, Condition, error) {
+	num, den := r.Num(), r.Denom()
+	// Find den = 2**a * 5**b * q.
+	q := new(big.Int).Set(den)
+	a := q.TrailingZeroBits()
+	q.Rsh(q, a)
+	var b uint
+	quo, rem := new(big.Int), new(big.Int)
+	for {
+		quo.QuoRem(q, bigFive, rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		q.Set(quo)
+		b++
+	}
+	if q.Cmp(bigOne) != 0 {
+		if c.Precision == 0 {
+			return nil, 0, errors.Errorf("%s has no finite decimal expansion", r)
+		}
+		res, err := c.Quo(d, NewWithBigInt(num, 0), NewWithBigInt(den, 0))
+		return d, res, err
+	}
+	// num / (2**a * 5**b) = num * 2**(k-a) * 5**(k-b) / 10**k.
+	k := a
+	if b > k {
+		k = b
+	}
+	d.Coeff.Abs(num)
+	d.Coeff.Lsh(&d.Coeff, k-a)
+	d.Coeff.Mul(&d.Coeff, new(big.Int).Exp(bigFive, big.NewInt(int64(k-b)), nil))
+	d.Form = Finite
+	d.Negative = num.Sign() < 0
+	res := d.setExponent(c, 0, -int64(k))
+	res |= c.round(d, d)
+	_, err := c.goError(res)
+	return d, res, err
+}
+
 // Set sets d's fields to the values of x and returns d.
 func (d *Decimal) Set(x *Decimal) *Decimal {
 	if d == x {
@@ -237,40 +636,123 @@ func (d *Decimal) setCoefficient(x int64) {
 }
 
 // SetFloat64 sets d's Coefficient and Exponent to x and returns d. d will
-// hold the exact value of f.
+// hold the exact value of f, that is, its full binary expansion: 0.1 becomes
+// 0.1000000000000000055511151231257827021181583404541015625. Use
+// Context.SetFloat64 to round the result. The returned error is always nil.
 func (d *Decimal) SetFloat64(f float64) (*Decimal, error) {
-	_, _, err := d.SetString(strconv.FormatFloat(f, 'E', -1, 64))
-	return d, err
+	d.Negative = math.Signbit(f)
+	switch {
+	case math.IsNaN(f):
+		d.Form = NaN
+		d.Coeff.SetInt64(0)
+		d.Exponent = 0
+		d.Negative = false
+		return d, nil
+	case math.IsInf(f, 0):
+		d.Form = Infinite
+		d.Coeff.SetInt64(0)
+		d.Exponent = 0
+		return d, nil
+	}
+	d.Form = Finite
+	// f = m * 2**e with m an odd integer. For e < 0, this is
+	// m * 5**-e * 10**e.
+	frac, e := math.Frexp(math.Abs(f))
+	m := uint64(frac * (1 << 53))
+	e -= 53
+	if m == 0 {
+		d.Coeff.SetInt64(0)
+		d.Exponent = 0
+		return d, nil
+	}
+	tz := bits.TrailingZeros64(m)
+	m >>= uint(tz)
+	e += tz
+	d.Coeff.SetUint64(m)
+	if e >= 0 {
+		d.Coeff.Lsh(&d.Coeff, uint(e))
+		d.Exponent = 0
+	} else {
+		d.Coeff.Mul(&d.Coeff, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(-e)), nil))
+		d.Exponent = int32(e)
+	}
+	return d, nil
 }
 
 // Int64 returns the int64 representation of x. If x cannot be represented in an int64, an error is returned.
 func (d *Decimal) Int64() (int64, error) {
-	if d.Form != Finite {
-		return 0, errors.Errorf("%s is not finite", d)
-	}
-	integ, frac := new(Decimal), new(Decimal)
-	d.Modf(integ, frac)
-	if !frac.IsZero() {
-		return 0, errors.Errorf("%s: has fractional part", d)
+	return d.int64(false)
+}
+
+// TruncInt64 is like Int64, but discards any fractional part of x instead of
+// returning an error.
+func (d *Decimal) TruncInt64() (int64, error) {
+	return d.int64(true)
+}
+
+// Uint64 returns the uint64 representation of x. If x cannot be represented
+// in a uint64, an error is returned.
+func (d *Decimal) Uint64() (uint64, error) {
+	return d.uint64(false)
+}
+
+// TruncUint64 is like Uint64, but discards any fractional part of x instead
+// of returning an error.
+func (d *Decimal) TruncUint64() (uint64, error) {
+	return d.uint64(true)
+}
+
+func (d *Decimal) int64(truncate bool) (int64, error) {
+	integ, err := d.integerPart(truncate)
+	if err != nil {
+		return 0, err
 	}
-	var ed ErrDecimal
-	if integ.Cmp(New(math.MaxInt64, 0)) > 0 {
+	if integ.Cmp(decimalMaxInt64) > 0 {
 		return 0, errors.Errorf("%s: greater than max int64", d)
 	}
-	if integ.Cmp(New(math.MinInt64, 0)) < 0 {
+	if integ.Cmp(decimalMinInt64) < 0 {
 		return 0, errors.Errorf("%s: less than min int64", d)
 	}
-	if err := ed.Err(); err != nil {
+	b, err := integerValue(new(big.Int), integ)
+	if err != nil {
 		return 0, err
 	}
-	v := integ.Coeff.Int64()
-	for i := int32(0); i < integ.Exponent; i++ {
-		v *= 10
-	}
 	if d.Negative {
-		v = -v
+		b.Neg(b)
+	}
+	return b.Int64(), nil
+}
+
+func (d *Decimal) uint64(truncate bool) (uint64, error) {
+	integ, err := d.integerPart(truncate)
+	if err != nil {
+		return 0, err
+	}
+	if integ.Sign() < 0 {
+		return 0, errors.Errorf("%s: less than zero", d)
+	}
+	if integ.Cmp(decimalMaxUint64) > 0 {
+		return 0, errors.Errorf("%s: greater than max uint64", d)
+	}
+	b, err := integerValue(new(big.Int), integ)
+	if err != nil {
+		return 0, err
+	}
+	return b.Uint64(), nil
+}
+
+// integerPart returns the integer part of d. Unless truncate is set, an
+// error is returned if d has a fractional part.
+func (d *Decimal) integerPart(truncate bool) (*Decimal, error) {
+	if d.Form != Finite {
+		return nil, errors.Errorf("%s is not finite", d)
+	}
+	integ, frac := new(Decimal), new(Decimal)
+	d.Modf(integ, frac)
+	if !truncate && !frac.IsZero() {
+		return nil, errors.Errorf("%s: has fractional part", d)
 	}
-	return v, nil
+	return integ, nil
 }
 
 // Float64 returns the float64 representation of x. This conversion may lose
@@ -279,6 +761,88 @@ func (d *Decimal) Float64() (float64, error) {
 	return strconv.ParseFloat(d.String(), 64)
 }
 
+// Float64Exact returns the float64 nearest to d, with ties rounded to even,
+// and whether it represents d exactly. Unlike Float64, it does not go through
+// a string conversion. If d is too large in magnitude for a float64, an
+// infinity is returned along with an error. Values too small in magnitude
+// become zero without an error, as with strconv.ParseFloat. NaNs convert to
+// a NaN that is not exact.
+func (d *Decimal) Float64Exact() (f float64, exact bool, err error) {
+	sign := 1
+	if d.Negative {
+		sign = -1
+	}
+	switch d.Form {
+	case Infinite:
+		return math.Inf(sign), true, nil
+	case NaN, NaNSignaling:
+		return math.NaN(), false, nil
+	}
+	// Bound the work for exponents far outside the float64 range:
+	// math.MaxFloat64 is about 1.8e308 and the smallest denormal about 4.9e-324.
+	switch adj := int64(d.Exponent) + d.NumDigits() - 1; {
+	case d.IsZero():
+		exact = true
+	case adj > 309:
+		return math.Inf(sign), false, errors.Errorf("%s: out of float64 range", d)
+	case adj < -325:
+	default:
+		r, _ := d.Rat()
+		f, exact = r.Abs(r).Float64()
+		if math.IsInf(f, 0) {
+			return math.Inf(sign), false, errors.Errorf("%s: out of float64 range", d)
+		}
+	}
+	if d.Negative {
+		f = -f
+	}
+	return f, exact, nil
+}
+
+// Rat returns the exact value of d as a big.Rat. An error is returned if d
+// is not finite.
+func (d *Decimal) Rat() (*big.Rat, error) {
+	if d.Form != Finite {
+		return nil, errors.Errorf("%s is not finite", d)
+	}
+	r := new(big.Rat)
+	if d.Exponent < 0 {
+		r.SetFrac(&d.Coeff, tableExp10(-int64(d.Exponent), nil))
+	} else {
+		r.SetInt(new(big.Int).Mul(&d.Coeff, tableExp10(int64(d.Exponent), nil)))
+	}
+	if d.Negative {
+		r.Neg(r)
+	}
+	return r, nil
+}
+
+// NumDenom returns the exact value of d as a fraction num/den in lowest
+// terms, where den > 0 and num has the sign of d: 1.50 is 3/2, -0.125 is
+// -1/8 and 1.2E+3 is 1200/1. It is Rat without the big.Rat, for callers
+// that work with the integers directly. A zero, including -0, is 0/1. An
+// error is returned if d is not finite.
+func (d *Decimal) NumDenom() (num, den *big.Int, err error) {
+	if d.Form != Finite {
+		return nil, nil, errors.Errorf("%s is not finite", d)
+	}
+	num = new(big.Int).Set(&d.Coeff)
+	den = big.NewInt(1)
+	if d.Exponent < 0 {
+		// Only twos and fives can cancel; the GCD finds both at once.
+		den.Set(tableExp10(-int64(d.Exponent), nil))
+		g := new(big.Int).GCD(nil, nil, num, den)
+		num.Quo(num, g)
+		den.Quo(den, g)
+	} else if d.Exponent > 0 {
+		num.Mul(num, tableExp10(int64(d.Exponent), nil))
+	}
+	if d.Negative {
+		num.Neg(num)
+	}
+	return num, den, nil
+}
+
 const (
 	errExponentOutOfRangeStr = "exponent out of range"
 )
@@ -319,43 +883,53 @@ func (d *Decimal) setExponent(c *Context, res Condition, xs ...int64) Condition
 			res |= Subnormal
 		}
 		Etiny := c.MinExponent - (int32(c.Precision) - 1)
-		// Only need to round if exponent < Etiny.
+		// Only need to round if exponent < Etiny. Digits, even zeros, are
+		// discarded only from a nonzero coefficient; a zero is just Clamped.
 		if r < Etiny {
+			if !d.IsZero() {
+				res |= Rounded
+			}
 			// We need to take off (r - Etiny) digits. Split up d.Coeff into integer and
 			// fractional parts and do operations similar Round. We avoid calling Round
 			// directly because it calls setExponent and modifies the result's exponent
 			// and coeff in ways that would be wrong here.
-			b := new(big.Int).Set(&d.Coeff)
-			tmp := &Decimal{
-				Coeff:    *b,
-				Exponent: r - Etiny,
-			}
-			integ, frac := new(Decimal), new(Decimal)
-			tmp.Modf(integ, frac)
-			frac.Abs(frac)
-			if !frac.IsZero() {
+			integ, frac := getBigInt(), getBigInt()
+			e := tableExp10(int64(Etiny-r), integ)
+			integ.QuoRem(&d.Coeff, e, frac)
+			if frac.Sign() != 0 {
 				res |= Inexact
-				if c.rounding()(&integ.Coeff, integ.Negative, frac.Cmp(decimalHalf)) {
-					integ.Coeff.Add(&integ.Coeff, bigOne)
+				if c.rounding()(integ, d.Negative, discardHalf(frac, e)) {
+					integ.Add(integ, bigOne)
 				}
 			}
-			if integ.IsZero() {
+			if integ.Sign() == 0 {
 				res |= Clamped
 			}
 			r = Etiny
-			d.Coeff = integ.Coeff
-			res |= Rounded
+			d.Coeff.Set(integ)
+			putBigInt(integ, frac)
 		}
 	} else if v > c.MaxExponent {
 		if d.IsZero() {
 			res |= Clamped
 			r = c.MaxExponent
 		} else {
-			res |= Overflow | Inexact
+			res |= Overflow | Inexact | Rounded
 			d.Form = Infinite
 		}
 	}
 
+	if c.Clamp && c.Precision > 0 && d.Form == Finite {
+		// Fold down an exponent that is too large for the precision.
+		if etop := c.MaxExponent - (int32(c.Precision) - 1); r > etop {
+			if !d.IsZero() {
+				d.Coeff.Mul(&d.Coeff, tableExp10(int64(r-etop), nil))
+			}
+			r = etop
+			res |= Clamped
+		}
+	}
+
 	if res.Inexact() && res.Subnormal() {
 		res |= Underflow
 	}
@@ -368,6 +942,12 @@ func (d *Decimal) setExponent(c *Context, res Condition, xs ...int64) Condition
 // them with this scaling, along with the scaling. An error can be produced
 // if the resulting scale factor is out of range.
 func upscale(a, b *Decimal) (*big.Int, *big.Int, int32, error) {
+	return upscaleTmp(a, b, nil)
+}
+
+// upscaleTmp is like upscale, but stores the scaled coefficient in tmp
+// instead of allocating it if tmp is not nil.
+func upscaleTmp(a, b *Decimal, tmp *big.Int) (*big.Int, *big.Int, int32, error) {
 	if a.Exponent == b.Exponent {
 		return &a.Coeff, &b.Coeff, a.Exponent, nil
 	}
@@ -382,7 +962,10 @@ func upscale(a, b *Decimal) (*big.Int, *big.Int, int32, error) {
 	if s > MaxExponent {
 		return nil, nil, 0, errors.New(errExponentOutOfRangeStr)
 	}
-	x := new(big.Int)
+	x := tmp
+	if x == nil {
+		x = new(big.Int)
+	}
 	e := tableExp10(s, x)
 	x.Mul(&a.Coeff, e)
 	y := &b.Coeff
@@ -434,6 +1017,9 @@ func (d *Decimal) setBig(b *big.Int) *big.Int {
 //   NaN
 //
 func (d *Decimal) CmpTotal(x *Decimal) int {
+	if d == nil || x == nil {
+		panic(&NilDecimalError{Method: "CmpTotal"})
+	}
 	do := d.cmpOrder()
 	xo := x.cmpOrder()
 
@@ -508,6 +1094,9 @@ func (c *Context) Cmp(d, x, y *Decimal) (Condition, error) {
 //   undefined if d or x are NaN
 //
 func (d *Decimal) Cmp(x *Decimal) int {
+	if d == nil || x == nil {
+		panic(&NilDecimalError{Method: "Cmp"})
+	}
 	ds := d.Sign()
 	xs := x.Sign()
 
@@ -556,7 +1145,8 @@ func (d *Decimal) Cmp(x *Decimal) int {
 		return gt
 	}
 
-	// Now have to use aligned big.Ints. This function previously used upscale to
+	// The adjusted exponents are equal, so the magnitudes overlap and the
+	// coefficients must be aligned. This function previously used upscale to
 	// align in all cases, but that requires an error in the return value. upscale
 	// does that so that it can fail if it needs to take the Exp of too-large a
 	// number, which is very slow. The only way for that to happen here is for d
@@ -564,18 +1154,22 @@ func (d *Decimal) Cmp(x *Decimal) int {
 	// more difficult, so we are assuming the user is already comfortable with
 	// slowness in those operations.
 
-	var cmp int
+	cmp, ok := cmpAbsUint64(d, x)
+	if ok {
+		if ds < 0 {
+			cmp = -cmp
+		}
+		return cmp
+	}
+	scaled := getBigInt()
 	if d.Exponent < x.Exponent {
-		var xScaled big.Int
-		xScaled.Set(&x.Coeff)
-		xScaled.Mul(&xScaled, tableExp10(int64(x.Exponent)-int64(d.Exponent), nil))
-		cmp = d.Coeff.Cmp(&xScaled)
+		scaled.Mul(&x.Coeff, tableExp10(int64(x.Exponent)-int64(d.Exponent), scaled))
+		cmp = d.Coeff.Cmp(scaled)
 	} else {
-		var dScaled big.Int
-		dScaled.Set(&d.Coeff)
-		dScaled.Mul(&dScaled, tableExp10(int64(d.Exponent)-int64(x.Exponent), nil))
-		cmp = dScaled.Cmp(&x.Coeff)
+		scaled.Mul(&d.Coeff, tableExp10(int64(d.Exponent)-int64(x.Exponent), scaled))
+		cmp = scaled.Cmp(&x.Coeff)
 	}
+	putBigInt(scaled)
 	if ds < 0 {
 		cmp = -cmp
 	}
@@ -594,6 +1188,9 @@ func (d *Decimal) Cmp(x *Decimal) int {
 //	+1 if d.Negative == false
 //
 func (d *Decimal) Sign() int {
+	if d == nil {
+		panic(&NilDecimalError{Method: "Sign"})
+	}
 	if d.Form == Finite && d.Coeff.Sign() == 0 {
 		return 0
 	}
@@ -605,44 +1202,112 @@ func (d *Decimal) Sign() int {
 
 // IsZero returns true if d == 0 or -0.
 func (d *Decimal) IsZero() bool {
+	if d == nil {
+		panic(&NilDecimalError{Method: "IsZero"})
+	}
 	return d.Sign() == 0
 }
 
+// IsInteger returns true if d is finite and has no fractional part, such
+// as 12, 12.00 or 1.2E+1. It does not allocate unless the coefficient of d
+// is large.
+func (d *Decimal) IsInteger() bool {
+	if d == nil {
+		panic(&NilDecimalError{Method: "IsInteger"})
+	}
+	if d.Form != Finite {
+		return false
+	}
+	if d.Exponent >= 0 || d.Coeff.Sign() == 0 {
+		return true
+	}
+	// The coefficient must be a multiple of 10**n. If it has no more than n
+	// digits, it is not.
+	n := -int64(d.Exponent)
+	if n >= d.NumDigits() {
+		return false
+	}
+	// 10**n is a multiple of 2**n, which is cheap to check first.
+	if d.Coeff.TrailingZeroBits() < uint(n) {
+		return false
+	}
+	if d.Coeff.IsUint64() {
+		return d.Coeff.Uint64()%pow10Uint64[n] == 0
+	}
+	q, r := getBigInt(), getBigInt()
+	defer putBigInt(q)
+	defer putBigInt(r)
+	q.QuoRem(&d.Coeff, tableExp10(n, nil), r)
+	return r.Sign() == 0
+}
+
+// IsNegative returns true if d is less than 0: a negative finite number
+// other than -0, or -Infinity. It returns false for NaNs.
+func (d *Decimal) IsNegative() bool {
+	return d.Sign() < 0 && d.Form != NaN && d.Form != NaNSignaling
+}
+
+// IsPositive returns true if d is greater than 0: a positive finite number
+// other than 0, or Infinity. It returns false for NaNs.
+func (d *Decimal) IsPositive() bool {
+	return d.Sign() > 0 && d.Form != NaN && d.Form != NaNSignaling
+}
+
 // Modf sets integ to the integral part of d and frac to the fractional part
-// such that d = integ+frac. If d is negative, both integ or frac will be either
-// 0 or negative. integ.Exponent will be >= 0; frac.Exponent will be <= 0.
-// Either argument can be nil, preventing it from being set.
+// such that d = integ+frac, exactly: 12.34 splits into 12 and 0.34, as
+// whole units and cents. If d is negative, both integ and frac will be
+// either 0 or negative. integ.Exponent will be >= 0; frac.Exponent will be
+// <= 0. Either argument can be nil, preventing it from being set, and
+// either can be d. As with math.Modf, an infinite d has itself as its
+// integral part and NaN as its fractional part, and a NaN d is copied to
+// both.
 func (d *Decimal) Modf(integ, frac *Decimal) {
 	if integ == nil && frac == nil {
 		return
 	}
 
+	if d.Form != Finite {
+		// Set frac last, since it is NaN even when it is d.
+		if integ != nil {
+			integ.Set(d)
+		}
+		if frac != nil && d.Form == Infinite {
+			frac.Set(decimalNaN)
+		} else if frac != nil {
+			frac.Set(d)
+		}
+		return
+	}
+
 	neg := d.Negative
+	dexp := d.Exponent
 
 	// No fractional part.
-	if d.Exponent > 0 {
+	if dexp > 0 {
+		if integ != nil {
+			integ.Set(d)
+		}
 		if frac != nil {
+			frac.Form = Finite
 			frac.Negative = neg
 			frac.Exponent = 0
 			frac.Coeff.SetInt64(0)
 		}
-		if integ != nil {
-			integ.Set(d)
-		}
 		return
 	}
 	nd := d.NumDigits()
-	exp := -int64(d.Exponent)
+	exp := -int64(dexp)
 	// d < 0 because exponent is larger than number of digits.
 	if exp > nd {
+		if frac != nil {
+			frac.Set(d)
+		}
 		if integ != nil {
+			integ.Form = Finite
 			integ.Negative = neg
 			integ.Exponent = 0
 			integ.Coeff.SetInt64(0)
 		}
-		if frac != nil {
-			frac.Set(d)
-		}
 		return
 	}
 
@@ -651,8 +1316,6 @@ func (d *Decimal) Modf(integ, frac *Decimal) {
 	var icoeff *big.Int
 	if integ != nil {
 		icoeff = &integ.Coeff
-		integ.Exponent = 0
-		integ.Negative = neg
 	} else {
 		// This is the integ == nil branch, and we already checked if both integ and
 		// frac were nil above, so frac can never be nil in this branch.
@@ -661,13 +1324,19 @@ func (d *Decimal) Modf(integ, frac *Decimal) {
 
 	if frac != nil {
 		icoeff.QuoRem(&d.Coeff, e, &frac.Coeff)
-		frac.Exponent = d.Exponent
+		frac.Form = Finite
+		frac.Exponent = dexp
 		frac.Negative = neg
 	} else {
 		// This is the frac == nil, which means integ must not be nil since they both
 		// can't be due to the check above.
 		icoeff.Quo(&d.Coeff, e)
 	}
+	if integ != nil {
+		integ.Form = Finite
+		integ.Exponent = 0
+		integ.Negative = neg
+	}
 }
 
 // Neg sets d to -x and returns d.
@@ -688,6 +1357,45 @@ func (d *Decimal) Abs(x *Decimal) *Decimal {
 	return d
 }
 
+// exactTraps are the conditions that make an exact operation fail. Subnormal
+// and Clamped are left out since they do not change the value.
+const exactTraps = ^(Subnormal | Clamped)
+
+// Add sets d to the exact sum x+y and returns d. Unlike Context.Add, which
+// rounds to the precision of the Context, the result has as many digits as
+// it takes, as with big.Int, so that intermediate results can be computed
+// exactly and rounded once at the end. An error is returned, and d is set
+// to NaN, for an invalid operation such as Infinity-Infinity or a signaling
+// NaN operand, or if the exponent of the result is out of the package's
+// range. A quiet NaN operand gives NaN without error.
+func (d *Decimal) Add(x, y *Decimal) (*Decimal, error) {
+	return d.exact(exactContext.Add(d, x, y))
+}
+
+// Sub sets d to the exact difference x-y and returns d. It is otherwise
+// like Add.
+func (d *Decimal) Sub(x, y *Decimal) (*Decimal, error) {
+	return d.exact(exactContext.Sub(d, x, y))
+}
+
+// Mul sets d to the exact product x*y and returns d. It is otherwise like
+// Add; 0*Infinity is an invalid operation.
+func (d *Decimal) Mul(x, y *Decimal) (*Decimal, error) {
+	return d.exact(exactContext.Mul(d, x, y))
+}
+
+// exact converts the result of an exactContext operation to the error
+// returned by Add, Sub and Mul.
+func (d *Decimal) exact(res Condition, err error) (*Decimal, error) {
+	if err == nil {
+		_, err = res.GoError(exactTraps)
+	}
+	if err != nil {
+		d.Set(decimalNaN)
+	}
+	return d, err
+}
+
 // Reduce sets d to x with all trailing zeros removed and returns d and the
 // number of zeros removed.
 func (d *Decimal) Reduce(x *Decimal) (*Decimal, int) {
@@ -743,6 +1451,119 @@ func (d *Decimal) Reduce(x *Decimal) (*Decimal, int) {
 	return d, nd
 }
 
+// Frexp breaks x into a mantissa and a power of ten. It sets d to the
+// mantissa, which has the coefficient and sign of x and satisfies
+// 0.1 <= |d| < 1, and returns d and the exponent e such that
+// x = d × 10**e: 123.45 is 0.12345 × 10**3. Trailing zeros are kept, so 1.20
+// is 0.120 × 10**1. If x is zero, infinite or NaN, d is set to x and e is
+// 0. Use e-1 for a mantissa in [1, 10), as in scientific notation.
+func (d *Decimal) Frexp(x *Decimal) (*Decimal, int64) {
+	if x.Form != Finite || x.IsZero() {
+		return d.Set(x), 0
+	}
+	nd := x.NumDigits()
+	e := int64(x.Exponent) + nd
+	d.Set(x)
+	d.Exponent = int32(-nd)
+	return d, e
+}
+
+// Ldexp sets d to x × 10**e and returns d. It is the inverse of Frexp. The
+// result is exact: only the exponent changes. An error is returned, and d
+// is unchanged, if the exponent of the result is out of range. Infinities
+// and NaNs are copied to d as they are.
+func (d *Decimal) Ldexp(x *Decimal, e int64) (*Decimal, error) {
+	if x.Form == Finite {
+		// Bound e first so that the sum below cannot overflow.
+		if e > MaxExponent-MinExponent || e < MinExponent-MaxExponent {
+			return d, errors.New(errExponentOutOfRangeStr)
+		}
+		if n := int64(x.Exponent) + e; n > MaxExponent || n < MinExponent {
+			return d, errors.New(errExponentOutOfRangeStr)
+		}
+	}
+	d.Set(x)
+	if d.Form == Finite {
+		d.Exponent += int32(e)
+	}
+	return d, nil
+}
+
+// Ceil sets d to the smallest integer not less than x and returns d. Like
+// the other rounding methods of Decimal, it needs no Context: the result is
+// exact, with as many digits as it takes. x is copied unchanged if it is
+// already an integer with an exponent of 0 or more, or if it is infinite or
+// NaN; otherwise the result has an exponent of 0.
+func (d *Decimal) Ceil(x *Decimal) *Decimal {
+	return d.roundInteger(x, RoundCeiling)
+}
+
+// Floor sets d to the largest integer not greater than x and returns d. It
+// is otherwise like Ceil.
+func (d *Decimal) Floor(x *Decimal) *Decimal {
+	return d.roundInteger(x, RoundFloor)
+}
+
+// roundInteger implements Ceil and Floor.
+func (d *Decimal) roundInteger(x *Decimal, rounding string) *Decimal {
+	if x.Form != Finite || x.Exponent >= 0 {
+		return d.Set(x)
+	}
+	// An exponent of 0 is always in range.
+	d.rescale(x, 0, rounding)
+	return d
+}
+
+// Trunc sets d to x truncated, that is rounded toward zero, to scale digits
+// after the decimal point and returns d. It is RoundTo with RoundDown.
+func (d *Decimal) Trunc(x *Decimal, scale int32) (*Decimal, error) {
+	return d.RoundTo(x, scale, RoundDown)
+}
+
+// RoundTo sets d to x rounded to scale digits after the decimal point and
+// returns d. rounding is one of the Round* constants, or the empty string
+// for RoundHalfUp as with Context. The result has an exponent of -scale,
+// with zeros appended to x if it has fewer digits after the decimal point,
+// as with Context.Quantize; but no Context is needed, since the number of
+// digits of the result is not limited. A negative scale rounds x to a
+// multiple of 10**-scale. An error is returned if -scale is out of the
+// package's exponent range, or so far below the exponent of x that the
+// zeros to append would not fit in it. Infinities and NaNs are copied to d.
+func (d *Decimal) RoundTo(x *Decimal, scale int32, rounding string) (*Decimal, error) {
+	if x.Form != Finite {
+		return d.Set(x), nil
+	}
+	if !d.rescale(x, -int64(scale), rounding) {
+		return d, errors.New(errExponentOutOfRangeStr)
+	}
+	return d, nil
+}
+
+// Clamp sets d to x constrained to the closed range [lo, hi] and returns d:
+// to lo if x < lo, to hi if x > hi, and to x otherwise, including when x
+// is equal to a bound but written differently, as 1.0 is to 1.00. A nil
+// bound leaves that side of the range open. An error is returned, and d is
+// unchanged, if lo > hi or a bound is NaN, since the range is then empty
+// or undefined; lo == hi is allowed. A NaN x is copied to d.
+func (d *Decimal) Clamp(x, lo, hi *Decimal) (*Decimal, error) {
+	for _, b := range []*Decimal{lo, hi} {
+		if b != nil && (b.Form == NaN || b.Form == NaNSignaling) {
+			return d, errors.Errorf("clamp: bound %s is NaN", b)
+		}
+	}
+	if lo != nil && hi != nil && lo.Cmp(hi) > 0 {
+		return d, errors.Errorf("clamp: lower bound %s is greater than upper bound %s", lo, hi)
+	}
+	switch {
+	case x.Form == NaN || x.Form == NaNSignaling:
+	case lo != nil && x.Cmp(lo) < 0:
+		x = lo
+	case hi != nil && x.Cmp(hi) > 0:
+		x = hi
+	}
+	return d.Set(x), nil
+}
+
 // Value implements the database/sql/driver.Valuer interface. It converts d to a
 // string.
 func (d Decimal) Value() (driver.Value, error) {
@@ -763,7 +1584,7 @@ func (d *Decimal) Scan(src interface{}) error {
 		d.SetInt64(src)
 		return nil
 	case float64:
-		_, err := d.SetFloat64(src)
+		_, _, err := d.SetString(strconv.FormatFloat(src, 'E', -1, 64))
 		return err
 	default:
 		return errors.Errorf("could not convert %T to Decimal", src)
@@ -776,15 +1597,154 @@ func (d *Decimal) UnmarshalText(b []byte) error {
 	return err
 }
 
-// MarshalText implements the encoding.TextMarshaler interface.
-func (d *Decimal) MarshalText() ([]byte, error) {
-	if d == nil {
-		return []byte("<nil>"), nil
-	}
+// MarshalText implements the encoding.TextMarshaler interface. It has a
+// value receiver so that Decimal fields and map values are marshaled as text
+// by encoding/json, encoding/xml and similar packages even when they are not
+// addressable. Those packages encode a nil *Decimal without calling it;
+// calling it directly on a nil *Decimal panics.
+func (d Decimal) MarshalText() ([]byte, error) {
 	return []byte(d.String()), nil
 }
 
-// NullDecimal represents a string that may be null. NullDecimal implements
+// MarshalJSONAsNumber controls the output of MarshalJSON. If false, the
+// default, decimals are marshaled as JSON strings, which every JSON decoder
+// reads without loss. If true, finite decimals are marshaled as JSON
+// numbers, which some decoders convert to float64. NaN and infinities are
+// always marshaled as strings since JSON numbers cannot represent them.
+// Modifying it is not safe during marshaling.
+var MarshalJSONAsNumber = false
+
+// MarshalJSON implements the json.Marshaler interface. See
+// MarshalJSONAsNumber. Like MarshalText it has a value receiver; a nil
+// *Decimal is encoded as null by encoding/json without calling it.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	s := d.String()
+	if MarshalJSONAsNumber && d.Form == Finite {
+		return []byte(s), nil
+	}
+	b := make([]byte, 0, len(s)+2)
+	b = append(b, '"')
+	b = append(b, s...)
+	return append(b, '"'), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It accepts both
+// JSON strings and JSON numbers regardless of MarshalJSONAsNumber. As with
+// other types, null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	_, _, err := d.SetString(s)
+	return err
+}
+
+// Flags of the first byte of the binary layout written by appendBinary.
+const (
+	binaryNegative = 1 << iota
+	binaryInfinite
+	binaryNaN
+	binaryNaNSignaling
+)
+
+// appendBinary appends the compact binary layout of d to b: a flag byte
+// holding the sign and form, followed for finite values by the exponent as
+// a zig-zag varint and the coefficient as big-endian bytes.
+func (d *Decimal) appendBinary(b []byte) []byte {
+	var flags byte
+	if d.Negative {
+		flags |= binaryNegative
+	}
+	switch d.Form {
+	case Infinite:
+		return append(b, flags|binaryInfinite)
+	case NaN:
+		return append(b, flags|binaryNaN)
+	case NaNSignaling:
+		return append(b, flags|binaryNaNSignaling)
+	}
+	var buf [binary.MaxVarintLen32]byte
+	b = append(b, flags)
+	b = append(b, buf[:binary.PutVarint(buf[:], int64(d.Exponent))]...)
+	return append(b, d.Coeff.Bytes()...)
+}
+
+// setBinary sets d to the value of the binary layout b written by
+// appendBinary.
+func (d *Decimal) setBinary(b []byte) error {
+	if len(b) == 0 {
+		return errors.New("empty decimal encoding")
+	}
+	flags := b[0]
+	d.Negative = flags&binaryNegative != 0
+	d.Exponent = 0
+	d.Coeff.SetInt64(0)
+	switch flags &^ binaryNegative {
+	case binaryInfinite:
+		d.Form = Infinite
+	case binaryNaN:
+		d.Form = NaN
+	case binaryNaNSignaling:
+		d.Form = NaNSignaling
+	case 0:
+		e, n := binary.Varint(b[1:])
+		if n <= 0 || e > math.MaxInt32 || e < math.MinInt32 {
+			return errors.New("invalid decimal exponent")
+		}
+		d.Form = Finite
+		d.Exponent = int32(e)
+		d.Coeff.SetBytes(b[1+n:])
+		return nil
+	default:
+		return errors.Errorf("invalid decimal flags %#x", flags)
+	}
+	if len(b) != 1 {
+		return errors.New("unexpected trailing data in decimal encoding")
+	}
+	return nil
+}
+
+// decimalBinaryVersion is the first byte of the MarshalBinary layout. It
+// allows the layout to change while older encodings remain decodable.
+const decimalBinaryVersion = 1
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The
+// layout is:
+//
+//     version  1 byte, currently 1
+//     flags    1 byte: 0x1 negative, 0x2 infinite, 0x4 NaN, 0x8 signaling NaN
+//     exponent zig-zag varint as written by binary.PutVarint, finite only
+//     coeff    big-endian unsigned bytes without leading zeros, finite only
+//
+// so that most values of up to a few digits take three or four bytes.
+func (d *Decimal) MarshalBinary() ([]byte, error) {
+	return d.appendBinary([]byte{decimalBinaryVersion}), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (d *Decimal) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 || b[0] != decimalBinaryVersion {
+		return errors.New("unsupported decimal encoding version")
+	}
+	return d.setBinary(b[1:])
+}
+
+// GobEncode implements the gob.GobEncoder interface. It uses the layout of
+// MarshalBinary, which does not depend on the fields of Decimal or big.Int.
+func (d *Decimal) GobEncode() ([]byte, error) {
+	return d.MarshalBinary()
+}
+
+// GobDecode implements the gob.GobDecoder interface.
+func (d *Decimal) GobDecode(b []byte) error {
+	return errors.Wrap(d.UnmarshalBinary(b), "gob")
+}
+
+// NullDecimal represents a decimal that may be null. NullDecimal implements
 // the database/sql.Scanner interface so it can be used as a scan destination:
 //
 //  var d NullDecimal
@@ -796,6 +1756,8 @@ func (d *Decimal) MarshalText() ([]byte, error) {
 //     // NULL value
 //  }
 //
+// NULL values are marshaled to JSON as null, and JSON null unmarshals to a
+// NULL value.
 type NullDecimal struct {
 	Decimal Decimal
 	Valid   bool // Valid is true if Decimal is not NULL
@@ -818,3 +1780,47 @@ func (nd NullDecimal) Value() (driver.Value, error) {
 	}
 	return nd.Decimal.Value()
 }
+
+// GobEncode implements the gob.GobEncoder interface. A NULL value is
+// encoded as no bytes, otherwise the layout is that of Decimal.GobEncode.
+func (nd *NullDecimal) GobEncode() ([]byte, error) {
+	if !nd.Valid {
+		return []byte{}, nil
+	}
+	return nd.Decimal.GobEncode()
+}
+
+// GobDecode implements the gob.GobDecoder interface.
+func (nd *NullDecimal) GobDecode(b []byte) error {
+	if len(b) == 0 {
+		nd.Decimal = Decimal{}
+		nd.Valid = false
+		return nil
+	}
+	nd.Valid = true
+	return nd.Decimal.GobDecode(b)
+}
+
+// MarshalJSON implements the json.Marshaler interface. A NULL value is
+// marshaled as null, otherwise the output is that of Decimal.MarshalJSON.
+func (nd NullDecimal) MarshalJSON() ([]byte, error) {
+	if !nd.Valid {
+		return []byte("null"), nil
+	}
+	return nd.Decimal.MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Unlike
+// Decimal.UnmarshalJSON, null sets nd to a NULL value.
+func (nd *NullDecimal) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		nd.Decimal = Decimal{}
+		nd.Valid = false
+		return nil
+	}
+	if err := nd.Decimal.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	nd.Valid = true
+	return nil
+}
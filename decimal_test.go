@@ -15,12 +15,21 @@
 package apd
 
 import (
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"math"
 	"math/big"
+	"math/rand"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 	"unsafe"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -82,6 +91,71 @@ func TestNewWithBigInt(t *testing.T) {
 	}
 }
 
+func TestNewFromComponents(t *testing.T) {
+	tests := []struct {
+		neg   bool
+		coeff string
+		exp   int32
+		prec  uint32
+		s     string
+		flags Condition
+	}{
+		{coeff: "0", s: "0"},
+		{neg: true, coeff: "0", exp: -2, s: "-0.00"},
+		{neg: true, coeff: "150", exp: -2, s: "-1.50"},
+		{coeff: "123456789012345678901234567890", exp: 3, s: "1.23456789012345678901234567890E+32"},
+		{coeff: "123456", exp: -3, prec: 4, s: "123.5", flags: Inexact | Rounded},
+		{neg: true, coeff: "1", exp: MinExponent, s: "-1E-100000"},
+		{coeff: "1", exp: MaxExponent, s: "1E+100000"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			b, ok := new(big.Int).SetString(tc.coeff, 10)
+			if !ok {
+				t.Fatal("bad bigint")
+			}
+			c := BaseContext.WithPrecision(tc.prec)
+			d, res, err := c.NewFromComponents(tc.neg, b, tc.exp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.s {
+				t.Fatalf("expected %s, got %s", tc.s, s)
+			}
+			if res != tc.flags {
+				t.Fatalf("expected flags %s, got %s", tc.flags, res)
+			}
+			// Verify that changing b doesn't change d.
+			b.SetInt64(7)
+			if s := d.String(); s != tc.s {
+				t.Fatalf("expected %s after changing coeff, got %s", tc.s, s)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		coeff *big.Int
+		exp   int32
+	}{
+		{coeff: nil},
+		{coeff: big.NewInt(-1)},
+		{coeff: big.NewInt(1), exp: MaxExponent + 1},
+		{coeff: big.NewInt(10), exp: MaxExponent},
+		{coeff: big.NewInt(1), exp: MinExponent - 1},
+	} {
+		if d, _, err := NewFromComponents(false, tc.coeff, tc.exp); err == nil {
+			t.Errorf("%v, %d: expected error, got %s", tc.coeff, tc.exp, d)
+		}
+	}
+
+	// The Context variant enforces the context's exponent limits.
+	c := BaseContext.WithPrecision(5)
+	c.MaxExponent = 10
+	if _, _, err := c.NewFromComponents(false, big.NewInt(1), 11); err == nil {
+		t.Error("expected overflow")
+	}
+}
+
 func TestUpscale(t *testing.T) {
 	tests := []struct {
 		x, y *Decimal
@@ -154,6 +228,12 @@ func TestCmp(t *testing.T) {
 		{x: ".1e1", y: "100e-2", c: 0},
 		{x: "1", y: ".1e1", c: 0},
 		{x: "1", y: "1", c: 0},
+
+		{x: "12345678901234567890123.45", y: "12345678901234567890123.4500001", c: -1},
+		{x: "-12345678901234567890123.45", y: "-12345678901234567890123.4500001", c: 1},
+		{x: "12345678901234567890123.4500", y: "12345678901234567890123.45", c: 0},
+		{x: "9.99999999999999999999999", y: "1E+1", c: -1},
+		{x: "-1E+100", y: "-99999999999999999999999", c: -1},
 	}
 	for _, tc := range tests {
 		t.Run(fmt.Sprintf("%s, %s", tc.x, tc.y), func(t *testing.T) {
@@ -167,6 +247,27 @@ func TestCmp(t *testing.T) {
 	}
 }
 
+func TestCmpAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	for _, tc := range [][2]string{
+		{"12345678901234567890123.45", "12345678901234567890123.4500001"},
+		{"1.5E+30", "-2"},
+		{"123456789012345678901234567890", "1.23E-10"},
+	} {
+		x := newDecimal(t, testCtx, tc[0])
+		y := newDecimal(t, testCtx, tc[1])
+		x.Cmp(y)
+		if allocs := testing.AllocsPerRun(100, func() {
+			x.Cmp(y)
+			y.Cmp(x)
+		}); allocs != 0 {
+			t.Errorf("%s, %s: expected no allocations, got %v", tc[0], tc[1], allocs)
+		}
+	}
+}
+
 func TestModf(t *testing.T) {
 	tests := []struct {
 		x string
@@ -245,6 +346,41 @@ func TestModf(t *testing.T) {
 	// Ensure we don't panic on both nil.
 	a := new(Decimal)
 	a.Modf(nil, nil)
+
+	// Infinities and NaNs, into destinations that held other forms.
+	for _, tc := range []struct{ x, i, f string }{
+		{x: "Infinity", i: "Infinity", f: "NaN"},
+		{x: "-Infinity", i: "-Infinity", f: "NaN"},
+		{x: "NaN", i: "NaN", f: "NaN"},
+		{x: "12.34", i: "12", f: "0.34"},
+		{x: "0.05", i: "0", f: "0.05"},
+		{x: "1E+3", i: "1E+3", f: "0"},
+	} {
+		integ, frac := newDecimal(t, testCtx, "-Infinity"), newDecimal(t, testCtx, "NaN")
+		newDecimal(t, testCtx, tc.x).Modf(integ, frac)
+		if integ.String() != tc.i || frac.String() != tc.f {
+			t.Errorf("%s: expected %s and %s, got %s and %s", tc.x, tc.i, tc.f, integ, frac)
+		}
+	}
+
+	// In place.
+	for _, s := range []string{"-12.34", "-1E+3", "-0.05"} {
+		integ, frac := new(Decimal), new(Decimal)
+		x := newDecimal(t, testCtx, s)
+		x.Modf(integ, frac)
+		x2 := newDecimal(t, testCtx, s)
+		f2 := new(Decimal)
+		x2.Modf(x2, f2)
+		if x2.CmpTotal(integ) != 0 || f2.CmpTotal(frac) != 0 {
+			t.Errorf("%s: expected %s and %s, got %s and %s", s, integ, frac, x2, f2)
+		}
+		x2 = newDecimal(t, testCtx, s)
+		i2 := new(Decimal)
+		x2.Modf(i2, x2)
+		if i2.CmpTotal(integ) != 0 || x2.CmpTotal(frac) != 0 {
+			t.Errorf("%s: expected %s and %s, got %s and %s", s, integ, frac, i2, x2)
+		}
+	}
 }
 
 func TestInt64(t *testing.T) {
@@ -284,6 +420,73 @@ func TestInt64(t *testing.T) {
 	}
 }
 
+func TestUint64(t *testing.T) {
+	tests := []struct {
+		x        string
+		u, trunc uint64
+		err      bool
+		truncErr bool
+	}{
+		{x: "0", u: 0, trunc: 0},
+		{x: "-0", u: 0, trunc: 0},
+		{x: "12.3e3", u: 12300, trunc: 12300},
+		{x: "18446744073709551615", u: math.MaxUint64, trunc: math.MaxUint64},
+		{x: "18446744073709551615.9", err: true, trunc: math.MaxUint64},
+		{x: "18446744073709551616", err: true, truncErr: true},
+		{x: "1E+100", err: true, truncErr: true},
+		{x: "2.7", err: true, trunc: 2},
+		{x: "-0.5", err: true, trunc: 0},
+		{x: "-1", err: true, truncErr: true},
+		{x: "NaN", err: true, truncErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			u, err := x.Uint64()
+			if tc.err != (err != nil) {
+				t.Fatalf("expected error: %v, got error: %v", tc.err, err)
+			}
+			if err == nil && u != tc.u {
+				t.Fatalf("expected: %v, got %v", tc.u, u)
+			}
+			u, err = x.TruncUint64()
+			if tc.truncErr != (err != nil) {
+				t.Fatalf("trunc: expected error: %v, got error: %v", tc.truncErr, err)
+			}
+			if err == nil && u != tc.trunc {
+				t.Fatalf("trunc: expected: %v, got %v", tc.trunc, u)
+			}
+		})
+	}
+}
+
+func TestTruncInt64(t *testing.T) {
+	tests := []struct {
+		x   string
+		i   int64
+		err bool
+	}{
+		{x: "0.12e1", i: 1},
+		{x: "-2.9", i: -2},
+		{x: "1e-1", i: 0},
+		{x: "12.3e3", i: 12300},
+		{x: "-9223372036854775808.7", i: math.MinInt64},
+		{x: "9223372036854775808", err: true},
+		{x: "Inf", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			i, err := newDecimal(t, testCtx, tc.x).TruncInt64()
+			if tc.err != (err != nil) {
+				t.Fatalf("expected error: %v, got error: %v", tc.err, err)
+			}
+			if err == nil && i != tc.i {
+				t.Fatalf("expected: %v, got %v", tc.i, i)
+			}
+		})
+	}
+}
+
 func TestQuoErr(t *testing.T) {
 	tests := []struct {
 		x, y string
@@ -350,6 +553,421 @@ func TestFloat64(t *testing.T) {
 	}
 }
 
+func TestFloat64Exact(t *testing.T) {
+	tests := []struct {
+		s     string
+		f     float64
+		exact bool
+		err   bool
+	}{
+		{s: "0", f: 0, exact: true},
+		{s: "-0", f: math.Copysign(0, -1), exact: true},
+		{s: "1.5", f: 1.5, exact: true},
+		{s: "-2.5E+10", f: -2.5e10, exact: true},
+		{s: "0.1", f: 0.1},
+		{s: "9007199254740993", f: 9007199254740992},
+		{s: "9007199254740995", f: 9007199254740996},
+		{s: "4.940656458412465441765687928682213723651E-324", f: math.SmallestNonzeroFloat64},
+		{s: "1E-400", f: 0},
+		{s: "-1E-400", f: math.Copysign(0, -1)},
+		{s: "1.7976931348623157E+308", f: math.MaxFloat64},
+		{s: "1.8E+308", f: math.Inf(1), err: true},
+		{s: "-1E+1000", f: math.Inf(-1), err: true},
+		{s: "Infinity", f: math.Inf(1), exact: true},
+		{s: "-Infinity", f: math.Inf(-1), exact: true},
+		{s: "NaN", f: math.NaN()},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			d := newDecimal(t, testCtx, tc.s)
+			f, exact, err := d.Float64Exact()
+			if (err != nil) != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Float64bits(f) != math.Float64bits(tc.f) && !(math.IsNaN(f) && math.IsNaN(tc.f)) {
+				t.Errorf("expected %v, got %v", tc.f, f)
+			}
+			if exact != tc.exact {
+				t.Errorf("expected exact %v, got %v", tc.exact, exact)
+			}
+		})
+	}
+}
+
+func TestSetFloat64(t *testing.T) {
+	tests := []struct {
+		f float64
+		s string
+	}{
+		{f: 0, s: "0"},
+		{f: math.Copysign(0, -1), s: "-0"},
+		{f: 1, s: "1"},
+		{f: -2.5, s: "-2.5"},
+		{f: 0.1, s: "0.1000000000000000055511151231257827021181583404541015625"},
+		{f: 1e23, s: "99999999999999991611392"},
+		{f: 1 << 60, s: "1152921504606846976"},
+		{f: math.SmallestNonzeroFloat64, s: "4.940656458412465441765687928682213723650598026143247644255856825006755072702087518652998363616359923797965646954457177309266567103559397963987747960107818781263007131903114045278458171678489821036887186360569987307230500063874091535649843873124733972731696151400317153853980741262385655911710266585566867681870395603106249319452715914924553293054565444011274801297099995419319894090804165633245247571478690147267801593552386115501348035264934720193790268107107491703332226844753335720832431936092382893458368060106011506169809753078342277318329247904982524730776375927247874656084778203734469699533647017972677717585125660551199131504891101451037862738167250955837389733598993664809941164205702637090279242767544565229087538682506419718265533447265625E-324"},
+		{f: math.Inf(-1), s: "-Infinity"},
+		{f: math.NaN(), s: "NaN"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.f), func(t *testing.T) {
+			d, err := new(Decimal).SetFloat64(tc.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.s {
+				t.Fatalf("expected %s, got %s", tc.s, s)
+			}
+			if d.Form == NaN {
+				return
+			}
+			f, exact, err := d.Float64Exact()
+			if err != nil || !exact || math.Float64bits(f) != math.Float64bits(tc.f) {
+				t.Fatalf("round trip: got %v, %v, %v", f, exact, err)
+			}
+		})
+	}
+
+	c := BaseContext.WithPrecision(5)
+	d, res, err := c.SetFloat64(new(Decimal), 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "0.10000" {
+		t.Fatalf("expected 0.10000, got %s", s)
+	}
+	if res != Inexact|Rounded {
+		t.Fatalf("expected inexact, rounded, got %s", res)
+	}
+	if _, res, _ = c.SetFloat64(d, 0.5); res != 0 {
+		t.Fatalf("expected no flags, got %s", res)
+	}
+}
+
+func TestNewFromFloat(t *testing.T) {
+	tests := []struct {
+		f float64
+		s string
+	}{
+		{f: 0, s: "0"},
+		{f: math.Copysign(0, -1), s: "-0"},
+		{f: 0.1, s: "0.1"},
+		{f: -123.456, s: "-123.456"},
+		{f: 100, s: "100"},
+		{f: 1e20, s: "100000000000000000000"},
+		{f: 1e21, s: "1E+21"},
+		{f: 1e23, s: "1E+23"},
+		{f: 1.0 / 3, s: "0.3333333333333333"},
+		{f: math.MaxFloat64, s: "1.7976931348623157E+308"},
+		{f: math.SmallestNonzeroFloat64, s: "5E-324"},
+		{f: math.Inf(1), s: "Infinity"},
+		{f: math.NaN(), s: "NaN"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			d := NewFromFloat(tc.f)
+			if s := d.String(); s != tc.s {
+				t.Fatalf("expected %s, got %s", tc.s, s)
+			}
+			if f, err := d.Float64(); err != nil || f != tc.f && !math.IsNaN(f) {
+				t.Fatalf("round trip: got %v, %v", f, err)
+			}
+		})
+	}
+}
+
+func TestRat(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		prec     uint32
+		s        string
+		flags    Condition
+		err      bool
+	}{
+		{num: 0, den: 1, s: "0"},
+		{num: 3, den: 1, s: "3"},
+		{num: -1, den: 8, s: "-0.125"},
+		{num: 7, den: 20, s: "0.35"},
+		{num: 1, den: 1 << 20, s: "9.5367431640625E-7"},
+		{num: 1, den: 3, err: true},
+		{num: 1, den: 3, prec: 5, s: "0.33333", flags: Inexact | Rounded},
+		{num: -2, den: 7, prec: 5, s: "-0.28571", flags: Inexact | Rounded},
+		{num: 12345, den: 4, prec: 3, s: "3.09E+3", flags: Inexact | Rounded},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tc.num, tc.den), func(t *testing.T) {
+			r := big.NewRat(tc.num, tc.den)
+			c := BaseContext.WithPrecision(tc.prec)
+			d, res, err := c.SetRat(new(Decimal), r)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.s {
+				t.Fatalf("expected %s, got %s", tc.s, s)
+			}
+			if res != tc.flags {
+				t.Fatalf("expected flags %s, got %s", tc.flags, res)
+			}
+			back, err := d.Rat()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.flags == 0 && back.Cmp(r) != 0 {
+				t.Fatalf("expected %s, got %s", r, back)
+			}
+		})
+	}
+
+	if _, err := newDecimal(t, testCtx, "Infinity").Rat(); err == nil {
+		t.Fatal("expected error")
+	}
+	r, err := newDecimal(t, testCtx, "-1.50E+3").Rat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := r.String(); s != "-1500/1" {
+		t.Fatalf("expected -1500/1, got %s", s)
+	}
+}
+
+func TestNumDenom(t *testing.T) {
+	tests := []struct {
+		x        string
+		num, den string
+	}{
+		{x: "0", num: "0", den: "1"},
+		{x: "-0.000", num: "0", den: "1"},
+		{x: "1.50", num: "3", den: "2"},
+		{x: "-0.125", num: "-1", den: "8"},
+		{x: "0.35", num: "7", den: "20"},
+		{x: "1.2E+3", num: "1200", den: "1"},
+		{x: "-7", num: "-7", den: "1"},
+		{x: "123456789.0000", num: "123456789", den: "1"},
+		{x: "1E-30", num: "1", den: "1000000000000000000000000000000"},
+		{x: "2.5E-29", num: "1", den: "40000000000000000000000000000"},
+	}
+	for _, tc := range tests {
+		x := newDecimal(t, testCtx, tc.x)
+		num, den, err := x.NumDenom()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.x, err)
+		}
+		if num.String() != tc.num || den.String() != tc.den {
+			t.Errorf("%s: expected %s/%s, got %s/%s", tc.x, tc.num, tc.den, num, den)
+		}
+		// The fraction must agree with Rat, which big.Rat keeps reduced.
+		r, err := x.Rat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Num().Cmp(num) != 0 || r.Denom().Cmp(den) != 0 {
+			t.Errorf("%s: %s/%s does not match Rat %s", tc.x, num, den, r)
+		}
+		// And SetRat must give the value back.
+		back, _, err := new(Decimal).SetRat(new(big.Rat).SetFrac(num, den))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back.Cmp(x) != 0 {
+			t.Errorf("%s: got %s back", tc.x, back)
+		}
+	}
+
+	// The results must not share memory with d or the table of powers.
+	x := newDecimal(t, testCtx, "0.001")
+	_, den, err := x.NumDenom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	den.SetInt64(7)
+	if _, den, _ = x.NumDenom(); den.String() != "1000" {
+		t.Errorf("expected 1000, got %s", den)
+	}
+
+	for _, s := range []string{"Infinity", "NaN"} {
+		if _, _, err := newDecimal(t, testCtx, s).NumDenom(); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestHypot(t *testing.T) {
+	tests := []struct {
+		x, y  string
+		r     string
+		exact bool
+	}{
+		{x: "3", y: "4", r: "5", exact: true},
+		{x: "-5", y: "12", r: "13", exact: true},
+		{x: "0", y: "-2.50", r: "2.50", exact: true},
+		{x: "1", y: "1", r: "1.414213562373095"},
+		{x: "1E+60000", y: "1E+60000", r: "1.414213562373095E+60000"},
+		{x: "1E-60000", y: "2E-60000", r: "2.236067977499790E-60000"},
+		{x: "1.5", y: "0.000002", r: "1.500000000001333"},
+		{x: "1E+100", y: "1", r: "1.000000000000000E+100"},
+		{x: "-Infinity", y: "NaN", r: "Infinity", exact: true},
+		{x: "NaN", y: "1", r: "NaN", exact: true},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s", tc.x, tc.y), func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			y := newDecimal(t, testCtx, tc.y)
+			d := new(Decimal)
+			res, err := c.Hypot(d, x, y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := newDecimal(t, testCtx, tc.r)
+			if d.CmpTotal(r) != 0 && d.Cmp(r) != 0 {
+				t.Fatalf("expected %s, got %s", r, d)
+			}
+			if res.Inexact() == tc.exact {
+				t.Fatalf("unexpected flags: %s", res)
+			}
+		})
+	}
+}
+
+func TestHypotRounding(t *testing.T) {
+	tests := []struct {
+		x, y     string
+		rounding string
+		r        string
+	}{
+		{x: "1", y: "1", rounding: RoundCeiling, r: "1.4143"},
+		{x: "1", y: "1", rounding: RoundFloor, r: "1.4142"},
+		{x: "1", y: "1E-10", rounding: RoundCeiling, r: "1.0001"},
+		{x: "1", y: "1E-10", rounding: RoundUp, r: "1.0001"},
+		{x: "1", y: "1E-10", rounding: RoundDown, r: "1.0000"},
+		{x: "1", y: "1E-99999", rounding: RoundCeiling, r: "1.0001"},
+		{x: "1.00005", y: "1E-20", rounding: RoundHalfEven, r: "1.0001"},
+		{x: "3", y: "4", rounding: RoundCeiling, r: "5"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s, %s", tc.x, tc.y, tc.rounding), func(t *testing.T) {
+			c := BaseContext.WithPrecision(5)
+			c.Rounding = tc.rounding
+			d := new(Decimal)
+			if _, err := c.Hypot(d, newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.y)); err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Fatalf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+
+	// Round05Up at a high precision followed by rounding to a lower one
+	// gives the correctly rounded result.
+	hc := BaseContext.WithPrecision(60)
+	hc.Rounding = Round05Up
+	for rounding := range Roundings {
+		c := BaseContext.WithPrecision(5)
+		c.Rounding = rounding
+		for _, x := range []string{"1", "2.5", "0.3", "123456789"} {
+			for _, y := range []string{"1", "0.7", "1E-3", "1E-8", "12345"} {
+				a, b := newDecimal(t, testCtx, x), newDecimal(t, testCtx, y)
+				d, expect := new(Decimal), new(Decimal)
+				if _, err := c.Hypot(d, a, b); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := hc.Hypot(expect, a, b); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := c.Round(expect, expect); err != nil {
+					t.Fatal(err)
+				}
+				if d.Cmp(expect) != 0 {
+					t.Errorf("%s: hypot(%s, %s): expected %s, got %s", rounding, x, y, expect, d)
+				}
+			}
+		}
+	}
+}
+
+func TestAGM(t *testing.T) {
+	tests := []struct {
+		x, y string
+		r    string
+	}{
+		{x: "1", y: "1.414213562373095048801689", r: "1.198140234735592"},
+		{x: "24", y: "6", r: "13.45817148172562"},
+		{x: "6", y: "24", r: "13.45817148172562"},
+		{x: "0.001", y: "0.002", r: "0.001456791031046907"},
+		{x: "1", y: "1E-100", r: "0.006781055745575451"},
+		{x: "1E+90000", y: "1E-90000", r: "3.789921639781839E+89994"},
+		{x: "2.5", y: "2.5", r: "2.5"},
+		{x: "0", y: "3", r: "0"},
+		{x: "Infinity", y: "3", r: "Infinity"},
+		{x: "Infinity", y: "0", r: "NaN"},
+		{x: "-1", y: "3", r: "NaN"},
+		{x: "NaN", y: "1", r: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s", tc.x, tc.y), func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			y := newDecimal(t, testCtx, tc.y)
+			d := new(Decimal)
+			if _, err := c.AGM(d, x, y); err != nil {
+				t.Fatal(err)
+			}
+			r := newDecimal(t, testCtx, tc.r)
+			if d.CmpTotal(r) != 0 && d.Cmp(r) != 0 {
+				t.Fatalf("expected %s, got %s", r, d)
+			}
+		})
+	}
+}
+
+func TestCompound(t *testing.T) {
+	tests := []struct {
+		rate, periods string
+		r             string
+		flags         Condition
+	}{
+		{rate: "0.05", periods: "2", r: "1.1025"},
+		{rate: "0.05", periods: "10", r: "1.628894626777441", flags: Inexact | Rounded},
+		{rate: "0.07", periods: "12", r: "2.252191588960823", flags: Inexact | Rounded},
+		{rate: "1", periods: "10", r: "1024"},
+		{rate: "1", periods: "100", r: "1.267650600228229E+30", flags: Inexact | Rounded},
+		{rate: "9", periods: "20", r: "1E+20"},
+		{rate: "0.0001", periods: "1000000", r: "2.674710993142140E+43", flags: Inexact | Rounded},
+		{rate: "0.25", periods: "-2", r: "0.64"},
+		{rate: "0.1", periods: "-2", r: "0.8264462809917355", flags: Inexact | Rounded},
+		{rate: "-0.1", periods: "-3", r: "1.371742112482853", flags: Inexact | Rounded},
+		{rate: "0.01", periods: "0", r: "1"},
+		{rate: "-1", periods: "3", r: "0"},
+		{rate: "0.5", periods: "0.5", r: "1.224744871391589", flags: Inexact | Rounded},
+		{rate: "NaN", periods: "1", r: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s", tc.rate, tc.periods), func(t *testing.T) {
+			d := new(Decimal)
+			res, err := c.Compound(d, newDecimal(t, testCtx, tc.rate), newDecimal(t, testCtx, tc.periods))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
+
 func TestCeil(t *testing.T) {
 	tests := map[float64]int64{
 		0:    0,
@@ -576,29 +1194,278 @@ func TestFormatFlags(t *testing.T) {
 	}
 }
 
-func TestContextSetStringt(t *testing.T) {
+func TestFormatPrecision(t *testing.T) {
 	tests := []struct {
-		s      string
-		c      *Context
-		expect string
+		d   string
+		fmt string
+		out string
 	}{
-		{
-			s:      "1.234",
-			c:      &BaseContext,
-			expect: "1.234",
-		},
-		{
-			s:      "1.234",
-			c:      BaseContext.WithPrecision(2),
-			expect: "1.2",
-		},
+		{d: "1.25", fmt: "%.1f", out: "1.2"},
+		{d: "1.35", fmt: "%.1f", out: "1.4"},
+		{d: "-1.25", fmt: "%.0f", out: "-1"},
+		{d: "1.5", fmt: "%.3f", out: "1.500"},
+		{d: "1E+3", fmt: "%.1f", out: "1000.0"},
+		{d: "0.0004", fmt: "%.3f", out: "0.000"},
+		{d: "-0.05", fmt: "%.1f", out: "-0.0"},
+		{d: "9.99", fmt: "%.1f", out: "10.0"},
+		{d: "0.1234567890123456789", fmt: "%.18f", out: "0.123456789012345679"},
+		{d: "9.99", fmt: "%.1e", out: "1.0e+1"},
+		{d: "123456", fmt: "%.3e", out: "1.235e+5"},
+		{d: "1.5", fmt: "%.3E", out: "1.500E+0"},
+		{d: "0", fmt: "%.2e", out: "0.00e+0"},
+		{d: "-0.0", fmt: "%.1e", out: "-0.0e-1"},
+		{d: "123456", fmt: "%.2g", out: "1.2e+5"},
+		{d: "123456", fmt: "%.2v", out: "1.2E+5"},
+		{d: "1.5", fmt: "%.3g", out: "1.5"},
+		{d: "9.99", fmt: "%.2G", out: "10"},
+		{d: "0.00012345", fmt: "%.0g", out: "0.0001"},
+		{d: "1.25", fmt: "%08.1f", out: "000001.2"},
+		{d: "-1.25", fmt: "%-8.1f|", out: "-1.2    |"},
+		{d: "1.25", fmt: "%+.1e", out: "+1.2e+0"},
+		{d: "NaN", fmt: "%.2f", out: "NaN"},
+		{d: "-Inf", fmt: "%8.2e", out: "-Infinity"},
 	}
-	for i, tc := range tests {
-		t.Run(fmt.Sprintf("%d: %s", i, tc.s), func(t *testing.T) {
-			d := new(Decimal)
-			if _, _, err := tc.c.SetString(d, tc.s); err != nil {
-				t.Fatal(err)
-			}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s: %s", tc.d, tc.fmt), func(t *testing.T) {
+			d := newDecimal(t, &BaseContext, tc.d)
+			s := fmt.Sprintf(tc.fmt, d)
+			if s != tc.out {
+				t.Fatalf("expected %q, got %q", tc.out, s)
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		d    string
+		fmt  byte
+		prec int
+		out  string
+	}{
+		{d: "1.25", fmt: 'f', prec: -1, out: "1.25"},
+		{d: "1.25", fmt: 'f', prec: 1, out: "1.2"},
+		{d: "1.25", fmt: 'f', prec: 4, out: "1.2500"},
+		{d: "-123.456", fmt: 'e', prec: 2, out: "-1.23e+2"},
+		{d: "123456789012345678901234567890", fmt: 'G', prec: -1, out: "123456789012345678901234567890"},
+		{d: "123456789012345678901234567890", fmt: 'G', prec: 5, out: "1.2346E+29"},
+		{d: "1E-7", fmt: 'g', prec: 3, out: "1e-7"},
+		{d: "NaN", fmt: 'f', prec: 2, out: "NaN"},
+		{d: "1", fmt: 'x', prec: -1, out: "%x"},
+	}
+	for _, tc := range tests {
+		d := newDecimal(t, testCtx, tc.d)
+		buf := d.Append([]byte("x="), tc.fmt, tc.prec)
+		if s := string(buf); s != "x="+tc.out {
+			t.Errorf("%s, %c, %d: expected x=%s, got %s", tc.d, tc.fmt, tc.prec, tc.out, s)
+		}
+	}
+
+	d := newDecimal(t, testCtx, "-1234.5678")
+	b, err := d.AppendText([]byte("v:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "v:-1234.5678" {
+		t.Fatalf("unexpected %s", s)
+	}
+
+	buf := make([]byte, 0, 64)
+	if n := testing.AllocsPerRun(100, func() {
+		buf = d.Append(buf[:0], 'G', -1)
+	}); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+}
+
+func TestAppendCoeff(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var vals []*big.Int
+	for n := int64(18); n < 1000; n += 19 {
+		// Powers of ten and their neighbours cross the chunk boundaries.
+		p := new(big.Int).Set(tableExp10(n, nil))
+		vals = append(vals, p, new(big.Int).Sub(p, bigOne), new(big.Int).Add(p, bigOne))
+	}
+	for w := uint(1); w <= 2*chunkedConvMaxWords; w++ {
+		vals = append(vals, new(big.Int).Rand(rng, new(big.Int).Lsh(bigOne, 64*w)))
+	}
+	for _, v := range vals {
+		expect := v.String()
+		if s := string(appendCoeff([]byte("x"), v)); s != "x"+expect {
+			t.Fatalf("expected x%s, got %s", expect, s)
+		}
+	}
+}
+
+func TestAppendAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	d := newDecimal(t, testCtx, "-1234567890123456789012345678901234567890123456789.0123456789E-20")
+	buf := make([]byte, 0, 128)
+	buf = d.Append(buf[:0], 'G', -1)
+	if n := testing.AllocsPerRun(100, func() {
+		buf = d.Append(buf[:0], 'G', -1)
+	}); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+}
+
+func TestStringFixed(t *testing.T) {
+	tests := []struct {
+		d        string
+		n        int32
+		rounding string
+		out      string
+	}{
+		{d: "1.5", n: 2, out: "1.50"},
+		{d: "1.005", n: 2, out: "1.01"},
+		{d: "1.005", n: 2, rounding: RoundHalfEven, out: "1.00"},
+		{d: "-1.005", n: 2, rounding: RoundFloor, out: "-1.01"},
+		{d: "2.5", n: 0, rounding: RoundHalfEven, out: "2"},
+		{d: "1E+3", n: 1, out: "1000.0"},
+		{d: "1.23E-10", n: 3, out: "0.000"},
+		{d: "-0.001", n: 2, out: "-0.00"},
+		{d: "1234.5", n: -2, out: "1200"},
+		{d: "1250", n: -2, rounding: RoundHalfEven, out: "1200"},
+		{d: "NaN", n: 2, out: "NaN"},
+		{d: "-Infinity", n: 2, out: "-Infinity"},
+	}
+	for _, tc := range tests {
+		d := newDecimal(t, testCtx, tc.d)
+		if s := d.StringFixed(tc.n, tc.rounding); s != tc.out {
+			t.Errorf("%s, %d, %q: expected %s, got %s", tc.d, tc.n, tc.rounding, tc.out, s)
+		}
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		s        string
+		min, max int32
+		r        string
+	}{
+		{s: "1E+3", min: -6, max: 20, r: "1000"},
+		{s: "1.23E+20", min: -6, max: 20, r: "123000000000000000000"},
+		{s: "1.23E+21", min: -6, max: 20, r: "1.23E+21"},
+		{s: "0.00123", min: -6, max: 20, r: "0.00123"},
+		{s: "0.00123", min: -2, max: 20, r: "1.23E-3"},
+		{s: "-12.50", min: -2, max: 1, r: "-12.50"},
+		{s: "125.0", min: -2, max: 1, r: "1.250E+2"},
+		{s: "0", min: -6, max: 20, r: "0"},
+		{s: "0E+5", min: -6, max: 20, r: "0"},
+		{s: "-0E+25", min: -6, max: 20, r: "-0E+25"},
+		{s: "0.000", min: -2, max: 20, r: "0E-3"},
+		{s: "-Infinity", min: -6, max: 20, r: "-Infinity"},
+		{s: "NaN", min: -6, max: 20, r: "NaN"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d/%d", tc.s, tc.min, tc.max), func(t *testing.T) {
+			d := newDecimal(t, testCtx, tc.s)
+			f := Formatter{MinExponent: tc.min, MaxExponent: tc.max}
+			if s := f.Format(d); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+}
+
+func TestShortestString(t *testing.T) {
+	tests := []struct {
+		s    string
+		prec uint32
+		r    string
+	}{
+		{s: "1.50", r: "1.5"},
+		{s: "100", r: "100"},
+		{s: "1000", r: "1E3"},
+		{s: "10000", r: "1E4"},
+		{s: "12300000", r: "1.23E7"},
+		{s: "0.01", r: "0.01"},
+		{s: "0.001", r: "1E-3"},
+		{s: "0.0001", r: "1E-4"},
+		{s: "0.00012", r: "1.2E-4"},
+		{s: "0.0012", r: "0.0012"},
+		{s: "-0.000", r: "-0"},
+		{s: "0E+10", r: "0"},
+		{s: "123.4500", r: "123.45"},
+		{s: "1.23456789", prec: 4, r: "1.235"},
+		{s: "99999", prec: 3, r: "1E5"},
+		{s: "-Infinity", r: "-Infinity"},
+		{s: "NaN", r: "NaN"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			c := BaseContext.WithPrecision(tc.prec)
+			d := newDecimal(t, testCtx, tc.s)
+			s := c.ShortestString(d)
+			if s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if d.Form != Finite {
+				return
+			}
+			back, _, err := c.NewFromString(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := new(Decimal)
+			if _, err := c.Round(expect, d); err != nil {
+				t.Fatal(err)
+			}
+			if back.Cmp(expect) != 0 {
+				t.Errorf("%s does not round-trip: got %s", s, back)
+			}
+		})
+	}
+}
+
+func TestFmtScanner(t *testing.T) {
+	var a, b, c Decimal
+	var n int
+	if _, err := fmt.Sscan(" 1.5e3 -0.25\n7", a.FmtScanner(), b.FmtScanner(), &n); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != "1.5E+3" || b.String() != "-0.25" || n != 7 {
+		t.Fatalf("unexpected %s %s %d", &a, &b, n)
+	}
+	if _, err := fmt.Sscanf("x=-Infinity, 12", "x=%v, %f", a.FmtScanner(), c.FmtScanner()); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != "-Infinity" || c.String() != "12" {
+		t.Fatalf("unexpected %s %s", &a, &c)
+	}
+	if _, err := fmt.Sscan("1.2.3", a.FmtScanner()); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := fmt.Sscanf("1", "%d", a.FmtScanner()); err == nil {
+		t.Fatal("expected error for bad verb")
+	}
+}
+
+func TestContextSetStringt(t *testing.T) {
+	tests := []struct {
+		s      string
+		c      *Context
+		expect string
+	}{
+		{
+			s:      "1.234",
+			c:      &BaseContext,
+			expect: "1.234",
+		},
+		{
+			s:      "1.234",
+			c:      BaseContext.WithPrecision(2),
+			expect: "1.2",
+		},
+	}
+	for i, tc := range tests {
+		t.Run(fmt.Sprintf("%d: %s", i, tc.s), func(t *testing.T) {
+			d := new(Decimal)
+			if _, _, err := tc.c.SetString(d, tc.s); err != nil {
+				t.Fatal(err)
+			}
 			got := d.String()
 			if got != tc.expect {
 				t.Fatalf("expected: %s, got: %s", tc.expect, got)
@@ -607,11 +1474,477 @@ func TestContextSetStringt(t *testing.T) {
 	}
 }
 
-func TestQuantize(t *testing.T) {
+func TestSetStringScanner(t *testing.T) {
 	tests := []struct {
 		s      string
-		e      int32
 		expect string
+		err    bool
+	}{
+		{s: "0", expect: "0"},
+		{s: "-0.00", expect: "-0.00"},
+		{s: "+12.5", expect: "12.5"},
+		{s: ".5", expect: "0.5"},
+		{s: "5.", expect: "5"},
+		{s: "1E5", expect: "1E+5"},
+		{s: "1.5e-3", expect: "0.0015"},
+		{s: "INF", expect: "Infinity"},
+		{s: "-InFiNiTy", expect: "-Infinity"},
+		{s: "NaN123", expect: "NaN"},
+		{s: "SNAN", expect: "sNaN"},
+		{s: "12345678901234567890123456789.0123456789", expect: "12345678901234567890123456789.0123456789"},
+		{s: "", err: true},
+		{s: ".", err: true},
+		{s: "+-1", err: true},
+		{s: "1.2.3", err: true},
+		{s: "e5", err: true},
+		{s: "1e", err: true},
+		{s: "1e5.5", err: true},
+		{s: "infin", err: true},
+		{s: "nanx", err: true},
+		{s: "1_000", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			d, _, err := NewFromString(tc.s)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.expect {
+				t.Fatalf("expected %s, got %s", tc.expect, s)
+			}
+		})
+	}
+
+	// Long coefficients span several words and must agree with big.Int.
+	digits := strings.Repeat("9876543210", 30)
+	var expect big.Int
+	expect.SetString(digits, 10)
+	d, _, err := NewFromString(digits[:123] + "." + digits[123:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Coeff.Cmp(&expect) != 0 || d.Exponent != int32(123-len(digits)) {
+		t.Fatalf("unexpected %s", d)
+	}
+}
+
+func TestSetStringAllocs(t *testing.T) {
+	d := new(Decimal)
+	for _, s := range []string{"123.456", "-1.5E-10", "12345678901234567890123456789"} {
+		if _, _, err := d.SetString(s); err != nil {
+			t.Fatal(err)
+		}
+		// Once d has storage, parsing into it again must not allocate.
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _, _ = d.SetString(s)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: expected no allocations, got %v", s, allocs)
+		}
+	}
+}
+
+func TestSetFraction(t *testing.T) {
+	tests := []struct {
+		s     string
+		prec  uint32
+		r     string
+		flags Condition
+		err   bool
+	}{
+		{s: "1/4", r: "0.25"},
+		{s: "-3/8", r: "-0.375"},
+		{s: " 6 / 3 ", r: "2"},
+		{s: "1.5/0.5", r: "3"},
+		{s: "1/3", prec: 5, r: "0.33333", flags: Inexact | Rounded},
+		{s: "2/3", prec: 5, r: "0.66667", flags: Inexact | Rounded},
+		{s: "1/1024", prec: 5, r: "0.00097656", flags: Inexact | Rounded},
+		{s: "1.25", prec: 2, r: "1.2", flags: Inexact | Rounded},
+		{s: "1/3", err: true},
+		{s: "1/0", prec: 5, err: true},
+		{s: "Infinity/2", prec: 5, r: "Infinity"},
+		{s: "1/x", err: true},
+		{s: "/2", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			c := BaseContext.WithPrecision(tc.prec)
+			c.Rounding = RoundHalfEven
+			d, res, err := c.NewFromFraction(tc.s)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
+
+func TestContextQuiet(t *testing.T) {
+	c := BaseContext
+	c.Quiet = true
+	c.Traps = 0
+	var flags Condition
+	c.Flags = &flags
+	parsers := map[string]func(s string) (*Decimal, Condition, error){
+		"SetString":   c.NewFromString,
+		"SetBytes":    func(s string) (*Decimal, Condition, error) { return c.NewFromBytes([]byte(s)) },
+		"SetFraction": c.NewFromFraction,
+		"Parse":       func(s string) (*Decimal, Condition, error) { return c.NewFromStringFlags(s, ParseStrict) },
+	}
+	for name, parse := range parsers {
+		for _, s := range []string{"abc", "1..2", "-", "1e", "1/x"} {
+			d, res, err := parse(s)
+			if err != nil {
+				t.Fatalf("%s(%q): %v", name, s, err)
+			}
+			if d.Form != NaN || d.Negative {
+				t.Errorf("%s(%q): expected NaN, got %s", name, s, d)
+			}
+			if res != InvalidOperation {
+				t.Errorf("%s(%q): expected invalid operation, got %s", name, s, res)
+			}
+		}
+		if d, res, err := parse("1.5"); err != nil || res != 0 || d.String() != "1.5" {
+			t.Errorf("%s: unexpected result %s, %s, %v", name, d, res, err)
+		}
+	}
+	if _, _, err := c.NewFromStringFlags("+1", ParseStrict); err != nil {
+		t.Fatal(err)
+	}
+	if !flags.InvalidOperation() {
+		t.Errorf("expected invalid operation flag, got %s", flags)
+	}
+
+	// A trapped InvalidOperation returns the parse error.
+	c.Traps = InvalidOperation
+	if _, _, err := c.NewFromString("abc"); err == nil || err.Error() == InvalidOperation.String() {
+		t.Errorf("expected parse error, got %v", err)
+	}
+	// Without Quiet, parse errors are always returned.
+	c.Quiet = false
+	c.Traps = 0
+	if _, _, err := c.NewFromString("abc"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestContextFlags(t *testing.T) {
+	var flags Condition
+	c := BaseContext.WithPrecision(5)
+	c.Flags = &flags
+	d := new(Decimal)
+	if _, err := c.Add(d, New(1, 0), New(2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if c.TestFlags(Inexact | Rounded) {
+		t.Fatalf("unexpected flags %s", flags)
+	}
+	if _, err := c.Quo(d, New(1, 0), New(3, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Flags are shared with derived contexts.
+	c2 := c.WithPrecision(10)
+	if _, err := c2.Quo(d, New(1, 0), New(0, 0)); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := c.Add(d, New(1, 0), New(2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if expect := Inexact | Rounded | DivisionByZero; flags != expect {
+		t.Fatalf("expected flags %s, got %s", expect, flags)
+	}
+	if !c.TestFlags(Inexact) || c.TestFlags(Overflow) {
+		t.Fatalf("unexpected TestFlags results for %s", flags)
+	}
+	c.ClearFlags()
+	if flags != 0 || c.TestFlags(Inexact) {
+		t.Fatalf("expected cleared flags, got %s", flags)
+	}
+
+	// Intermediate results of an exact operation leave no flags.
+	if _, err := c.Sqrt(d, New(4, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Cbrt(d, New(8, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Gamma(d, New(5, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if flags != 0 {
+		t.Fatalf("expected no flags, got %s", flags)
+	}
+	// The conditions of the final result are accumulated.
+	if _, err := c.Sqrt(d, New(2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if expect := Inexact | Rounded; flags != expect {
+		t.Fatalf("expected flags %s, got %s", expect, flags)
+	}
+	c.ClearFlags()
+	c.Traps = 0
+	if _, err := c.Gamma(d, New(1, 6)); err != nil {
+		t.Fatal(err)
+	}
+	if !c.TestFlags(Overflow) {
+		t.Fatalf("expected overflow, got %s", flags)
+	}
+
+	var nilFlags Context
+	nilFlags.ClearFlags()
+	if nilFlags.TestFlags(^Condition(0)) {
+		t.Fatal("expected false with nil Flags")
+	}
+}
+
+func TestContextDone(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	c := BaseContext.WithPrecision(2000)
+	c.Done = done
+	x := New(2, 0)
+	y := New(15, -1)
+	for _, op := range []struct {
+		name string
+		f    func(d *Decimal) (Condition, error)
+	}{
+		{"exp", func(d *Decimal) (Condition, error) { return c.Exp(d, x) }},
+		{"ln", func(d *Decimal) (Condition, error) { return c.Ln(d, x) }},
+		{"pow", func(d *Decimal) (Condition, error) { return c.Pow(d, x, y) }},
+		{"cbrt", func(d *Decimal) (Condition, error) { return c.Cbrt(d, x) }},
+		{"sinh", func(d *Decimal) (Condition, error) { return c.Sinh(d, x) }},
+		{"sin", func(d *Decimal) (Condition, error) { return c.Sin(d, x) }},
+		{"irr", func(d *Decimal) (Condition, error) {
+			return c.IRR(d, []*Decimal{New(-100, 0), x, New(110, 0)}, nil)
+		}},
+	} {
+		if _, err := op.f(new(Decimal)); errors.Cause(err) != ErrCanceled {
+			t.Errorf("%s: expected ErrCanceled, got %v", op.name, err)
+		}
+	}
+
+	// A computation canceled while running stops early.
+	done = make(chan struct{})
+	c = BaseContext.WithPrecision(2000)
+	c.Done = done
+	time.AfterFunc(10*time.Millisecond, func() { close(done) })
+	start := time.Now()
+	if _, err := c.Ln(new(Decimal), x); errors.Cause(err) != ErrCanceled {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("cancellation took %s", d)
+	}
+
+	// An open channel does not interfere.
+	c = BaseContext.WithPrecision(16)
+	c.Done = make(chan struct{})
+	d := new(Decimal)
+	if _, err := c.Exp(d, x); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "7.389056098930650" {
+		t.Errorf("expected 7.389056098930650, got %s", s)
+	}
+}
+
+func TestIEEEContexts(t *testing.T) {
+	tests := []struct {
+		c        *Context
+		third    string
+		max, min string
+	}{
+		{&Decimal32Context, "0.3333333", "9.999999E+96", "1E-101"},
+		{&Decimal64Context, "0.3333333333333333", "9.999999999999999E+384", "1E-398"},
+		{&Decimal128Context, "0.3333333333333333333333333333333333", "9.999999999999999999999999999999999E+6144", "1E-6176"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.max, func(t *testing.T) {
+			d := new(Decimal)
+			res, err := tc.c.Quo(d, New(1, 0), New(3, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.third {
+				t.Errorf("expected %s, got %s", tc.third, s)
+			}
+			if res != Inexact|Rounded {
+				t.Errorf("unexpected flags %s", res)
+			}
+			// The largest and smallest values are representable.
+			for _, s := range []string{tc.max, tc.min} {
+				x, res, err := tc.c.NewFromString(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if x.String() != s || res&(Overflow|Inexact) != 0 {
+					t.Errorf("%s: got %s with flags %s", s, x, res)
+				}
+			}
+			// Anything larger overflows to infinity without an error.
+			x := newDecimal(t, testCtx, tc.max)
+			res, err = tc.c.Mul(d, x, New(10, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Form != Infinite || !res.Overflow() {
+				t.Errorf("expected overflow, got %s with flags %s", d, res)
+			}
+		})
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		s       string
+		clamp   bool
+		r       string
+		clamped bool
+	}{
+		{s: "1E+384", clamp: true, r: "1.000000000000000E+384", clamped: true},
+		{s: "1E+384", r: "1E+384"},
+		{s: "1.23E+372", clamp: true, r: "1.230E+372", clamped: true},
+		{s: "1.23E+368", clamp: true, r: "1.23E+368"},
+		{s: "-9.999999999999999E+384", clamp: true, r: "-9.999999999999999E+384"},
+		{s: "0E+400", clamp: true, r: "0E+369", clamped: true},
+		{s: "0E+400", r: "0E+384", clamped: true},
+		{s: "1E+385", clamp: true, r: "Infinity"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%v", tc.s, tc.clamp), func(t *testing.T) {
+			c := Decimal64Context
+			c.Clamp = tc.clamp
+			d, res, err := c.NewFromString(tc.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res.Clamped() != tc.clamped {
+				t.Errorf("expected clamped %v, got %s", tc.clamped, res)
+			}
+			if d.Form == Finite && tc.clamp && d.Exponent > c.MaxExponent-int32(c.Precision-1) {
+				t.Errorf("exponent %d not clamped", d.Exponent)
+			}
+		})
+	}
+
+	// Arithmetic results are clamped too.
+	d := new(Decimal)
+	res, err := Decimal32Context.Mul(d, New(5, 95), New(2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "1.000000E+96" || !res.Clamped() {
+		t.Errorf("expected 1.000000E+96 clamped, got %s with %s", d, res)
+	}
+}
+
+func TestDefaultContexts(t *testing.T) {
+	d := new(Decimal)
+	// BasicContext rounds half up and traps division by zero.
+	if _, err := BasicContext.Quo(d, New(2, 0), New(3, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "0.666666667" {
+		t.Errorf("expected 0.666666667, got %s", s)
+	}
+	if _, err := BasicContext.Add(d, newDecimal(t, testCtx, "1.00000000"), newDecimal(t, testCtx, "0.000000005")); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "1.00000001" {
+		t.Errorf("expected 1.00000001, got %s", s)
+	}
+	if _, err := BasicContext.Quo(d, New(1, 0), New(0, 0)); err == nil {
+		t.Error("expected error")
+	}
+
+	// ExtendedDefaultContext rounds half even and traps nothing.
+	if _, err := ExtendedDefaultContext.Add(d, newDecimal(t, testCtx, "1.00000000"), newDecimal(t, testCtx, "0.000000005")); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "1.00000000" {
+		t.Errorf("expected 1.00000000, got %s", s)
+	}
+	res, err := ExtendedDefaultContext.Quo(d, New(1, 0), New(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Form != Infinite || !res.DivisionByZero() {
+		t.Errorf("expected Infinity with division by zero, got %s with %s", d, res)
+	}
+}
+
+func TestSetBytes(t *testing.T) {
+	tests := []string{
+		"0", "-0", "+1", "1.", ".5", "-.5e3", "123.456", "1E+5", "1e-5", "12e0",
+		"0.000", "9999999999999999999", "18446744073709551616",
+		"123456789012345678901234567890.5", "1e100001", "1e-100001", "1e",
+		"Infinity", "-inf", "NaN", "sNaN12", "1.2.3", "", "-", ".", "1e+", "1x",
+		"1_0", " 1",
+	}
+	for _, prec := range []uint32{0, 5} {
+		c := BaseContext.WithPrecision(prec)
+		for _, s := range tests {
+			t.Run(fmt.Sprintf("%d/%s", prec, s), func(t *testing.T) {
+				expect, eres, eerr := c.NewFromString(s)
+				d, res, err := c.NewFromBytes([]byte(s))
+				if (err != nil) != (eerr != nil) {
+					t.Fatalf("expected error %v, got %v", eerr, err)
+				}
+				if err != nil {
+					return
+				}
+				if d.CmpTotal(expect) != 0 || d.String() != expect.String() {
+					t.Errorf("expected %s, got %s", expect, d)
+				}
+				if res != eres {
+					t.Errorf("expected flags %s, got %s", eres, res)
+				}
+			})
+		}
+	}
+
+	var d Decimal
+	b := []byte("-1234567.891e-3")
+	d.Coeff.SetUint64(1 << 62)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, err := d.SetBytes(b); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+	if s := d.String(); s != "-1234.567891" {
+		t.Errorf("expected -1234.567891, got %s", s)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		s        string
+		e        int32
+		rounding string
+		expect   string
 	}{
 		{
 			s:      "1.00",
@@ -633,25 +1966,501 @@ func TestQuantize(t *testing.T) {
 			e:      -2,
 			expect: "10.00",
 		},
+		// Every digit is discarded: directed roundings still round up.
+		{s: "0.001", e: 0, rounding: RoundCeiling, expect: "1"},
+		{s: "-0.001", e: 0, rounding: RoundFloor, expect: "-1"},
+		{s: "0.001", e: -1, rounding: Round05Up, expect: "0.1"},
+		{s: "-0.001", e: 0, rounding: RoundUp, expect: "-1"},
+		{s: "0.001", e: 0, rounding: RoundHalfUp, expect: "0"},
+		{s: "12345678901234567890123E-30", e: 0, rounding: RoundCeiling, expect: "1"},
+		{s: "12345678901234567890123E-30", e: 0, rounding: RoundDown, expect: "0"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s: %d %s", tc.s, tc.e, tc.rounding), func(t *testing.T) {
+			c := BaseContext.WithPrecision(10)
+			c.Rounding = tc.rounding
+			c.Traps = 0
+			d, _, err := NewFromString(tc.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Quantize(d, d, tc.e); err != nil {
+				t.Fatal(err)
+			}
+			s := d.String()
+			if s != tc.expect {
+				t.Fatalf("expected: %s, got: %s", tc.expect, s)
+			}
+		})
+	}
+}
+
+func TestZeroPrecision(t *testing.T) {
+	c := BaseContext.WithPrecision(0)
+	x := newDecimal(t, testCtx, "123456789012345678901234567890.123456789")
+	y := newDecimal(t, testCtx, "7")
+	exact := []struct {
+		name string
+		f    func(d *Decimal) (Condition, error)
+		r    string
+	}{
+		{"add", func(d *Decimal) (Condition, error) { return c.Add(d, x, y) }, "123456789012345678901234567897.123456789"},
+		{"sub", func(d *Decimal) (Condition, error) { return c.Sub(d, y, x) }, "-123456789012345678901234567883.123456789"},
+		{"mul", func(d *Decimal) (Condition, error) { return c.Mul(d, x, x) }, "15241578753238836750495351562566681945005334557625361987875.019051998750190521"},
+		{"quantize", func(d *Decimal) (Condition, error) { return c.Quantize(d, x, -12) }, "123456789012345678901234567890.123456789000"},
+		{"quointeger", func(d *Decimal) (Condition, error) { return c.QuoInteger(d, x, y) }, "17636684144620811271604938270"},
+		{"rem", func(d *Decimal) (Condition, error) { return c.Rem(d, x, y) }, "0.123456789"},
+		{"pow", func(d *Decimal) (Condition, error) { return c.Pow(d, New(2, 0), New(100, 0)) }, "1267650600228229401496703205376"},
+		{"pow-frac", func(d *Decimal) (Condition, error) { return c.Pow(d, New(11, -1), New(30, 0)) }, "17.449402268886407318558803753801"},
+	}
+	for _, tc := range exact {
+		d := new(Decimal)
+		res, err := tc.f(d)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if res.Inexact() || res.Rounded() {
+			t.Errorf("%s: unexpected %s", tc.name, res)
+		}
+		if s := d.String(); s != tc.r {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.r, s)
+		}
+	}
+	inexact := map[string]func(d *Decimal) (Condition, error){
+		"quo":     func(d *Decimal) (Condition, error) { return c.Quo(d, x, y) },
+		"sqrt":    func(d *Decimal) (Condition, error) { return c.Sqrt(d, New(4, 0)) },
+		"cbrt":    func(d *Decimal) (Condition, error) { return c.Cbrt(d, x) },
+		"ln":      func(d *Decimal) (Condition, error) { return c.Ln(d, x) },
+		"log10":   func(d *Decimal) (Condition, error) { return c.Log10(d, New(100, 0)) },
+		"exp":     func(d *Decimal) (Condition, error) { return c.Exp(d, y) },
+		"pow-neg": func(d *Decimal) (Condition, error) { return c.Pow(d, y, New(-1, 0)) },
+		"pow-non": func(d *Decimal) (Condition, error) { return c.Pow(d, y, New(5, -1)) },
+	}
+	for name, f := range inexact {
+		if _, err := f(new(Decimal)); err == nil || err.Error() != errZeroPrecisionStr {
+			t.Errorf("%s: expected zero precision error, got %v", name, err)
+		}
+	}
+}
+
+func TestMaxDigits(t *testing.T) {
+	c := BaseContext.WithPrecision(0)
+	c.MaxDigits = 100
+	c.Traps = 0
+	huge := New(1, 1000)
+	tiny := New(1, -1000)
+	tests := []struct {
+		name string
+		f    func(d *Decimal) (Condition, error)
+	}{
+		{"add", func(d *Decimal) (Condition, error) { return c.Add(d, huge, tiny) }},
+		{"sub", func(d *Decimal) (Condition, error) { return c.Sub(d, tiny, huge) }},
+		{"mul", func(d *Decimal) (Condition, error) {
+			x := newDecimal(t, testCtx, strings.Repeat("9", 60))
+			return c.Mul(d, x, x)
+		}},
+		{"quantize", func(d *Decimal) (Condition, error) { return c.Quantize(d, New(1, 0), -200) }},
+		{"quointeger", func(d *Decimal) (Condition, error) { return c.QuoInteger(d, huge, New(3, 0)) }},
+		{"rem", func(d *Decimal) (Condition, error) { return c.Rem(d, huge, New(3, 0)) }},
+		{"pow", func(d *Decimal) (Condition, error) { return c.Pow(d, New(2, 0), New(1000, 0)) }},
+		{"pow exponent", func(d *Decimal) (Condition, error) { return c.Pow(d, New(1, 0), New(1, 200)) }},
+		{"roundtomultiple", func(d *Decimal) (Condition, error) { return c.RoundToMultiple(d, huge, tiny) }},
+		{"setstring", func(d *Decimal) (Condition, error) {
+			_, res, err := c.SetString(d, strings.Repeat("1", 101))
+			return res, err
+		}},
+		{"setstring fraction", func(d *Decimal) (Condition, error) {
+			_, res, err := c.SetString(d, "-0.01"+strings.Repeat("2", 100)+"E+5")
+			return res, err
+		}},
+		{"newfromcomponents", func(d *Decimal) (Condition, error) {
+			x, res, err := c.NewFromComponents(false, new(big.Int).Lsh(bigOne, 400), 0)
+			d.Set(x)
+			return res, err
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := new(Decimal)
+			res, err := tc.f(d)
+			if tc.name == "pow" {
+				// The limit is hit by an intermediate product, which is
+				// always trapped.
+				if err == nil || !res.DigitLimit() {
+					t.Fatalf("expected digit limit error, got %s, %v", res, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != DigitLimit || d.Form != NaN {
+				t.Errorf("expected NaN with digit limit, got %s, %s", d, res)
+			}
+		})
+	}
+
+	// Results within the limit are unaffected.
+	d := new(Decimal)
+	if _, err := c.Add(d, New(1, 50), New(1, -40)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Pow(d, New(2, 0), New(300, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Leading zeros are not part of the coefficient.
+	if _, _, err := c.SetString(d, "-000.0"+strings.Repeat("0", 200)+strings.Repeat("3", 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A string over the limit is rejected before its coefficient is built.
+	long := strings.Repeat("7", 100000)
+	if n := testing.AllocsPerRun(10, func() {
+		if _, res, _ := c.SetString(d, long); res != DigitLimit {
+			t.Fatalf("expected digit limit, got %s", res)
+		}
+	}); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+
+	c.Traps = DefaultTraps
+	if _, err := c.Add(d, huge, tiny); err == nil || err.Error() != DigitLimit.String() {
+		t.Fatalf("expected digit limit error, got %v", err)
+	}
+}
+
+func TestGuardDigits(t *testing.T) {
+	ops := []struct {
+		name string
+		f    func(c *Context, d, x *Decimal) (Condition, error)
+	}{
+		{"exp", (*Context).Exp},
+		{"ln", (*Context).Ln},
+		{"log10", (*Context).Log10},
+		{"pow", func(c *Context, d, x *Decimal) (Condition, error) {
+			return c.Pow(d, x, New(25, -1))
+		}},
+	}
+	const prec = 30
+	for _, s := range []string{"0.5", "1.0000001", "2", "7.25", "123.456"} {
+		x := newDecimal(t, testCtx, s)
+		for _, op := range ops {
+			ref := new(Decimal)
+			if _, err := op.f(BaseContext.WithPrecision(prec+30), ref, x); err != nil {
+				t.Fatal(err)
+			}
+			// ulp is one unit in the last place of the result.
+			ulp := New(1, int32(adjustedExponent(ref)-prec+1))
+			if _, err := BaseContext.WithPrecision(prec).Round(ref, ref); err != nil {
+				t.Fatal(err)
+			}
+			for _, g := range []int32{-100, -1, 0, 5, 20} {
+				c := BaseContext.WithPrecision(prec)
+				c.GuardDigits = g
+				d := new(Decimal)
+				if _, err := op.f(c, d, x); err != nil {
+					t.Fatal(err)
+				}
+				diff := new(Decimal)
+				if _, err := BaseContext.Sub(diff, d, ref); err != nil {
+					t.Fatal(err)
+				}
+				diff.Abs(diff)
+				// The default guard digits are occasionally 1 ulp off, for
+				// example Exp(2), but a few more are enough for these.
+				if g >= 5 && diff.Sign() != 0 {
+					t.Errorf("%s(%s) guard %d: expected %s, got %s", op.name, s, g, ref, d)
+				} else if diff.Cmp(ulp) > 0 {
+					t.Errorf("%s(%s) guard %d: %s is more than 1 ulp from %s", op.name, s, g, d, ref)
+				}
+			}
+		}
+	}
+}
+
+func TestExpSplit(t *testing.T) {
+	for _, tc := range []struct{ a, b, n int64 }{
+		{1, 1, 2},
+		{1, 2, 10},
+		{-3, 10, 17},
+		{123456789, 1000000000, 40},
+	} {
+		a, b := big.NewInt(tc.a), big.NewInt(tc.b)
+		q, p := expSplit(a, b, 1, tc.n)
+		expect, term := new(big.Rat), big.NewRat(1, 1)
+		for k := int64(1); k < tc.n; k++ {
+			term.Mul(term, big.NewRat(tc.a, tc.b*k))
+			expect.Add(expect, term)
+		}
+		if got := new(big.Rat).SetFrac(p, q); got.Cmp(expect) != 0 {
+			t.Errorf("%d/%d, %d terms: expected %s, got %s", tc.a, tc.b, tc.n, expect, got)
+		}
+	}
+}
+
+// TestExpHighPrecision checks a precision whose series needs more terms than
+// the term-by-term evaluation used to allow.
+func TestExpHighPrecision(t *testing.T) {
+	c := BaseContext.WithPrecision(3000)
+	d := new(Decimal)
+	if _, err := c.Exp(d, New(9, -1)); err != nil {
+		t.Fatal(err)
+	}
+	s := d.String()
+	const prefix, suffix = "2.459603111156949663", "44460084842854858033"
+	if len(s) != 3001 || !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, suffix) {
+		t.Fatalf("expected %s...%s, got %s", prefix, suffix, s)
+	}
+}
+
+func TestLnAGM(t *testing.T) {
+	// Compare against Halley's iteration at a precision below lnAGMPrecision.
+	c := BaseContext.WithPrecision(40)
+	for _, s := range []string{"0.5", "0.0123", "2", "10", "12345.678", "1E+400", "1E-400"} {
+		x := newDecimal(t, testCtx, s)
+		expect, d := new(Decimal), new(Decimal)
+		if _, err := c.Ln(expect, x); err != nil {
+			t.Fatal(err)
+		}
+		nc := c.WithPrecision(c.Precision + 2)
+		if err := nc.lnAGM(d, x); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Round(d, d); err != nil {
+			t.Fatal(err)
+		}
+		if d.Cmp(expect) != 0 {
+			t.Errorf("%s: expected %s, got %s", s, expect, d)
+		}
+	}
+
+	c = BaseContext.WithPrecision(250)
+	for _, tc := range []struct{ x, ln string }{
+		{"1E+400", "921.0340371976182736071965818737456830404405954515091904133311603870290438709409920943988820358393193367871136169144994533638101860331227026665149476395126757931628833302218723375199579304932794113574021235861510930515384653464889150792879546986174670"},
+		{"0.00123", "-6.700741109597810924827948663461887744981606415921813794812682260940390138762495928344053129262272591883878401750098885577928991935247607923024623382286871931727156774187109855329993613450312931029004004107815808454393750839945577012259968336513592462"},
+	} {
+		d := new(Decimal)
+		if _, err := c.Ln(d, newDecimal(t, testCtx, tc.x)); err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != tc.ln {
+			t.Errorf("%s: expected %s, got %s", tc.x, tc.ln, s)
+		}
+	}
+}
+
+func TestSqrtHighPrecision(t *testing.T) {
+	c := BaseContext.WithPrecision(1000)
+	d := new(Decimal)
+	res, err := c.Sqrt(d, decimalTwo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.String()
+	const prefix, suffix = "1.414213562373095048", "58215212822951848847"
+	if len(s) != 1001 || !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, suffix) {
+		t.Fatalf("expected %s...%s, got %s", prefix, suffix, s)
+	}
+	if !res.Inexact() {
+		t.Errorf("expected inexact, got %s", res)
+	}
+
+	// The square of a 381 digit number has an exact root.
+	x := New(3, -100)
+	x.Coeff.Exp(big.NewInt(7), big.NewInt(450), nil)
+	sq := new(Decimal)
+	if _, err := c.Mul(sq, x, x); err != nil {
+		t.Fatal(err)
+	}
+	res, err = c.Sqrt(d, sq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.CmpTotal(x) != 0 || res != 0 {
+		t.Errorf("expected %s, got %s (%s)", x, d, res)
+	}
+}
+
+func TestRoundToMultiple(t *testing.T) {
+	tests := []struct {
+		x, inc   string
+		rounding string
+		r        string
+		flags    Condition
+	}{
+		{x: "1.3", inc: "0.125", r: "1.250", flags: Inexact | Rounded},
+		{x: "1.3125", inc: "0.125", r: "1.375", flags: Inexact | Rounded},
+		{x: "1.3125", inc: "0.125", rounding: RoundHalfEven, r: "1.250", flags: Inexact | Rounded},
+		{x: "1.4375", inc: "0.125", rounding: RoundHalfEven, r: "1.500", flags: Inexact | Rounded},
+		{x: "1.3125", inc: "0.125", rounding: RoundHalfDown, r: "1.250", flags: Inexact | Rounded},
+		{x: "1.3", inc: "0.25", rounding: RoundUp, r: "1.50", flags: Inexact | Rounded},
+		{x: "1.3", inc: "0.25", rounding: RoundDown, r: "1.25", flags: Inexact | Rounded},
+		{x: "-1.3", inc: "0.25", rounding: RoundCeiling, r: "-1.25", flags: Inexact | Rounded},
+		{x: "-1.3", inc: "0.25", rounding: RoundFloor, r: "-1.50", flags: Inexact | Rounded},
+		{x: "-1.3", inc: "-0.25", r: "-1.25", flags: Inexact | Rounded},
+		{x: "2.75", inc: "0.25", rounding: RoundUp, r: "2.75"},
+		{x: "7.9", inc: "0.25", r: "8.00", flags: Inexact | Rounded},
+		{x: "1234.5", inc: "1E+2", r: "1.2E+3", flags: Inexact | Rounded},
+		{x: "123456789", inc: "0.25", r: "123456789.0", flags: Rounded},
+		{x: "Infinity", inc: "0.25", r: "Infinity"},
+		{x: "1", inc: "NaN", r: "NaN"},
+		{x: "1", inc: "-0", r: "NaN", flags: InvalidOperation},
+		{x: "1", inc: "-Infinity", r: "NaN", flags: InvalidOperation},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x+"/"+tc.inc+"/"+tc.rounding, func(t *testing.T) {
+			c := BaseContext.WithPrecision(10)
+			c.Traps = 0
+			c.Rounding = tc.rounding
+			d := new(Decimal)
+			res, err := c.RoundToMultiple(d, newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.inc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
+
+func TestQuoScale(t *testing.T) {
+	tests := []struct {
+		x, y     string
+		scale    int32
+		rounding string
+		prec     uint32
+		r        string
+		flags    Condition
+	}{
+		{x: "1", y: "3", scale: 2, r: "0.33", flags: Inexact | Rounded},
+		{x: "2", y: "3", scale: 2, r: "0.67", flags: Inexact | Rounded},
+		{x: "2", y: "3", scale: 2, rounding: RoundDown, r: "0.66", flags: Inexact | Rounded},
+		{x: "-2", y: "3", scale: 2, rounding: RoundFloor, r: "-0.67", flags: Inexact | Rounded},
+		{x: "-2", y: "3", scale: 2, rounding: RoundCeiling, r: "-0.66", flags: Inexact | Rounded},
+		{x: "1", y: "8", scale: 2, r: "0.13", flags: Inexact | Rounded},
+		{x: "1", y: "8", scale: 2, rounding: RoundHalfEven, r: "0.12", flags: Inexact | Rounded},
+		{x: "1", y: "4", scale: 4, r: "0.2500"},
+		{x: "10.50", y: "0.5", scale: 0, r: "21"},
+		{x: "1234", y: "1", scale: -2, r: "1.2E+3", flags: Inexact | Rounded},
+		{x: "1E+10", y: "1E-10", scale: 1, r: "100000000000000000000.0"},
+		{x: "0", y: "7", scale: 3, r: "0.000"},
+		{x: "-0", y: "7", scale: 3, r: "-0.000"},
+		// Quo at precision 9 gives 0.500000000, which Quantize would round
+		// to 1.
+		{x: "1", y: "2.0000000004", scale: 0, r: "0", flags: Inexact | Rounded},
+		{x: "1", y: "3", scale: 5, prec: 4, r: "NaN", flags: InvalidOperation},
+		{x: "1", y: "3", scale: -MaxExponent - 1, r: "NaN", flags: InvalidOperation},
+		{x: "1", y: "3", scale: MaxExponent + 1, r: "NaN", flags: InvalidOperation},
+		{x: "Infinity", y: "3", scale: 2, r: "Infinity"},
+		{x: "3", y: "-Infinity", scale: 2, r: "-0.00"},
+		{x: "1", y: "0", scale: 2, r: "Infinity", flags: DivisionByZero},
+		{x: "0", y: "0", scale: 2, r: "NaN", flags: DivisionUndefined},
+		{x: "NaN", y: "0", scale: 2, r: "NaN"},
 	}
-	c := BaseContext.WithPrecision(10)
 	for _, tc := range tests {
-		t.Run(fmt.Sprintf("%s: %d", tc.s, tc.e), func(t *testing.T) {
-			d, _, err := NewFromString(tc.s)
+		t.Run(fmt.Sprintf("%s/%s/%d/%s", tc.x, tc.y, tc.scale, tc.rounding), func(t *testing.T) {
+			c := BaseContext.WithPrecision(tc.prec)
+			c.Traps = 0
+			d := new(Decimal)
+			res, err := c.QuoScale(d, newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.y), tc.scale, Roundings[tc.rounding])
 			if err != nil {
 				t.Fatal(err)
 			}
-			if _, err := c.Quantize(d, d, tc.e); err != nil {
-				t.Fatal(err)
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
 			}
-			s := d.String()
-			if s != tc.expect {
-				t.Fatalf("expected: %s, got: %s", tc.expect, s)
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
 			}
 		})
 	}
 }
 
+func TestMod(t *testing.T) {
+	tests := []struct {
+		x, y     string
+		rem      string
+		mod      string
+		modFloor string
+		flags    Condition
+	}{
+		{x: "7", y: "3", rem: "1", mod: "1", modFloor: "1"},
+		{x: "-7", y: "3", rem: "-1", mod: "2", modFloor: "2"},
+		{x: "7", y: "-3", rem: "1", mod: "1", modFloor: "-2"},
+		{x: "-7", y: "-3", rem: "-1", mod: "2", modFloor: "-1"},
+		{x: "-6", y: "3", rem: "-0", mod: "0", modFloor: "0"},
+		{x: "6", y: "-3", rem: "0", mod: "0", modFloor: "-0"},
+		{x: "-7.5", y: "2", rem: "-1.5", mod: "0.5", modFloor: "0.5"},
+		{x: "-1", y: "0.25", rem: "-0.00", mod: "0.00", modFloor: "0.00"},
+		{x: "-0.1", y: "7", rem: "-0.1", mod: "6.9", modFloor: "6.9"},
+		{x: "-400", y: "7", rem: "-1", mod: "6", modFloor: "6"},
+		{x: "5", y: "Infinity", rem: "5", mod: "5", modFloor: "5"},
+		{x: "-0", y: "-Infinity", rem: "-0", mod: "0", modFloor: "-0"},
+		{x: "NaN", y: "3", rem: "NaN", mod: "NaN", modFloor: "NaN"},
+		{x: "Infinity", y: "3", rem: "NaN", mod: "NaN", modFloor: "NaN", flags: InvalidOperation},
+		{x: "1", y: "0", rem: "NaN", mod: "NaN", modFloor: "NaN", flags: InvalidOperation},
+		{x: "0", y: "0", rem: "NaN", mod: "NaN", modFloor: "NaN", flags: DivisionUndefined},
+		{x: "1E+20", y: "3", rem: "NaN", mod: "NaN", modFloor: "NaN", flags: DivisionImpossible},
+	}
+	c := BaseContext.WithPrecision(10)
+	c.Traps = 0
+	for _, tc := range tests {
+		x := newDecimal(t, testCtx, tc.x)
+		y := newDecimal(t, testCtx, tc.y)
+		for _, op := range []struct {
+			name   string
+			f      func(d, x, y *Decimal) (Condition, error)
+			expect string
+		}{
+			{"Rem", c.Rem, tc.rem},
+			{"Mod", c.Mod, tc.mod},
+			{"ModFloor", c.ModFloor, tc.modFloor},
+		} {
+			d := new(Decimal)
+			res, err := op.f(d, x, y)
+			if err != nil {
+				t.Fatalf("%s(%s, %s): %v", op.name, tc.x, tc.y, err)
+			}
+			if s := d.String(); s != op.expect {
+				t.Errorf("%s(%s, %s): expected %s, got %s", op.name, tc.x, tc.y, op.expect, s)
+			}
+			if res != tc.flags {
+				t.Errorf("%s(%s, %s): expected flags %s, got %s", op.name, tc.x, tc.y, tc.flags, res)
+			}
+		}
+	}
+
+	// x mod ±Infinity is infinite when x must be moved by |y|.
+	for _, tc := range []struct {
+		x, y string
+		f    func(d, x, y *Decimal) (Condition, error)
+	}{
+		{x: "-5", y: "Infinity", f: c.Mod},
+		{x: "-5", y: "-Infinity", f: c.Mod},
+		{x: "-5", y: "Infinity", f: c.ModFloor},
+		{x: "5", y: "-Infinity", f: c.ModFloor},
+	} {
+		d := new(Decimal)
+		res, err := tc.f(d, newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.y))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Form != NaN || res != InvalidOperation {
+			t.Errorf("%s, %s: expected NaN and invalid operation, got %s and %s", tc.x, tc.y, d, res)
+		}
+	}
+
+	// In place, with the divisor as the destination.
+	d := New(3, 0)
+	if _, err := c.Mod(d, New(-7, 0), d); err != nil || d.String() != "2" {
+		t.Errorf("expected 2, got %s, %v", d, err)
+	}
+}
+
 func TestCmpOrder(t *testing.T) {
 	tests := []struct {
 		s     string
@@ -724,6 +2533,171 @@ func TestIsZero(t *testing.T) {
 	}
 }
 
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		s                 string
+		integer, neg, pos bool
+		subnormal         bool
+	}{
+		{s: "-NaN"},
+		{s: "sNaN"},
+		{s: "-Infinity", neg: true},
+		{s: "Infinity", pos: true},
+		{s: "-0", integer: true},
+		{s: "0.000", integer: true},
+		{s: "0E-1000", integer: true},
+		{s: "12", integer: true, pos: true},
+		{s: "12.00", integer: true, pos: true},
+		{s: "1.2E+1", integer: true, pos: true},
+		{s: "-1.20", neg: true},
+		{s: "-0.5", neg: true},
+		{s: "0.1", pos: true},
+		{s: "1.0000000000000000000001", pos: true},
+		{s: "10000000000000000000000000000000.00000000000", integer: true, pos: true},
+		{s: "1024E-10", pos: true},
+		{s: "1E-999", pos: true},
+		{s: "-1E-1000", neg: true},
+		{s: "-1E-1001", neg: true, subnormal: true},
+		{s: "1.5E-1000", pos: true},
+		{s: "15E-1001", pos: true},
+		{s: "1.5E-1001", pos: true, subnormal: true},
+	}
+	c := BaseContext.WithPrecision(5)
+	c.MinExponent = -1000
+	for _, tc := range tests {
+		d := newDecimal(t, testCtx, tc.s)
+		if v := d.IsInteger(); v != tc.integer {
+			t.Errorf("%s: IsInteger: expected %v", tc.s, tc.integer)
+		}
+		if v := d.IsNegative(); v != tc.neg {
+			t.Errorf("%s: IsNegative: expected %v", tc.s, tc.neg)
+		}
+		if v := d.IsPositive(); v != tc.pos {
+			t.Errorf("%s: IsPositive: expected %v", tc.s, tc.pos)
+		}
+		if v := c.IsSubnormal(d); v != tc.subnormal {
+			t.Errorf("%s: IsSubnormal: expected %v", tc.s, tc.subnormal)
+		}
+	}
+}
+
+func TestIsIntegerAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	for _, s := range []string{"12.00", "1.25", "123456789012345678901234567890.000", "123456789012345678901234567890.001"} {
+		d := newDecimal(t, testCtx, s)
+		if n := testing.AllocsPerRun(100, func() { d.IsInteger() }); n != 0 {
+			t.Errorf("%s: expected no allocations, got %v", s, n)
+		}
+	}
+}
+
+// TestZeroValue verifies that the zero Decimal behaves like any other 0,
+// here one whose coefficient has backing storage from an earlier value.
+func TestZeroValue(t *testing.T) {
+	c := BaseContext.WithPrecision(20)
+	c.Traps = 0
+	zero := func() *Decimal {
+		return newDecimal(t, testCtx, "123456789012345678901234567890.5").SetInt64(0)
+	}
+	if s := new(Decimal).String(); s != "0" {
+		t.Fatalf("expected 0, got %s", s)
+	}
+	unary := map[string]func(d, x *Decimal) (Condition, error){
+		"Abs":   c.Abs,
+		"Neg":   c.Neg,
+		"Sqrt":  c.Sqrt,
+		"Cbrt":  c.Cbrt,
+		"Ln":    c.Ln,
+		"Log10": c.Log10,
+		"Exp":   c.Exp,
+		"Round": c.Round,
+		"Floor": c.Floor,
+		"Ceil":  c.Ceil,
+		"Tanh":  c.Tanh,
+		"Gamma": c.Gamma,
+	}
+	for name, f := range unary {
+		var a, b Decimal
+		ra, erra := f(&a, &Decimal{})
+		rb, errb := f(&b, zero())
+		if a.CmpTotal(&b) != 0 || ra != rb || (erra == nil) != (errb == nil) {
+			t.Errorf("%s: expected %s (%s), got %s (%s)", name, &b, rb, &a, ra)
+		}
+	}
+	binary := map[string]func(d, x, y *Decimal) (Condition, error){
+		"Add":        c.Add,
+		"Sub":        c.Sub,
+		"Mul":        c.Mul,
+		"Quo":        c.Quo,
+		"QuoInteger": c.QuoInteger,
+		"Rem":        c.Rem,
+		"Pow":        c.Pow,
+		"Hypot":      c.Hypot,
+		"GCD":        c.GCD,
+	}
+	for name, f := range binary {
+		for _, s := range []string{"0", "1.5", "-2", "NaN", "-Infinity"} {
+			y := newDecimal(t, testCtx, s)
+			var a, b Decimal
+			ra, erra := f(&a, &Decimal{}, y)
+			rb, errb := f(&b, zero(), y)
+			if a.CmpTotal(&b) != 0 || ra != rb || (erra == nil) != (errb == nil) {
+				t.Errorf("%s(0, %s): expected %s (%s), got %s (%s)", name, s, &b, rb, &a, ra)
+			}
+			ra, erra = f(&a, y, &Decimal{})
+			rb, errb = f(&b, y, zero())
+			if a.CmpTotal(&b) != 0 || ra != rb || (erra == nil) != (errb == nil) {
+				t.Errorf("%s(%s, 0): expected %s (%s), got %s (%s)", name, s, &b, rb, &a, ra)
+			}
+		}
+	}
+}
+
+func TestNilDecimal(t *testing.T) {
+	var d *Decimal
+	for _, s := range []string{
+		d.String(),
+		d.Text('f'),
+		d.StringFixed(2, RoundHalfEven),
+		string(d.Append(nil, 'G', -1)),
+		fmt.Sprintf("%v", d),
+		fmt.Sprintf("%.2f", d),
+	} {
+		if s != "<nil>" {
+			t.Errorf("expected <nil>, got %q", s)
+		}
+	}
+	// The marshaling methods have value receivers; encoding/json encodes a
+	// nil *Decimal as null without calling them.
+	if b, err := json.Marshal(struct{ D *Decimal }{d}); err != nil || string(b) != `{"D":null}` {
+		t.Errorf("expected {\"D\":null}, got %s, %v", b, err)
+	}
+
+	x := New(1, 0)
+	for name, f := range map[string]func(){
+		"Sign":      func() { d.Sign() },
+		"IsZero":    func() { d.IsZero() },
+		"NumDigits": func() { d.NumDigits() },
+		"Cmp":       func() { x.Cmp(d) },
+		"CmpTotal":  func() { d.CmpTotal(x) },
+	} {
+		func() {
+			defer func() {
+				err, ok := recover().(*NilDecimalError)
+				if !ok {
+					t.Fatalf("%s: expected *NilDecimalError, got %v", name, err)
+				}
+				if err.Method != name {
+					t.Errorf("%s: unexpected method %s", name, err.Method)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
 func TestNeg(t *testing.T) {
 	tests := map[string]string{
 		"0":          "0",
@@ -785,19 +2759,308 @@ func TestReduce(t *testing.T) {
 	}
 }
 
+func TestFrexpLdexp(t *testing.T) {
+	tests := []struct {
+		x    string
+		mant string
+		exp  int64
+	}{
+		{x: "123.45", mant: "0.12345", exp: 3},
+		{x: "-1.20", mant: "-0.120", exp: 1},
+		{x: "1", mant: "0.1", exp: 1},
+		{x: "0.001", mant: "0.1", exp: -2},
+		{x: "9.99E+1000", mant: "0.999", exp: 1001},
+		{x: "1E-1000", mant: "0.1", exp: -999},
+		{x: "0", mant: "0", exp: 0},
+		{x: "-0.00", mant: "-0.00", exp: 0},
+		{x: "-Infinity", mant: "-Infinity", exp: 0},
+		{x: "NaN", mant: "NaN", exp: 0},
+	}
+	for _, tc := range tests {
+		x := newDecimal(t, testCtx, tc.x)
+		mant, exp := new(Decimal).Frexp(x)
+		if s := mant.String(); s != tc.mant || exp != tc.exp {
+			t.Errorf("%s: expected %s, %d, got %s, %d", tc.x, tc.mant, tc.exp, s, exp)
+		}
+		back, err := new(Decimal).Ldexp(mant, exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back.CmpTotal(x) != 0 {
+			t.Errorf("%s: Ldexp(Frexp) = %s", tc.x, back)
+		}
+	}
+
+	// In place.
+	d := newDecimal(t, testCtx, "-5.5")
+	if _, exp := d.Frexp(d); d.String() != "-0.55" || exp != 1 {
+		t.Errorf("expected -0.55, 1, got %s, %d", d, exp)
+	}
+	if _, err := d.Ldexp(d, 3); err != nil || d.String() != "-5.5E+2" {
+		t.Errorf("expected -5.5E+2, got %s, %v", d, err)
+	}
+
+	for _, e := range []int64{MaxExponent, MinExponent - 10, math.MaxInt64, math.MinInt64} {
+		if _, err := d.Ldexp(New(1, 5), e); err == nil {
+			t.Errorf("%d: expected error", e)
+		}
+		if s := d.String(); s != "-5.5E+2" {
+			t.Errorf("%d: expected d to be unchanged, got %s", e, s)
+		}
+	}
+}
+
+func TestDecimalRounding(t *testing.T) {
+	tests := []struct {
+		x           string
+		ceil, floor string
+		scale       int32
+		trunc       string
+		half        string
+	}{
+		{x: "1.5", ceil: "2", floor: "1", scale: 0, trunc: "1", half: "2"},
+		{x: "-1.5", ceil: "-1", floor: "-2", scale: 0, trunc: "-1", half: "-2"},
+		{x: "1.005", ceil: "2", floor: "1", scale: 2, trunc: "1.00", half: "1.01"},
+		{x: "-1.005", ceil: "-1", floor: "-2", scale: 2, trunc: "-1.00", half: "-1.01"},
+		{x: "2.5", ceil: "3", floor: "2", scale: 2, trunc: "2.50", half: "2.50"},
+		{x: "0.001", ceil: "1", floor: "0", scale: 1, trunc: "0.0", half: "0.0"},
+		{x: "-0.001", ceil: "-0", floor: "-1", scale: 1, trunc: "-0.0", half: "-0.0"},
+		{x: "1E-100", ceil: "1", floor: "0", scale: 5, trunc: "0.00000", half: "0.00000"},
+		{x: "12345678901234567890123.456", ceil: "12345678901234567890124", floor: "12345678901234567890123", scale: 1, trunc: "12345678901234567890123.4", half: "12345678901234567890123.5"},
+		{x: "1234.5", ceil: "1235", floor: "1234", scale: -2, trunc: "1.2E+3", half: "1.2E+3"},
+		{x: "1250", ceil: "1250", floor: "1250", scale: -2, trunc: "1.2E+3", half: "1.3E+3"},
+		{x: "1E+5", ceil: "1E+5", floor: "1E+5", scale: 1, trunc: "100000.0", half: "100000.0"},
+		{x: "-0.00", ceil: "-0", floor: "-0", scale: 0, trunc: "-0", half: "-0"},
+		{x: "-Infinity", ceil: "-Infinity", floor: "-Infinity", scale: 2, trunc: "-Infinity", half: "-Infinity"},
+		{x: "NaN", ceil: "NaN", floor: "NaN", scale: 2, trunc: "NaN", half: "NaN"},
+	}
+	for _, tc := range tests {
+		x := newDecimal(t, testCtx, tc.x)
+		orig := new(Decimal).Set(x)
+		if s := new(Decimal).Ceil(x).String(); s != tc.ceil {
+			t.Errorf("Ceil(%s): expected %s, got %s", tc.x, tc.ceil, s)
+		}
+		if s := new(Decimal).Floor(x).String(); s != tc.floor {
+			t.Errorf("Floor(%s): expected %s, got %s", tc.x, tc.floor, s)
+		}
+		d, err := new(Decimal).Trunc(x, tc.scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != tc.trunc {
+			t.Errorf("Trunc(%s, %d): expected %s, got %s", tc.x, tc.scale, tc.trunc, s)
+		}
+		d, err = new(Decimal).RoundTo(x, tc.scale, RoundHalfUp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != tc.half {
+			t.Errorf("RoundTo(%s, %d): expected %s, got %s", tc.x, tc.scale, tc.half, s)
+		}
+		if x.CmpTotal(orig) != 0 {
+			t.Errorf("%s: operand modified to %s", tc.x, x)
+		}
+	}
+
+	// In place, and with other roundings.
+	d := newDecimal(t, testCtx, "2.345")
+	if _, err := d.RoundTo(d, 2, RoundHalfEven); err != nil || d.String() != "2.34" {
+		t.Errorf("expected 2.34, got %s, %v", d, err)
+	}
+	if _, err := d.RoundTo(d, 1, RoundUp); err != nil || d.String() != "2.4" {
+		t.Errorf("expected 2.4, got %s, %v", d, err)
+	}
+	if s := d.Floor(d).String(); s != "2" {
+		t.Errorf("expected 2, got %s", s)
+	}
+	if _, err := d.RoundTo(New(1, 0), math.MaxInt32, RoundDown); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := d.Trunc(New(1, 0), MinExponent-1); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDecimalClamp(t *testing.T) {
+	tests := []struct {
+		x, lo, hi string
+		expect    string
+	}{
+		{x: "5", lo: "0", hi: "10", expect: "5"},
+		{x: "-5", lo: "0", hi: "10", expect: "0"},
+		{x: "15", lo: "0", hi: "10", expect: "10"},
+		{x: "1.00", lo: "1.0", hi: "2", expect: "1.00"},
+		{x: "2.000", lo: "1.0", hi: "2", expect: "2.000"},
+		{x: "0.5", lo: "1", hi: "1", expect: "1"},
+		{x: "-Infinity", lo: "-100", hi: "100", expect: "-100"},
+		{x: "Infinity", lo: "nil", hi: "100", expect: "100"},
+		{x: "-1E+10", lo: "nil", hi: "100", expect: "-1E+10"},
+		{x: "1E+10", lo: "0", hi: "nil", expect: "1E+10"},
+		{x: "-1E+10", lo: "0", hi: "nil", expect: "0"},
+		{x: "7", lo: "nil", hi: "nil", expect: "7"},
+		{x: "3", lo: "-Infinity", hi: "Infinity", expect: "3"},
+		{x: "NaN", lo: "0", hi: "1", expect: "NaN"},
+	}
+	parse := func(s string) *Decimal {
+		if s == "nil" {
+			return nil
+		}
+		return newDecimal(t, testCtx, s)
+	}
+	for _, tc := range tests {
+		d, err := new(Decimal).Clamp(parse(tc.x), parse(tc.lo), parse(tc.hi))
+		if err != nil {
+			t.Fatalf("%s in [%s, %s]: %v", tc.x, tc.lo, tc.hi, err)
+		}
+		if s := d.String(); s != tc.expect {
+			t.Errorf("%s in [%s, %s]: expected %s, got %s", tc.x, tc.lo, tc.hi, tc.expect, s)
+		}
+	}
+
+	d := New(42, 0)
+	for _, tc := range []struct{ lo, hi string }{
+		{lo: "2", hi: "1"},
+		{lo: "NaN", hi: "1"},
+		{lo: "nil", hi: "sNaN"},
+	} {
+		if _, err := d.Clamp(New(1, 0), parse(tc.lo), parse(tc.hi)); err == nil {
+			t.Errorf("[%s, %s]: expected error", tc.lo, tc.hi)
+		}
+		if s := d.String(); s != "42" {
+			t.Errorf("[%s, %s]: expected d to be unchanged, got %s", tc.lo, tc.hi, s)
+		}
+	}
+
+	// In place.
+	if _, err := d.Clamp(d, New(0, 0), New(10, 0)); err != nil || d.String() != "10" {
+		t.Errorf("expected 10, got %s, %v", d, err)
+	}
+}
+
+func TestDecimalExactArithmetic(t *testing.T) {
+	tests := []struct {
+		x, y          string
+		add, sub, mul string
+	}{
+		{x: "1", y: "2", add: "3", sub: "-1", mul: "2"},
+		{x: "1.5", y: "0.25", add: "1.75", sub: "1.25", mul: "0.375"},
+		{x: "1.0", y: "2.00", add: "3.00", sub: "-1.00", mul: "2.000"},
+		{x: "123456789012345678901234567890", y: "0.000000000000000000001",
+			add: "123456789012345678901234567890.000000000000000000001",
+			sub: "123456789012345678901234567889.999999999999999999999",
+			mul: "123456789.012345678901234567890"},
+		{x: "99999999999999999999", y: "99999999999999999999",
+			add: "199999999999999999998", sub: "0", mul: "9999999999999999999800000000000000000001"},
+		{x: "-0", y: "0", add: "0", sub: "-0", mul: "-0"},
+		{x: "1E+10", y: "1E-10", add: "10000000000.0000000001", sub: "9999999999.9999999999", mul: "1"},
+		{x: "Infinity", y: "-3", add: "Infinity", sub: "Infinity", mul: "-Infinity"},
+		{x: "NaN", y: "1", add: "NaN", sub: "NaN", mul: "NaN"},
+	}
+	for _, tc := range tests {
+		x := newDecimal(t, testCtx, tc.x)
+		y := newDecimal(t, testCtx, tc.y)
+		for _, op := range []struct {
+			name   string
+			f      func(d, x, y *Decimal) (*Decimal, error)
+			expect string
+		}{
+			{"+", (*Decimal).Add, tc.add},
+			{"-", (*Decimal).Sub, tc.sub},
+			{"*", (*Decimal).Mul, tc.mul},
+		} {
+			d, err := op.f(new(Decimal), x, y)
+			if err != nil {
+				t.Fatalf("%s %s %s: %v", tc.x, op.name, tc.y, err)
+			}
+			if s := d.String(); s != op.expect {
+				t.Errorf("%s %s %s: expected %s, got %s", tc.x, op.name, tc.y, op.expect, s)
+			}
+		}
+	}
+
+	// In place.
+	d := New(7, -1)
+	if _, err := d.Mul(d, d); err != nil || d.String() != "0.49" {
+		t.Errorf("expected 0.49, got %s, %v", d, err)
+	}
+	if _, err := d.Add(d, d); err != nil || d.String() != "0.98" {
+		t.Errorf("expected 0.98, got %s, %v", d, err)
+	}
+
+	for _, tc := range []struct {
+		x, y string
+		f    func(d, x, y *Decimal) (*Decimal, error)
+	}{
+		{x: "Infinity", y: "-Infinity", f: (*Decimal).Add},
+		{x: "Infinity", y: "Infinity", f: (*Decimal).Sub},
+		{x: "0", y: "-Infinity", f: (*Decimal).Mul},
+		{x: "sNaN", y: "1", f: (*Decimal).Add},
+		{x: "1E+100000", y: "10", f: (*Decimal).Mul},
+		{x: "1E-100000", y: "1E-10", f: (*Decimal).Mul},
+	} {
+		d, err := tc.f(New(42, 0), newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.y))
+		if err == nil {
+			t.Errorf("%s, %s: expected error", tc.x, tc.y)
+		}
+		if d.Form != NaN {
+			t.Errorf("%s, %s: expected NaN, got %s", tc.x, tc.y, d)
+		}
+	}
+}
+
 // TestSizeof is meant to catch changes that unexpectedly increase
 // the size of the Decimal struct.
 func TestSizeof(t *testing.T) {
 	var d Decimal
-	if s := unsafe.Sizeof(d); s != 48 {
+	if s := unsafe.Sizeof(d); s != 48+unsafe.Sizeof(stringCache{}) {
 		t.Errorf("sizeof(Decimal) changed: %d", s)
 	}
 	var c Context
-	if s := unsafe.Sizeof(c); s != 32 {
+	if s := unsafe.Sizeof(c); s != 80 {
 		t.Errorf("sizeof(Context) changed: %d", s)
 	}
 }
 
+// TestStringCache checks that String reflects changes to a Decimal made
+// after an earlier call. Run with -tags apd_stringcache to test the cache.
+func TestStringCache(t *testing.T) {
+	d := New(12345, -2)
+	for _, tc := range []struct {
+		mutate func()
+		s      string
+	}{
+		{func() {}, "123.45"},
+		{func() {}, "123.45"},
+		{func() { d.Exponent-- }, "12.345"},
+		{func() { d.Negative = true }, "-12.345"},
+		{func() { d.Coeff.SetInt64(6789) }, "-6.789"},
+		{func() { d.Coeff.Lsh(&d.Coeff, 100) }, "-8606079924949449406761118061297.664"},
+		{func() { d.Form = Infinite }, "-Infinity"},
+		{func() { d.SetFinite(5, 0) }, "5"},
+		{func() { *d = *New(7, 1) }, "7E+1"},
+	} {
+		tc.mutate()
+		if s := d.String(); s != tc.s {
+			t.Fatalf("expected %s, got %s", tc.s, s)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if s := d.String(); s != "7E+1" {
+					t.Errorf("expected 7E+1, got %s", s)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestJSONEncoding(t *testing.T) {
 	var encodingTests = []string{
 		"0",
@@ -837,3 +3100,273 @@ func TestJSONEncoding(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONNumbers(t *testing.T) {
+	type value struct {
+		A, B, C Decimal
+	}
+	in := value{A: *New(-15, -1), B: *New(2, 3)}
+	in.C.Form = Infinite
+	defer func(v bool) { MarshalJSONAsNumber = v }(MarshalJSONAsNumber)
+	for _, tc := range []struct {
+		number bool
+		expect string
+	}{
+		{number: false, expect: `{"A":"-1.5","B":"2E+3","C":"Infinity"}`},
+		{number: true, expect: `{"A":-1.5,"B":2E+3,"C":"Infinity"}`},
+	} {
+		MarshalJSONAsNumber = tc.number
+		b, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.expect {
+			t.Fatalf("expected %s, got %s", tc.expect, b)
+		}
+		// Both forms are accepted regardless of the setting.
+		for _, s := range []string{
+			`{"A":"-1.5","B":"2E+3","C":"Infinity"}`,
+			`{"A":-1.5,"B":2E+3,"C":"Infinity"}`,
+		} {
+			out := value{A: *New(1, 0)}
+			if err := json.Unmarshal([]byte(s), &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.A.Cmp(&in.A) != 0 || out.B.Cmp(&in.B) != 0 || out.C.Form != Infinite {
+				t.Fatalf("%s: unexpected %+v", s, out)
+			}
+		}
+	}
+	d := New(3, 0)
+	if err := json.Unmarshal([]byte("null"), d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Cmp(New(3, 0)) != 0 {
+		t.Fatalf("expected null to leave the value unchanged, got %s", d)
+	}
+	if err := json.Unmarshal([]byte(`"abc"`), d); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestTextEncoding(t *testing.T) {
+	type value struct {
+		D Decimal
+		P *Decimal
+		N *Decimal
+		M map[string]Decimal
+	}
+	in := value{
+		D: *New(-15, -1),
+		P: New(2, 3),
+		M: map[string]Decimal{"a": *New(7, -2)},
+	}
+	// A nil *Decimal is encoded as null without calling MarshalText.
+	const expect = `{"D":"-1.5","P":"2E+3","N":null,"M":{"a":"0.07"}}`
+	for _, v := range []interface{}{in, &in} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expect {
+			t.Fatalf("expected %s, got %s", expect, b)
+		}
+	}
+	var out value
+	if err := json.Unmarshal([]byte(expect), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.D.Cmp(&in.D) != 0 || out.P.Cmp(in.P) != 0 || out.N != nil {
+		t.Fatalf("unexpected %+v", out)
+	}
+	if d := out.M["a"]; d.Cmp(New(7, -2)) != 0 {
+		t.Fatalf("unexpected map value %s", &d)
+	}
+
+	type xmlValue struct {
+		A Decimal `xml:"a,attr"`
+		E Decimal `xml:"e"`
+	}
+	b, err := xml.Marshal(xmlValue{A: *New(1, 1), E: *New(25, -1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expectXML = `<xmlValue a="1E+1"><e>2.5</e></xmlValue>`
+	if string(b) != expectXML {
+		t.Fatalf("expected %s, got %s", expectXML, b)
+	}
+	var x xmlValue
+	if err := xml.Unmarshal(b, &x); err != nil {
+		t.Fatal(err)
+	}
+	if x.A.Cmp(New(10, 0)) != 0 || x.E.Cmp(New(25, -1)) != 0 {
+		t.Fatalf("unexpected %+v", x)
+	}
+}
+
+func TestGobEncoding(t *testing.T) {
+	type value struct {
+		D  Decimal
+		P  *Decimal
+		N  NullDecimal
+		NN NullDecimal
+	}
+	for _, s := range []string{"0", "-0", "1.5", "-123.456E-7", "298472983472983471903246121093472394872319615612417471234712061", "NaN", "-sNaN", "-Inf"} {
+		x := newDecimal(t, testCtx, s)
+		var in value
+		in.D.Set(x)
+		in.P = x
+		in.N = NullDecimal{Valid: true}
+		in.N.Decimal.Set(x)
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+			t.Fatalf("%s: %+v", s, err)
+		}
+		var out value
+		if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+			t.Fatalf("%s: %+v", s, err)
+		}
+		for _, d := range []*Decimal{&out.D, out.P, &out.N.Decimal} {
+			if d.CmpTotal(x) != 0 || d.Negative != x.Negative {
+				t.Errorf("%s: got %s", s, d)
+			}
+		}
+		if !out.N.Valid || out.NN.Valid {
+			t.Errorf("%s: unexpected validity %v, %v", s, out.N.Valid, out.NN.Valid)
+		}
+	}
+}
+
+func TestNullDecimalJSON(t *testing.T) {
+	type value struct {
+		N  NullDecimal
+		NN NullDecimal
+		P  *NullDecimal
+	}
+	in := value{N: NullDecimal{Valid: true}}
+	in.N.Decimal.Set(New(-15, -1))
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `{"N":"-1.5","NN":null,"P":null}`; string(b) != expect {
+		t.Fatalf("expected %s, got %s", expect, b)
+	}
+	out := value{NN: NullDecimal{Valid: true}}
+	out.NN.Decimal.Set(New(3, 0))
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.N.Valid || out.N.Decimal.Cmp(New(-15, -1)) != 0 {
+		t.Fatalf("unexpected %+v", out.N)
+	}
+	if out.NN.Valid || !out.NN.Decimal.IsZero() {
+		t.Fatalf("expected NULL, got %+v", out.NN)
+	}
+	if err := json.Unmarshal([]byte(`{"P":2.50}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.P == nil || !out.P.Valid || out.P.Decimal.String() != "2.50" {
+		t.Fatalf("unexpected %+v", out.P)
+	}
+	var nd NullDecimal
+	if err := json.Unmarshal([]byte(`"abc"`), &nd); err == nil || nd.Valid {
+		t.Fatalf("expected error and NULL, got %v, %+v", err, nd)
+	}
+}
+
+func TestGobLayout(t *testing.T) {
+	b, err := newDecimal(t, testCtx, "-1.50").GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []byte{decimalBinaryVersion, 0x01, 0x03, 0x96}; !bytes.Equal(b, expect) {
+		t.Fatalf("expected %x, got %x", expect, b)
+	}
+	for _, b := range [][]byte{nil, {2, 0, 0}, {decimalBinaryVersion, 0x10}} {
+		if err := new(Decimal).GobDecode(b); err == nil {
+			t.Errorf("%x: expected error", b)
+		}
+	}
+}
+
+func TestBinaryEncoding(t *testing.T) {
+	tests := []struct {
+		x string
+		b string
+	}{
+		{x: "0", b: "010000"},
+		{x: "-0.00", b: "010103"},
+		{x: "1", b: "01000001"},
+		{x: "123.45", b: "0100033039"},
+		{x: "-1E+100", b: "0101c80101"},
+		{x: "Infinity", b: "0102"},
+		{x: "-NaN", b: "0105"},
+		{x: "sNaN", b: "0108"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			b, err := x.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := fmt.Sprintf("%x", b); s != tc.b {
+				t.Fatalf("expected %s, got %s", tc.b, s)
+			}
+			var d Decimal
+			if err := d.UnmarshalBinary(b); err != nil {
+				t.Fatal(err)
+			}
+			if d.CmpTotal(x) != 0 || d.Negative != x.Negative {
+				t.Fatalf("expected %s, got %s", x, &d)
+			}
+		})
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	tests := []struct {
+		src    interface{}
+		expect string
+	}{
+		{src: "1.50", expect: "1.50"},
+		{src: []byte("-2E+3"), expect: "-2E+3"},
+		{src: int64(-42), expect: "-42"},
+		{src: float64(0.1), expect: "0.1"},
+		{src: float64(100), expect: "1E+2"},
+	}
+	for _, tc := range tests {
+		var d Decimal
+		if err := d.Scan(tc.src); err != nil {
+			t.Fatalf("%v: %s", tc.src, err)
+		}
+		v, err := d.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.expect {
+			t.Errorf("%v: expected %s, got %v", tc.src, tc.expect, v)
+		}
+	}
+	if err := new(Decimal).Scan(true); err == nil {
+		t.Error("expected error for bool")
+	}
+	if err := new(Decimal).Scan(nil); err == nil {
+		t.Error("expected error for nil")
+	}
+
+	nd := NullDecimal{Valid: true}
+	if err := nd.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nd.Value(); err != nil || v != nil || nd.Valid {
+		t.Fatalf("expected NULL, got %v, %v, %v", v, nd.Valid, err)
+	}
+	if err := nd.Scan("3"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := nd.Value(); err != nil || v != "3" || !nd.Valid {
+		t.Fatalf("expected 3, got %v, %v, %v", v, nd.Valid, err)
+	}
+}
@@ -48,6 +48,9 @@ func (e *ErrDecimal) op2(d, x *Decimal, f func(a, b *Decimal) (Condition, error)
 	if e.Err() != nil {
 		return d
 	}
+	if e.err = e.Ctx.canceled(); e.err != nil {
+		return d
+	}
 	res, err := f(d, x)
 	e.Flags |= res
 	e.err = err
@@ -58,37 +61,145 @@ func (e *ErrDecimal) op3(d, x, y *Decimal, f func(a, b, c *Decimal) (Condition,
 	if e.Err() != nil {
 		return d
 	}
+	if e.err = e.Ctx.canceled(); e.err != nil {
+		return d
+	}
 	res, err := f(d, x, y)
 	e.Flags |= res
 	e.err = err
 	return d
 }
 
+// op performs f, which must only set d, and returns d.
+func (e *ErrDecimal) op(d *Decimal, f func() (Condition, error)) *Decimal {
+	if e.Err() != nil {
+		return d
+	}
+	if e.err = e.Ctx.canceled(); e.err != nil {
+		return d
+	}
+	res, err := f()
+	e.Flags |= res
+	e.err = err
+	return d
+}
+
 // Abs performs e.Ctx.Abs(d, x) and returns d.
 func (e *ErrDecimal) Abs(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Abs)
 }
 
+// Acosh performs e.Ctx.Acosh(d, x) and returns d.
+func (e *ErrDecimal) Acosh(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Acosh)
+}
+
 // Add performs e.Ctx.Add(d, x, y) and returns d.
 func (e *ErrDecimal) Add(d, x, y *Decimal) *Decimal {
 	return e.op3(d, x, y, e.Ctx.Add)
 }
 
+// AGM performs e.Ctx.AGM(d, x, y) and returns d.
+func (e *ErrDecimal) AGM(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.AGM)
+}
+
+// Asinh performs e.Ctx.Asinh(d, x) and returns d.
+func (e *ErrDecimal) Asinh(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Asinh)
+}
+
+// Atanh performs e.Ctx.Atanh(d, x) and returns d.
+func (e *ErrDecimal) Atanh(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Atanh)
+}
+
+// Binomial performs e.Ctx.Binomial(d, n, k) and returns d.
+func (e *ErrDecimal) Binomial(d, n, k *Decimal) *Decimal {
+	return e.op3(d, n, k, e.Ctx.Binomial)
+}
+
 // Ceil performs e.Ctx.Ceil(d, x) and returns d.
 func (e *ErrDecimal) Ceil(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Ceil)
 }
 
+// Cbrt performs e.Ctx.Cbrt(d, x) and returns d.
+func (e *ErrDecimal) Cbrt(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Cbrt)
+}
+
+// Cmp performs e.Ctx.Cmp(d, x, y) and returns d.
+func (e *ErrDecimal) Cmp(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.Cmp)
+}
+
+// Compound performs e.Ctx.Compound(d, rate, periods) and returns d.
+func (e *ErrDecimal) Compound(d, rate, periods *Decimal) *Decimal {
+	return e.op3(d, rate, periods, e.Ctx.Compound)
+}
+
+// Cos performs e.Ctx.Cos(d, x) and returns d.
+func (e *ErrDecimal) Cos(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Cos)
+}
+
+// CosDeg performs e.Ctx.CosDeg(d, x) and returns d.
+func (e *ErrDecimal) CosDeg(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.CosDeg)
+}
+
+// Cosh performs e.Ctx.Cosh(d, x) and returns d.
+func (e *ErrDecimal) Cosh(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Cosh)
+}
+
+// DegToRad performs e.Ctx.DegToRad(d, x) and returns d.
+func (e *ErrDecimal) DegToRad(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.DegToRad)
+}
+
+// E performs e.Ctx.E(d) and returns d.
+func (e *ErrDecimal) E(d *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.E(d) })
+}
+
 // Exp performs e.Ctx.Exp(d, x) and returns d.
 func (e *ErrDecimal) Exp(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Exp)
 }
 
+// FV performs e.Ctx.FV(d, rate, nper, pmt, pv) and returns d.
+func (e *ErrDecimal) FV(d, rate, nper, pmt, pv *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.FV(d, rate, nper, pmt, pv) })
+}
+
+// FVAccrued performs e.Ctx.FVAccrued(d, rate, nper, pmt, pv, a) and returns
+// d.
+func (e *ErrDecimal) FVAccrued(d, rate, nper, pmt, pv *Decimal, a Accrual) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.FVAccrued(d, rate, nper, pmt, pv, a) })
+}
+
+// Factorial performs e.Ctx.Factorial(d, n) and returns d.
+func (e *ErrDecimal) Factorial(d, n *Decimal) *Decimal {
+	return e.op2(d, n, e.Ctx.Factorial)
+}
+
 // Floor performs e.Ctx.Floor(d, x) and returns d.
 func (e *ErrDecimal) Floor(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Floor)
 }
 
+// GCD performs e.Ctx.GCD(d, x, y) and returns d.
+func (e *ErrDecimal) GCD(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.GCD)
+}
+
+// IRR performs e.Ctx.IRR(d, cashFlows, tolerance) and returns d.
+func (e *ErrDecimal) IRR(d *Decimal, cashFlows []*Decimal, tolerance *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.IRR(d, cashFlows, tolerance) })
+}
+
 // Int64 returns 0 if err is set. Otherwise returns d.Int64().
 func (e *ErrDecimal) Int64(d *Decimal) int64 {
 	if e.Err() != nil {
@@ -99,6 +210,26 @@ func (e *ErrDecimal) Int64(d *Decimal) int64 {
 	return r
 }
 
+// Gamma performs e.Ctx.Gamma(d, x) and returns d.
+func (e *ErrDecimal) Gamma(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Gamma)
+}
+
+// Hypot performs e.Ctx.Hypot(d, x, y) and returns d.
+func (e *ErrDecimal) Hypot(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.Hypot)
+}
+
+// ISqrt performs e.Ctx.ISqrt(d, r, x) and returns d.
+func (e *ErrDecimal) ISqrt(d, r, x *Decimal) *Decimal {
+	return e.op3(d, r, x, e.Ctx.ISqrt)
+}
+
+// LCM performs e.Ctx.LCM(d, x, y) and returns d.
+func (e *ErrDecimal) LCM(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.LCM)
+}
+
 // Ln performs e.Ctx.Ln(d, x) and returns d.
 func (e *ErrDecimal) Ln(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Ln)
@@ -109,21 +240,74 @@ func (e *ErrDecimal) Log10(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Log10)
 }
 
+// Mod performs e.Ctx.Mod(d, x, y) and returns d.
+func (e *ErrDecimal) Mod(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.Mod)
+}
+
+// ModFloor performs e.Ctx.ModFloor(d, x, y) and returns d.
+func (e *ErrDecimal) ModFloor(d, x, y *Decimal) *Decimal {
+	return e.op3(d, x, y, e.Ctx.ModFloor)
+}
+
 // Mul performs e.Ctx.Mul(d, x, y) and returns d.
 func (e *ErrDecimal) Mul(d, x, y *Decimal) *Decimal {
 	return e.op3(d, x, y, e.Ctx.Mul)
 }
 
+// NPER performs e.Ctx.NPER(d, rate, pmt, pv, fv) and returns d.
+func (e *ErrDecimal) NPER(d, rate, pmt, pv, fv *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.NPER(d, rate, pmt, pv, fv) })
+}
+
+// NPERAccrued performs e.Ctx.NPERAccrued(d, rate, pmt, pv, fv, a) and
+// returns d.
+func (e *ErrDecimal) NPERAccrued(d, rate, pmt, pv, fv *Decimal, a Accrual) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.NPERAccrued(d, rate, pmt, pv, fv, a) })
+}
+
+// NPV performs e.Ctx.NPV(d, rate, cashFlows) and returns d.
+func (e *ErrDecimal) NPV(d, rate *Decimal, cashFlows []*Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.NPV(d, rate, cashFlows) })
+}
+
 // Neg performs e.Ctx.Neg(d, x) and returns d.
 func (e *ErrDecimal) Neg(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Neg)
 }
 
+// PMT performs e.Ctx.PMT(d, rate, nper, pv, fv) and returns d.
+func (e *ErrDecimal) PMT(d, rate, nper, pv, fv *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.PMT(d, rate, nper, pv, fv) })
+}
+
+// PMTAccrued performs e.Ctx.PMTAccrued(d, rate, nper, pv, fv, a) and
+// returns d.
+func (e *ErrDecimal) PMTAccrued(d, rate, nper, pv, fv *Decimal, a Accrual) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.PMTAccrued(d, rate, nper, pv, fv, a) })
+}
+
+// PV performs e.Ctx.PV(d, rate, nper, pmt, fv) and returns d.
+func (e *ErrDecimal) PV(d, rate, nper, pmt, fv *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.PV(d, rate, nper, pmt, fv) })
+}
+
+// PVAccrued performs e.Ctx.PVAccrued(d, rate, nper, pmt, fv, a) and
+// returns d.
+func (e *ErrDecimal) PVAccrued(d, rate, nper, pmt, fv *Decimal, a Accrual) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.PVAccrued(d, rate, nper, pmt, fv, a) })
+}
+
 // Pow performs e.Ctx.Pow(d, x, y) and returns d.
 func (e *ErrDecimal) Pow(d, x, y *Decimal) *Decimal {
 	return e.op3(d, x, y, e.Ctx.Pow)
 }
 
+// PowMod performs e.Ctx.PowMod(d, x, y, m) and returns d.
+func (e *ErrDecimal) PowMod(d, x, y, m *Decimal) *Decimal {
+	return e.op(d, func() (Condition, error) { return e.Ctx.PowMod(d, x, y, m) })
+}
+
 // Quantize performs e.Ctx.Quantize(d, v, exp) and returns d.
 func (e *ErrDecimal) Quantize(d, v *Decimal, exp int32) *Decimal {
 	if e.Err() != nil {
@@ -145,6 +329,11 @@ func (e *ErrDecimal) QuoInteger(d, x, y *Decimal) *Decimal {
 	return e.op3(d, x, y, e.Ctx.QuoInteger)
 }
 
+// RadToDeg performs e.Ctx.RadToDeg(d, x) and returns d.
+func (e *ErrDecimal) RadToDeg(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.RadToDeg)
+}
+
 // Reduce performs e.Ctx.Reduce(d, x) and returns the number of zeros removed
 // and d.
 func (e *ErrDecimal) Reduce(d, x *Decimal) (int, *Decimal) {
@@ -167,6 +356,26 @@ func (e *ErrDecimal) Round(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Round)
 }
 
+// Sin performs e.Ctx.Sin(d, x) and returns d.
+func (e *ErrDecimal) Sin(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Sin)
+}
+
+// SinCos performs e.Ctx.SinCos(sin, cos, x) and returns sin.
+func (e *ErrDecimal) SinCos(sin, cos, x *Decimal) *Decimal {
+	return e.op(sin, func() (Condition, error) { return e.Ctx.SinCos(sin, cos, x) })
+}
+
+// SinDeg performs e.Ctx.SinDeg(d, x) and returns d.
+func (e *ErrDecimal) SinDeg(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.SinDeg)
+}
+
+// Sinh performs e.Ctx.Sinh(d, x) and returns d.
+func (e *ErrDecimal) Sinh(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Sinh)
+}
+
 // Sqrt performs e.Ctx.Sqrt(d, x) and returns d.
 func (e *ErrDecimal) Sqrt(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.Sqrt)
@@ -177,6 +386,11 @@ func (e *ErrDecimal) Sub(d, x, y *Decimal) *Decimal {
 	return e.op3(d, x, y, e.Ctx.Sub)
 }
 
+// Tanh performs e.Ctx.Tanh(d, x) and returns d.
+func (e *ErrDecimal) Tanh(d, x *Decimal) *Decimal {
+	return e.op2(d, x, e.Ctx.Tanh)
+}
+
 // RoundToIntegralValue performs e.Ctx.RoundToIntegralValue(d, x) and returns d.
 func (e *ErrDecimal) RoundToIntegralValue(d, x *Decimal) *Decimal {
 	return e.op2(d, x, e.Ctx.RoundToIntegralValue)
@@ -51,3 +51,40 @@ func TestMakeErrDecimalWithPrecision(t *testing.T) {
 		t.Fatalf("expected %d, got %d", 2, c.MaxExponent)
 	}
 }
+
+func TestErrDecimalChain(t *testing.T) {
+	c := BaseContext.WithPrecision(10)
+	ed := MakeErrDecimal(c)
+	// Monthly payment on 1000 at 6% a year over 12 months.
+	rate, pmt, fv, x := new(Decimal), new(Decimal), new(Decimal), new(Decimal)
+	ed.Quo(rate, New(6, -2), New(12, 0))
+	ed.PMT(pmt, rate, New(12, 0), New(-1000, 0), New(0, 0))
+	ed.FV(fv, rate, New(12, 0), pmt, New(-1000, 0))
+	ed.Cbrt(x, ed.Add(x, New(20, 0), New(7, 0)))
+	if err := ed.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if s := pmt.String(); s != "86.06642971" {
+		t.Errorf("expected 86.06642971, got %s", s)
+	}
+	if fv.Abs(fv).Cmp(New(1, -6)) > 0 {
+		t.Errorf("expected about 0, got %s", fv)
+	}
+	if x.Cmp(New(3, 0)) != 0 {
+		t.Errorf("expected 3, got %s", x)
+	}
+	if !ed.Flags.Inexact() {
+		t.Errorf("expected inexact, got %s", ed.Flags)
+	}
+
+	// The first error stops later operations.
+	d := New(7, 0)
+	ed.Quo(d, d, New(0, 0))
+	ed.Add(d, New(1, 0), New(2, 0))
+	if err := ed.Err(); err == nil {
+		t.Fatal("expected error")
+	}
+	if s := d.String(); s == "3" {
+		t.Errorf("operation after error was performed")
+	}
+}
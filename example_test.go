@@ -132,3 +132,19 @@ func ExampleContext_RoundToIntegralExact() {
 	// input: 120E-1, output:  12, integer:  true, strict: false, res: rounded
 	// input: 120E-2, output:   1, integer: false, strict: false, res: inexact, rounded
 }
+
+// ExampleContext_Calc computes a total with tax and a fee, rounded to cents.
+func ExampleContext_Calc() {
+	price, _, _ := apd.NewFromString("19.99")
+	qty := apd.New(3, 0)
+	taxRate, _, _ := apd.NewFromString("1.075")
+	fee, _, _ := apd.NewFromString("0.25")
+	c := apd.BaseContext.WithPrecision(16)
+	total, err := c.Calc(price).Mul(qty).Mul(taxRate).Add(fee).Quantize(-2).Value()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(total)
+	// Output: 64.72
+}
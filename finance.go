@@ -0,0 +1,552 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "github.com/pkg/errors"
+
+const (
+	// financeGuardDigits is the number of extra digits of precision used
+	// while computing NPV and IRR.
+	financeGuardDigits = 5
+	// irrMaxIterations is the number of Newton iterations after which IRR
+	// gives up.
+	irrMaxIterations = 100
+	// accruedMaxPeriods is the largest number of periods that the Accrued
+	// functions simulate. It is far more than any real schedule, such as a century
+	// of daily accruals, but keeps a huge nper from running indefinitely.
+	accruedMaxPeriods = 10000000
+)
+
+// decimalIRRGuess is the initial estimate of the internal rate of return.
+var decimalIRRGuess = New(1, -1)
+
+// NPV sets d to the net present value of cashFlows at the given rate per
+// period:
+//
+//   sum(cashFlows[i] / (1 + rate)**i)
+//
+// cashFlows[0] occurs at time 0 and is not discounted. Note that this
+// differs from spreadsheet NPV functions, which discount the first cash flow
+// by one period.
+func (c *Context) NPV(d, rate *Decimal, cashFlows []*Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, append([]*Decimal{rate}, cashFlows...)...); set {
+		return res, err
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+	nc := c.baseContext(c.Precision + financeGuardDigits)
+	nc.Rounding = RoundHalfEven
+	ed := MakeErrDecimal(nc)
+	v := new(Decimal)
+	ed.Add(v, decimalOne, rate)
+	ed.Quo(v, decimalOne, v)
+	z := new(Decimal)
+	npvHorner(&ed, z, nil, v, cashFlows)
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	res := c.round(d, z)
+	res |= ed.Flags & (Inexact | Rounded)
+	return c.goError(res)
+}
+
+// IRR sets d to the internal rate of return of cashFlows, the rate at which
+// their NPV is zero. cashFlows must contain both a positive and a negative
+// value. The rate is found by Newton's method starting from 0.1, and is
+// accepted once consecutive estimates differ by no more than tolerance. If
+// tolerance is nil, the estimates must agree to c.Precision digits. An error
+// is returned if the iteration does not converge.
+func (c *Context) IRR(d *Decimal, cashFlows []*Decimal, tolerance *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, cashFlows...); set {
+		return res, err
+	}
+	var pos, neg bool
+	for _, cf := range cashFlows {
+		switch cf.Sign() {
+		case 1:
+			pos = true
+		case -1:
+			neg = true
+		}
+	}
+	if !pos || !neg {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	nc := c.baseContext(c.Precision + financeGuardDigits)
+	nc.Rounding = RoundHalfEven
+	ed := MakeErrDecimal(nc)
+	r := new(Decimal).Set(decimalIRRGuess)
+	v := new(Decimal)
+	f := new(Decimal)
+	df := new(Decimal)
+	delta := new(Decimal)
+	loop := nc.newLoop("irr", r, c.Precision+1, 1)
+	for i := 0; ; i++ {
+		if i == irrMaxIterations {
+			return 0, errors.Errorf("irr: did not converge after %d iterations; last result %s", i, r)
+		}
+		// With v = 1/(1+r), NPV is the polynomial p(v) = sum(cashFlows[i]*v**i)
+		// and d NPV/dr = -v**2 * p'(v), so the Newton step is
+		// r += p(v) / (v**2 * p'(v)).
+		ed.Add(v, decimalOne, r)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		if v.Sign() <= 0 {
+			return 0, errors.Errorf("irr: did not converge; rate %s is not above -1", r)
+		}
+		ed.Quo(v, decimalOne, v)
+		npvHorner(&ed, f, df, v, cashFlows)
+		ed.Mul(df, df, v)
+		ed.Mul(df, df, v)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		if df.IsZero() {
+			return 0, errors.Errorf("irr: did not converge; zero derivative at rate %s", r)
+		}
+		ed.Quo(delta, f, df)
+		ed.Add(r, r, delta)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		if tolerance != nil {
+			if delta.Abs(delta).Cmp(tolerance) <= 0 {
+				break
+			}
+		} else if done, err := loop.done(r); err != nil {
+			return 0, err
+		} else if done {
+			break
+		}
+	}
+	res := c.round(d, r)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// npvHorner sets p to sum(cashFlows[i]*v**i) using Horner's method. If dp is
+// not nil, it is set to the derivative of that polynomial with respect to v.
+func npvHorner(ed *ErrDecimal, p, dp, v *Decimal, cashFlows []*Decimal) {
+	p.SetInt64(0)
+	if dp != nil {
+		dp.SetInt64(0)
+	}
+	for i := len(cashFlows) - 1; i >= 0; i-- {
+		if dp != nil {
+			ed.Mul(dp, dp, v)
+			ed.Add(dp, dp, p)
+		}
+		ed.Mul(p, p, v)
+		ed.Add(p, p, cashFlows[i])
+	}
+}
+
+// The time value of money functions below use the sign convention of
+// spreadsheets: money received is positive and money paid out is negative.
+// Payments are made at the end of each period. rate is the interest rate per
+// period, nper the number of periods, pmt the payment made each period, pv
+// the present value and fv the future value.
+
+// PMT sets d to the payment per period that pays off a present value pv and
+// leaves a future value fv after nper periods. To get a payment in whole
+// currency units, Quantize the result.
+func (c *Context) PMT(d, rate, nper, pv, fv *Decimal) (Condition, error) {
+	return c.tvm(d, rate, nper, []*Decimal{pv, fv}, func(ed *ErrDecimal, z, g *Decimal) {
+		// pmt = -(pv*g + fv) * rate / (g - 1), or -(pv + fv) / nper if rate is
+		// zero.
+		t := new(Decimal)
+		ed.Mul(z, pv, g)
+		ed.Add(z, z, fv)
+		if rate.IsZero() {
+			ed.Quo(z, z, nper)
+		} else {
+			ed.Mul(z, z, rate)
+			ed.Sub(t, g, decimalOne)
+			ed.Quo(z, z, t)
+		}
+		ed.Neg(z, z)
+	})
+}
+
+// FV sets d to the future value after nper periods of a present value pv
+// with payments of pmt each period. FV assumes that interest accrues
+// continuously with full precision; use FVAccrued to round the balance at
+// each period boundary.
+func (c *Context) FV(d, rate, nper, pmt, pv *Decimal) (Condition, error) {
+	return c.tvm(d, rate, nper, []*Decimal{pmt, pv}, func(ed *ErrDecimal, z, g *Decimal) {
+		// fv = -(pv*g + pmt*annuity(rate, nper)).
+		ed.Mul(z, pv, g)
+		t := annuityFactor(ed, rate, nper, g)
+		ed.Mul(t, t, pmt)
+		ed.Add(z, z, t)
+		ed.Neg(z, z)
+	})
+}
+
+// PV sets d to the present value of a future value fv after nper periods
+// with payments of pmt each period.
+func (c *Context) PV(d, rate, nper, pmt, fv *Decimal) (Condition, error) {
+	return c.tvm(d, rate, nper, []*Decimal{pmt, fv}, func(ed *ErrDecimal, z, g *Decimal) {
+		// pv = -(fv + pmt*annuity(rate, nper)) / g.
+		t := annuityFactor(ed, rate, nper, g)
+		ed.Mul(t, t, pmt)
+		ed.Add(z, fv, t)
+		ed.Quo(z, z, g)
+		ed.Neg(z, z)
+	})
+}
+
+// NPER sets d to the number of periods needed for payments of pmt to take a
+// present value pv to a future value fv. The result is generally not an
+// integer.
+func (c *Context) NPER(d, rate, pmt, pv, fv *Decimal) (Condition, error) {
+	return c.tvm(d, rate, nil, []*Decimal{pmt, pv, fv}, func(ed *ErrDecimal, z, _ *Decimal) {
+		t := new(Decimal)
+		if rate.IsZero() {
+			// nper = -(pv + fv) / pmt.
+			ed.Add(z, pv, fv)
+			ed.Quo(z, z, pmt)
+			ed.Neg(z, z)
+			return
+		}
+		// nper = ln((pmt - fv*rate) / (pmt + pv*rate)) / ln(1 + rate).
+		ed.Mul(z, fv, rate)
+		ed.Sub(z, pmt, z)
+		ed.Mul(t, pv, rate)
+		ed.Add(t, pmt, t)
+		ed.Quo(z, z, t)
+		ed.Ln(z, z)
+		ed.Add(t, decimalOne, rate)
+		ed.Ln(t, t)
+		ed.Quo(z, z, t)
+	})
+}
+
+// tvm implements the time value of money functions. f is called to set z
+// using a context with extra precision. If nper is not nil, g is
+// (1 + rate)**nper. args are the remaining operands, which are only checked
+// for NaNs.
+func (c *Context) tvm(d, rate, nper *Decimal, args []*Decimal, f func(ed *ErrDecimal, z, g *Decimal)) (Condition, error) {
+	args = append([]*Decimal{rate}, args...)
+	if nper != nil {
+		args = append(args, nper)
+	}
+	if set, res, err := c.setIfNaN(d, args...); set {
+		return res, err
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+	nc := c.baseContext(c.Precision + financeGuardDigits)
+	nc.Rounding = RoundHalfEven
+	// The conditions of the steps are reported under c's traps below, not
+	// nc's, so that a division by zero, say, gives NaN if c does not trap it.
+	nc.Traps = 0
+	ed := MakeErrDecimal(nc)
+	g := new(Decimal)
+	if nper != nil {
+		ed.Compound(g, rate, nper)
+	}
+	z := new(Decimal)
+	f(&ed, z, g)
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	res := c.round(d, z)
+	res |= ed.Flags & (Inexact | Rounded | DivisionByZero | DivisionUndefined | DivisionImpossible | InvalidOperation)
+	return c.goError(res)
+}
+
+// annuityFactor returns ((1 + rate)**nper - 1) / rate, or nper if rate is
+// zero, where g is (1 + rate)**nper.
+func annuityFactor(ed *ErrDecimal, rate, nper, g *Decimal) *Decimal {
+	t := new(Decimal)
+	if rate.IsZero() {
+		return t.Set(nper)
+	}
+	ed.Sub(t, g, decimalOne)
+	ed.Quo(t, t, rate)
+	return t
+}
+
+// Accrual specifies how the Accrued functions round interest at each period
+// boundary.
+type Accrual struct {
+	// Exponent is the exponent to which each period's interest is rounded,
+	// for example -2 for cents.
+	Exponent int32
+	// Rounding is the rounding algorithm used, with the same meaning as
+	// Context.Rounding.
+	Rounding string
+}
+
+// The Accrued functions below simulate the account period by period the way
+// banking systems accrue interest: at the end of each period the interest on
+// the balance is rounded as specified by an Accrual before it is added to
+// the balance along with the payment. nper, where given, must be a
+// non-negative integer, and an error is returned if it is over ten million.
+// The balance is computed exactly, subject to c.MaxDigits and c.Done; only
+// the final result is rounded to c.Precision, and only if c.Precision is
+// not 0.
+
+// FVAccrued is like FV, but with interest rounded at each period boundary
+// as specified by a.
+func (c *Context) FVAccrued(d, rate, nper, pmt, pv *Decimal, a Accrual) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, rate, nper, pmt, pv); set {
+		return res, err
+	}
+	n, ok := accruedPeriods(nper)
+	if !ok || rate.Form != Finite || pmt.Form != Finite || pv.Form != Finite {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if n > accruedMaxPeriods {
+		return 0, errors.Errorf("FVAccrued: %s periods is more than %d", nper, accruedMaxPeriods)
+	}
+	ac := c.newAccrual(rate, a)
+	z := new(Decimal)
+	if err := ac.fv(z, pv, pmt, n); err != nil {
+		return 0, err
+	}
+	res := ac.res | c.round(d, z)
+	return c.goError(res)
+}
+
+// PMTAccrued is like PMT, but with interest rounded at each period boundary
+// as specified by a. The payment is a multiple of 10**a.Exponent: of those,
+// it is the one for which FVAccrued gives the future value closest to fv,
+// so that the adjustment needed to the last payment is as small as
+// possible. nper must be positive and rate greater than -1.
+func (c *Context) PMTAccrued(d, rate, nper, pv, fv *Decimal, a Accrual) (Condition, error) {
+	return c.solveAccrued(d, "PMTAccrued", rate, nper, pv, fv, a, (*Context).PMT,
+		func(ac *accrual, z, pmt *Decimal, n int64) error {
+			return ac.fv(z, pv, pmt, n)
+		})
+}
+
+// PVAccrued is like PV, but with interest rounded at each period boundary as
+// specified by a. The present value is a multiple of 10**a.Exponent: of
+// those, it is the one for which FVAccrued gives the future value closest
+// to fv. nper must be positive and rate greater than -1.
+func (c *Context) PVAccrued(d, rate, nper, pmt, fv *Decimal, a Accrual) (Condition, error) {
+	return c.solveAccrued(d, "PVAccrued", rate, nper, pmt, fv, a, (*Context).PV,
+		func(ac *accrual, z, pv *Decimal, n int64) error {
+			return ac.fv(z, pv, pmt, n)
+		})
+}
+
+// NPERAccrued is like NPER, but with interest rounded at each period
+// boundary as specified by a. The result is the number of whole periods
+// after which the balance, starting from pv, first reaches or passes -fv,
+// the balance that leaves a future value of fv; the last payment may then
+// be smaller than pmt. It is NaN with InvalidOperation if the payments do
+// not bring the balance any closer to -fv, so that it is never reached, and
+// an error is returned if reaching it takes over ten million periods. rate
+// must be greater than -1.
+func (c *Context) NPERAccrued(d, rate, pmt, pv, fv *Decimal, a Accrual) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, rate, pmt, pv, fv); set {
+		return res, err
+	}
+	if !accruedRate(rate) || pmt.Form != Finite || pv.Form != Finite || fv.Form != Finite {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	ac := c.newAccrual(rate, a)
+	balance := new(Decimal).Set(pv)
+	gap, prev := new(Decimal), new(Decimal)
+	ac.ed.Add(gap, balance, fv)
+	side := gap.Sign()
+	var n int64
+	for ; side != 0; n++ {
+		if n == accruedMaxPeriods {
+			return 0, errors.Errorf("NPERAccrued: not reached after %d periods", n)
+		}
+		prev.Abs(gap)
+		ac.period(balance, pmt)
+		ac.ed.Add(gap, balance, fv)
+		if err := ac.ed.Err(); err != nil {
+			return 0, err
+		}
+		if gap.Sign() != side {
+			n++
+			break
+		}
+		if new(Decimal).Abs(gap).Cmp(prev) >= 0 {
+			d.Set(decimalNaN)
+			return c.goError(InvalidOperation)
+		}
+	}
+	d.SetInt64(n)
+	res := ac.res | c.round(d, d)
+	return c.goError(res)
+}
+
+// accruedPeriods returns nper as an int64 if it is a non-negative integer.
+func accruedPeriods(nper *Decimal) (int64, bool) {
+	n, ok := integerArg(nper)
+	return n, ok && !nper.Negative
+}
+
+// accruedRate reports whether rate is finite and greater than -1, so that
+// the future value of an account grows with its present value and
+// payments.
+func accruedRate(rate *Decimal) bool {
+	return rate.Form == Finite && new(Decimal).Neg(rate).Cmp(decimalOne) < 0
+}
+
+// accruedGuessPrecision is the precision of the closed-form estimate from
+// which solveAccrued starts.
+const accruedGuessPrecision = 34
+
+// solveAccrued implements PMTAccrued and PVAccrued. It finds the multiple x
+// of 10**a.Exponent for which fvOf, the accrued future value as a function
+// of x, is closest to fv, starting from the closed-form solution of guess.
+// other is the remaining operand of guess besides rate, nper and fv. fvOf
+// decreases as x grows, since the balance grows with it.
+func (c *Context) solveAccrued(
+	d *Decimal,
+	name string,
+	rate, nper, other, fv *Decimal,
+	a Accrual,
+	guess func(c *Context, d, rate, nper, other, fv *Decimal) (Condition, error),
+	fvOf func(ac *accrual, z, x *Decimal, n int64) error,
+) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, rate, nper, other, fv); set {
+		return res, err
+	}
+	n, ok := accruedPeriods(nper)
+	if !ok || n == 0 || !accruedRate(rate) || other.Form != Finite || fv.Form != Finite {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if n > accruedMaxPeriods {
+		return 0, errors.Errorf("%s: %s periods is more than %d", name, nper, accruedMaxPeriods)
+	}
+	gc := c.baseContext(accruedGuessPrecision)
+	if c.Precision > accruedGuessPrecision {
+		gc.Precision = c.Precision
+	}
+	gc.Traps = 0
+	x := new(Decimal)
+	if _, err := guess(gc, x, rate, nper, other, fv); err != nil {
+		return 0, err
+	}
+	if x.Form != Finite {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	ac := c.newAccrual(rate, a)
+	if r := ac.ed.Ctx.quantize(x, x, a.Exponent); r&(SystemOverflow|SystemUnderflow) != 0 {
+		return 0, errors.Errorf("%s: %s", name, errExponentOutOfRangeStr)
+	}
+
+	// Step x one unit at a time while that brings the future value closer to
+	// fv. The closed form is at most a unit or so off, so this takes few
+	// steps.
+	cur, next, y, step := new(Decimal), new(Decimal), new(Decimal), New(1, a.Exponent)
+	if err := fvOf(ac, cur, x, n); err != nil {
+		return 0, err
+	}
+	ac.ed.Sub(cur, cur, fv)
+	if cur.Sign() < 0 {
+		step.Negative = true
+	}
+	for !cur.IsZero() {
+		ac.ed.Add(y, x, step)
+		if err := fvOf(ac, next, y, n); err != nil {
+			return 0, err
+		}
+		ac.ed.Sub(next, next, fv)
+		if err := ac.ed.Err(); err != nil {
+			return 0, err
+		}
+		if new(Decimal).Abs(next).Cmp(new(Decimal).Abs(cur)) >= 0 {
+			break
+		}
+		x.Set(y)
+		cur.Set(next)
+	}
+	if err := ac.ed.Err(); err != nil {
+		return 0, err
+	}
+	res := ac.res | c.round(d, x)
+	return c.goError(res)
+}
+
+// accrual simulates an account for the Accrued functions.
+type accrual struct {
+	ed       ErrDecimal
+	rate     *Decimal
+	exponent int32
+	interest Decimal
+	// res holds the conditions raised by rounding the interest.
+	res Condition
+}
+
+func (c *Context) newAccrual(rate *Decimal, a Accrual) *accrual {
+	nc := c.baseContext(0)
+	nc.Rounding = a.Rounding
+	return &accrual{ed: MakeErrDecimal(nc), rate: rate, exponent: a.Exponent}
+}
+
+// period advances balance by one period: the interest on balance, rounded
+// to ac.exponent, is added to it along with pmt.
+func (ac *accrual) period(balance, pmt *Decimal) {
+	ac.ed.Mul(&ac.interest, balance, ac.rate)
+	if ac.ed.Err() == nil {
+		ac.res |= ac.ed.Ctx.quantize(&ac.interest, &ac.interest, ac.exponent)
+	}
+	ac.ed.Add(balance, balance, &ac.interest)
+	ac.ed.Add(balance, balance, pmt)
+}
+
+// fv sets z to the future value after n periods of pv with payments of pmt,
+// which is the negated balance.
+func (ac *accrual) fv(z, pv, pmt *Decimal, n int64) error {
+	balance := new(Decimal).Set(pv)
+	for i := int64(0); i < n; i++ {
+		ac.period(balance, pmt)
+		if err := ac.ed.Err(); err != nil {
+			return err
+		}
+	}
+	z.Neg(balance)
+	return nil
+}
+
+// RoundCash sets d to x rounded to a multiple of increment, the smallest
+// cash denomination of a currency, such as 0.05 for Swiss francs or 5 for
+// some yen prices. It is RoundToMultiple restricted to positive increments:
+// ties are broken by c.Rounding, typically RoundHalfUp or RoundHalfEven, and
+// the result has the exponent of increment, so rounding 1.234 to 0.05 gives
+// 1.25. An increment that is not finite and positive is an
+// InvalidOperation.
+func (c *Context) RoundCash(d, x, increment *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x, increment); set {
+		return res, err
+	}
+	if increment.Form == Finite && increment.Negative {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	return c.RoundToMultiple(d, x, increment)
+}
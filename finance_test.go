@@ -0,0 +1,372 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func decimals(t *testing.T, s ...string) []*Decimal {
+	t.Helper()
+	d := make([]*Decimal, len(s))
+	for i, v := range s {
+		d[i] = newDecimal(t, testCtx, v)
+	}
+	return d
+}
+
+func TestNPV(t *testing.T) {
+	tests := []struct {
+		rate  string
+		flows []string
+		r     string
+		exact bool
+	}{
+		{rate: "0.1", flows: []string{"-1000", "300", "400", "500"}, r: "-21.03681442524418"},
+		{rate: "0.25", flows: []string{"-100", "50", "50"}, r: "-28.000", exact: true},
+		{rate: "0.08", flows: []string{"-500", "200", "200", "200", "200"}, r: "162.4253680088665"},
+		{rate: "0", flows: []string{"1", "2", "3"}, r: "6", exact: true},
+		{rate: "0.25", flows: nil, r: "0", exact: true},
+		{rate: "0.1", flows: []string{"1", "NaN"}, r: "NaN", exact: true},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %v", tc.rate, tc.flows), func(t *testing.T) {
+			d := new(Decimal)
+			res, err := c.NPV(d, newDecimal(t, testCtx, tc.rate), decimals(t, tc.flows...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res.Inexact() == tc.exact {
+				t.Errorf("unexpected flags %s", res)
+			}
+		})
+	}
+}
+
+func TestIRR(t *testing.T) {
+	tests := []struct {
+		flows     []string
+		tolerance string
+		r         string
+	}{
+		{flows: []string{"-1000", "300", "400", "500"}, r: "0.08896339469334994"},
+		{flows: []string{"-100", "110"}, r: "0.1"},
+		{flows: []string{"-500", "200", "200", "200", "200"}, r: "0.2186226960983423"},
+		{flows: []string{"100", "-50", "-60"}, r: "0.06394102980498532"},
+		{flows: []string{"-500", "200", "200", "200", "200"}, tolerance: "0.0001", r: "0.2186226960983423"},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%v, %s", tc.flows, tc.tolerance), func(t *testing.T) {
+			var tolerance *Decimal
+			if tc.tolerance != "" {
+				tolerance = newDecimal(t, testCtx, tc.tolerance)
+			}
+			d := new(Decimal)
+			if _, err := c.IRR(d, decimals(t, tc.flows...), tolerance); err != nil {
+				t.Fatal(err)
+			}
+			if tolerance != nil {
+				// Only agreement to within tolerance is guaranteed.
+				diff := new(Decimal)
+				if _, err := c.Sub(diff, d, newDecimal(t, testCtx, tc.r)); err != nil {
+					t.Fatal(err)
+				}
+				if diff.Abs(diff).Cmp(tolerance) > 0 {
+					t.Errorf("expected %s within %s, got %s", tc.r, tolerance, d)
+				}
+			} else if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+
+	c.Traps = 0
+	d := new(Decimal)
+	res, err := c.IRR(d, decimals(t, "100", "50"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.InvalidOperation() || d.Form != NaN {
+		t.Fatalf("expected NaN, got %s (%s)", d, res)
+	}
+}
+
+func TestTimeValueOfMoney(t *testing.T) {
+	c := BaseContext.WithPrecision(16)
+	tests := []struct {
+		name string
+		f    func(d, a, b, c, e *Decimal) (Condition, error)
+		args []string
+		r    string
+	}{
+		{"pmt", c.PMT, []string{"0.005", "360", "200000", "0"}, "-1199.101050305505"},
+		{"pmt", c.PMT, []string{"0.01", "12", "-1000", "0"}, "88.84878867834171"},
+		{"pmt", c.PMT, []string{"0", "10", "1000", "-200"}, "-80"},
+		{"fv", c.FV, []string{"0.005", "120", "-100", "-1000"}, "18207.33141467858"},
+		{"fv", c.FV, []string{"0", "10", "-100", "-1000"}, "2000"},
+		{"pv", c.PV, []string{"0.0075", "60", "-500", "0"}, "24086.68676048183"},
+		{"pv", c.PV, []string{"0", "10", "-100", "0"}, "1000"},
+		{"nper", c.NPER, []string{"0.01", "-100", "5000", "0"}, "69.66071689357489"},
+		{"nper", c.NPER, []string{"0", "-100", "5000", "0"}, "50"},
+		{"fv", c.FV, []string{"NaN", "10", "-100", "0"}, "NaN"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s%v", tc.name, tc.args), func(t *testing.T) {
+			a := decimals(t, tc.args...)
+			d := new(Decimal)
+			if _, err := tc.f(d, a[0], a[1], a[2], a[3]); err != nil {
+				t.Fatal(err)
+			}
+			r := newDecimal(t, testCtx, tc.r)
+			if d.CmpTotal(r) != 0 && d.Cmp(r) != 0 {
+				t.Errorf("expected %s, got %s", r, d)
+			}
+		})
+	}
+
+	// Conditions of the intermediate steps are reported under c's traps.
+	c.Traps = 0
+	d := new(Decimal)
+	res, err := c.PMT(d, New(0, 0), New(0, 0), New(0, 0), New(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Form != NaN || !res.DivisionUndefined() {
+		t.Errorf("expected NaN with division undefined, got %s with %s", d, res)
+	}
+	c.Traps = DefaultTraps
+	if _, err := c.PMT(d, New(0, 0), New(0, 0), New(0, 0), New(0, 0)); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestFVAccrued(t *testing.T) {
+	tests := []struct {
+		rate, nper, pmt, pv string
+		a                   Accrual
+		r                   string
+	}{
+		{"0.005", "120", "-100", "-1000", Accrual{Exponent: -2, Rounding: RoundHalfEven}, "18207.28"},
+		{"0.0041666", "12", "0", "-1000", Accrual{Exponent: -2, Rounding: RoundHalfEven}, "1051.16"},
+		{"0.1", "2", "0", "-1.05", Accrual{Exponent: -1, Rounding: RoundDown}, "1.25"},
+		{"0.1", "2", "0", "-1.05", Accrual{Exponent: -1, Rounding: RoundUp}, "1.45"},
+		{"0.05", "0", "-100", "-1000", Accrual{Exponent: -2}, "1000"},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s, %s, %s, %v", tc.rate, tc.nper, tc.pmt, tc.pv, tc.a), func(t *testing.T) {
+			a := decimals(t, tc.rate, tc.nper, tc.pmt, tc.pv)
+			d := new(Decimal)
+			if _, err := c.FVAccrued(d, a[0], a[1], a[2], a[3], tc.a); err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+
+	c.Traps = 0
+	d := new(Decimal)
+	res, err := c.FVAccrued(d, New(1, -2), New(15, -1), New(0, 0), New(-1, 0), Accrual{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.InvalidOperation() {
+		t.Fatalf("expected invalid operation, got %s", res)
+	}
+
+	// A huge nper is refused rather than simulated.
+	if _, err := c.FVAccrued(d, New(1, -2), New(1, 12), New(0, 0), New(-1, 0), Accrual{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The simulation is canceled through c.Done.
+	done := make(chan struct{})
+	close(done)
+	cc := c
+	cc.Done = done
+	if _, err := cc.FVAccrued(d, New(1, -2), New(1000, 0), New(0, 0), New(-1, 0), Accrual{}); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+
+	// c.MaxDigits limits the balance.
+	cc = c
+	cc.MaxDigits = 10
+	if _, err := cc.FVAccrued(d, New(1, -2), New(1000, 0), New(0, 0), New(-1, 0), Accrual{Exponent: -20}); err == nil {
+		t.Fatal("expected digit limit error")
+	}
+}
+
+func TestAccruedSolvers(t *testing.T) {
+	c := BaseContext.WithPrecision(16)
+	a := Accrual{Exponent: -2, Rounding: RoundHalfEven}
+
+	// closest checks that the simulated future value of pmt (or pv) is at
+	// least as close to fv as that of its neighbours one unit away.
+	closest := func(t *testing.T, fvOf func(x *Decimal) *Decimal, x, fv *Decimal) {
+		t.Helper()
+		gap := func(x *Decimal) *Decimal {
+			g := new(Decimal)
+			if _, err := c.Sub(g, fvOf(x), fv); err != nil {
+				t.Fatal(err)
+			}
+			return g.Abs(g)
+		}
+		best := gap(x)
+		for _, step := range []*Decimal{New(1, a.Exponent), New(-1, a.Exponent)} {
+			y := new(Decimal)
+			if _, err := c.Add(y, x, step); err != nil {
+				t.Fatal(err)
+			}
+			if gap(y).Cmp(best) < 0 {
+				t.Errorf("%s is closer to %s than %s", y, fv, x)
+			}
+		}
+	}
+
+	t.Run("PMTAccrued", func(t *testing.T) {
+		v := decimals(t, "0.005", "360", "200000", "0")
+		d := new(Decimal)
+		if _, err := c.PMTAccrued(d, v[0], v[1], v[2], v[3], a); err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != "-1199.10" {
+			t.Errorf("expected -1199.10, got %s", s)
+		}
+		closest(t, func(x *Decimal) *Decimal {
+			fv := new(Decimal)
+			if _, err := c.FVAccrued(fv, v[0], v[1], x, v[2], a); err != nil {
+				t.Fatal(err)
+			}
+			return fv
+		}, d, v[3])
+	})
+
+	t.Run("PVAccrued", func(t *testing.T) {
+		v := decimals(t, "0.005", "120", "-100", "0")
+		d := new(Decimal)
+		if _, err := c.PVAccrued(d, v[0], v[1], v[2], v[3], a); err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != "9007.31" {
+			t.Errorf("expected 9007.31, got %s", s)
+		}
+		closest(t, func(x *Decimal) *Decimal {
+			fv := new(Decimal)
+			if _, err := c.FVAccrued(fv, v[0], v[1], v[2], x, a); err != nil {
+				t.Fatal(err)
+			}
+			return fv
+		}, d, v[3])
+	})
+
+	t.Run("NPERAccrued", func(t *testing.T) {
+		v := decimals(t, "0.01", "-100", "5000", "0")
+		d := new(Decimal)
+		if _, err := c.NPERAccrued(d, v[0], v[1], v[2], v[3], a); err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != "70" {
+			t.Errorf("expected 70, got %s", s)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := c
+		c.Traps = 0
+		d := new(Decimal)
+		// The payment never covers the interest.
+		res, err := c.NPERAccrued(d, New(1, -2), New(-10, 0), New(5000, 0), New(0, 0), a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.InvalidOperation() || d.Form != NaN {
+			t.Errorf("expected NaN with invalid operation, got %s, %s", d, res)
+		}
+		// A rate of -100% or less is rejected.
+		for _, f := range []func() (Condition, error){
+			func() (Condition, error) {
+				return c.PMTAccrued(d, New(-1, 0), New(12, 0), New(1000, 0), New(0, 0), a)
+			},
+			func() (Condition, error) {
+				return c.PVAccrued(d, New(-2, 0), New(12, 0), New(-100, 0), New(0, 0), a)
+			},
+			func() (Condition, error) {
+				return c.NPERAccrued(d, New(-1, 0), New(-100, 0), New(1000, 0), New(0, 0), a)
+			},
+		} {
+			res, err := f()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.InvalidOperation() || d.Form != NaN {
+				t.Errorf("expected NaN with invalid operation, got %s, %s", d, res)
+			}
+		}
+	})
+}
+
+func TestRoundCash(t *testing.T) {
+	tests := []struct {
+		x, inc   string
+		rounding string
+		r        string
+		flags    Condition
+	}{
+		{x: "1.234", inc: "0.05", r: "1.25", flags: Inexact | Rounded},
+		{x: "1.224", inc: "0.05", r: "1.20", flags: Inexact | Rounded},
+		{x: "1.225", inc: "0.05", r: "1.25", flags: Inexact | Rounded},
+		{x: "1.225", inc: "0.05", rounding: RoundHalfEven, r: "1.20", flags: Inexact | Rounded},
+		{x: "1.275", inc: "0.05", rounding: RoundHalfEven, r: "1.30", flags: Inexact | Rounded},
+		{x: "-1.225", inc: "0.05", r: "-1.25", flags: Inexact | Rounded},
+		{x: "1.25", inc: "0.05", r: "1.25"},
+		{x: "1.3", inc: "0.05", r: "1.30"},
+		{x: "127", inc: "5", r: "125", flags: Inexact | Rounded},
+		{x: "127.5", inc: "5", r: "130", flags: Inexact | Rounded},
+		{x: "122.5", inc: "5", rounding: RoundHalfEven, r: "120", flags: Inexact | Rounded},
+		{x: "1.01", inc: "0.05", rounding: RoundCeiling, r: "1.05", flags: Inexact | Rounded},
+		{x: "0", inc: "0.05", r: "0.00"},
+		{x: "-Infinity", inc: "0.05", r: "-Infinity"},
+		{x: "NaN", inc: "0.05", r: "NaN"},
+		{x: "1", inc: "0", r: "NaN", flags: InvalidOperation},
+		{x: "1", inc: "-0.05", r: "NaN", flags: InvalidOperation},
+		{x: "1", inc: "Infinity", r: "NaN", flags: InvalidOperation},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x+"/"+tc.inc+"/"+tc.rounding, func(t *testing.T) {
+			c := BaseContext.WithPrecision(10)
+			c.Traps = 0
+			c.Rounding = tc.rounding
+			d := new(Decimal)
+			res, err := c.RoundCash(d, newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.inc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
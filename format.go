@@ -8,6 +8,8 @@ package apd
 
 import (
 	"fmt"
+	"math/big"
+	"math/bits"
 	"strconv"
 )
 
@@ -24,21 +26,177 @@ import (
 // unrecognized.Format character. The 'f' format has the possibility of
 // displaying precision that is not present in the Decimal when it appends
 // zeros (the 'g' format avoids the use of 'f' in this case). All other
-// formats always show the exact precision of the Decimal.
+// formats always show the exact precision of the Decimal. If d is nil,
+// Text returns "<nil>".
 func (d *Decimal) Text(format byte) string {
-	cap := 10 // TODO(gri) determine a good/better value here
-	return string(d.Append(make([]byte, 0, cap), format))
+	if d == nil {
+		return "<nil>"
+	}
+	// Room for the digits, sign, decimal point and exponent in most cases.
+	cap := int(d.NumDigits()) + 10
+	return string(d.appendExact(make([]byte, 0, cap), format))
 }
 
 // String formats x like x.Text('G'). It matches the to-scientific-string
-// conversion of the GDA spec.
+// conversion of the GDA spec. When built with the apd_stringcache tag, the
+// result is cached on d and reused until d's value changes.
 func (d *Decimal) String() string {
-	return d.Text('G')
+	if d == nil {
+		return "<nil>"
+	}
+	if s, ok := d.loadString(); ok {
+		return s
+	}
+	s := d.Text('G')
+	d.storeString(s)
+	return s
+}
+
+// StringFixed returns d rounded to n fractional digits and formatted
+// without an exponent, always showing exactly n fractional digits: 1.5
+// with n = 2 is "1.50". rounding is one of the Round* constants, or the
+// empty string for RoundHalfUp as with Context. If n is negative, d is
+// rounded to a multiple of 10**-n. NaN and infinities are formatted as by
+// String.
+func (d *Decimal) StringFixed(n int32, rounding string) string {
+	if d == nil {
+		return "<nil>"
+	}
+	if d.Form != Finite {
+		return d.String()
+	}
+	var r Decimal
+	if !r.rescale(d, -int64(n), rounding) {
+		return d.Text('f')
+	}
+	if r.Exponent > 0 {
+		// Show the rounded-off digits as zeros.
+		r.Coeff.Mul(&r.Coeff, tableExp10(int64(r.Exponent), nil))
+		r.Exponent = 0
+	}
+	return r.Text('f')
+}
+
+// Append appends to buf the string form of the decimal number d, as
+// generated by d.Text, and returns the extended buffer. Like
+// strconv.AppendFloat, prec controls the number of digits: it is the number
+// of digits after the decimal point for 'e', 'E' and 'f', and the maximum
+// number of significant digits for 'g' and 'G'. Digits are rounded in
+// decimal using RoundHalfEven and for 'e', 'E' and 'f' zeros are appended
+// as needed. A negative prec shows all digits of d, as d.Text does.
+//
+// Append does not allocate if buf has enough capacity, prec is negative
+// and the coefficient of d fits in a uint64.
+func (d *Decimal) Append(buf []byte, fmt byte, prec int) []byte {
+	if d == nil {
+		return append(buf, "<nil>"...)
+	}
+	if prec < 0 || d.Form != Finite {
+		return d.appendExact(buf, fmt)
+	}
+	return d.appendPrec(buf, fmt, prec)
+}
+
+// AppendText implements the encoding.TextAppender interface. It appends
+// the same text as MarshalText.
+func (d Decimal) AppendText(buf []byte) ([]byte, error) {
+	return d.appendExact(buf, 'G'), nil
+}
+
+// A Formatter formats decimals like String, but with configurable limits on
+// when exponential notation is used. String follows the GDA rule, which uses
+// exponential notation for any positive exponent and for adjusted exponents
+// below -6, so 1E+3 stays in exponential form; a Formatter instead decides
+// by the adjusted exponent alone and prints 1E+3 as 1000 if it is in range.
+// All digits of the coefficient are always shown.
+type Formatter struct {
+	// MinExponent is the smallest adjusted exponent formatted without an
+	// exponent. The adjusted exponent is the exponent of the value in
+	// scientific notation: 0.00123 has adjusted exponent -3.
+	MinExponent int32
+	// MaxExponent is the largest adjusted exponent formatted without an
+	// exponent. Zero (0) is not a special value for either limit.
+	MaxExponent int32
+}
+
+// Format returns the string form of d.
+func (f Formatter) Format(d *Decimal) string {
+	return string(f.Append(make([]byte, 0, 10), d))
+}
+
+// Append appends the string form of d to buf and returns the extended
+// buffer.
+func (f Formatter) Append(buf []byte, d *Decimal) []byte {
+	if d.Form != Finite {
+		return d.appendExact(buf, 'G')
+	}
+	adj := int64(d.Exponent) + d.NumDigits() - 1
+	if adj < int64(f.MinExponent) || adj > int64(f.MaxExponent) {
+		return d.appendExact(buf, 'E')
+	}
+	if d.IsZero() && d.Exponent > 0 {
+		// Avoid printing 0E+3 as 0000.
+		z := Decimal{Negative: d.Negative}
+		return z.appendExact(buf, 'f')
+	}
+	return d.appendExact(buf, 'f')
+}
+
+// ShortestString returns the shortest string that NewFromString under c
+// parses to a value equal to d rounded to c's precision. Trailing zeros are
+// dropped, and whichever of positional and exponential notation is shorter
+// is used, preferring positional notation on ties; the exponent omits a
+// '+' sign, as in 1E6. The result compares equal to d but may have a
+// different exponent: ShortestString of 1.50 is "1.5".
+func (c *Context) ShortestString(d *Decimal) string {
+	if d.Form != Finite {
+		return d.String()
+	}
+	var r Decimal
+	c.round(&r, d)
+	if r.Form != Finite {
+		return r.String()
+	}
+	neg := r.Negative
+	r.Reduce(&r)
+	r.Negative = neg
+
+	var tmp [20]byte
+	digits := appendCoeff(tmp[:0], &r.Coeff)
+	var buf []byte
+	if r.Negative {
+		buf = append(buf, '-')
+	}
+	// Positional notation needs the digits plus either trailing zeros or
+	// a decimal point and any leading zeros.
+	adj := int64(r.Exponent) + int64(len(digits)) - 1
+	posLen := int64(len(digits))
+	switch {
+	case r.Exponent > 0:
+		posLen += int64(r.Exponent)
+	case adj < 0:
+		posLen += 1 - adj
+	case r.Exponent < 0:
+		posLen++
+	}
+	expLen := int64(len(digits)) + 1 + int64(len(strconv.FormatInt(adj, 10)))
+	if len(digits) > 1 {
+		expLen++
+	}
+	if posLen <= expLen {
+		return string(fmtF(buf, &r, digits))
+	}
+	buf = append(buf, digits[0])
+	if len(digits) > 1 {
+		buf = append(buf, '.')
+		buf = append(buf, digits[1:]...)
+	}
+	buf = append(buf, 'E')
+	return string(strconv.AppendInt(buf, adj, 10))
 }
 
-// Append appends to buf the string form of the decimal number d,
-// as generated by d.Text, and returns the extended buffer.
-func (d *Decimal) Append(buf []byte, fmt byte) []byte {
+// appendExact appends to buf the string form of d showing all its digits.
+func (d *Decimal) appendExact(buf []byte, fmt byte) []byte {
 	// sign
 	if d.Negative {
 		buf = append(buf, '-')
@@ -57,7 +215,18 @@ func (d *Decimal) Append(buf []byte, fmt byte) []byte {
 		return append(buf, "unknown"...)
 	}
 
-	digits := d.Coeff.String()
+	// Avoid allocating for the common case of a small coefficient, and use
+	// a pooled buffer for the digits of a large one.
+	var tmp [20]byte
+	var digits []byte
+	if d.Coeff.IsUint64() {
+		digits = strconv.AppendUint(tmp[:0], d.Coeff.Uint64(), 10)
+	} else {
+		p := getBytes()
+		defer putBytes(p)
+		*p = appendCoeff(*p, &d.Coeff)
+		digits = *p
+	}
 	switch fmt {
 	case 'e', 'E':
 		return fmtE(buf, fmt, d, digits)
@@ -82,8 +251,57 @@ func (d *Decimal) Append(buf []byte, fmt byte) []byte {
 	return append(buf, '%', fmt)
 }
 
+// chunkedConvMaxWords is the largest coefficient, in words, that appendCoeff
+// converts by repeated division by a word-sized power of ten. Larger ones
+// are left to big.Int, whose recursive subdivision is faster there.
+const chunkedConvMaxWords = 40
+
+// appendCoeff appends the decimal digits of b, which must not be negative,
+// to buf and returns the extended buffer. Coefficients of up to
+// chunkedConvMaxWords words are divided in place, in pooled scratch space,
+// by 10**wordDigits, and each remainder is written as a chunk of digits
+// directly into buf, so that nothing is allocated if buf has room.
+func appendCoeff(buf []byte, b *big.Int) []byte {
+	if b.IsUint64() {
+		return strconv.AppendUint(buf, b.Uint64(), 10)
+	}
+	if len(b.Bits()) > chunkedConvMaxWords {
+		return b.Append(buf, 10)
+	}
+	// The digits are produced least significant first, so make room for
+	// all of them and fill it from the end.
+	n := int(NumDigits(b))
+	buf = append(buf, make([]byte, n)...)
+	i := len(buf)
+
+	tmp := getBigInt()
+	z := tmp.Set(b).Bits()
+	chunk := uint(pow10Uint64[wordDigits])
+	for len(z) > 0 {
+		var r uint
+		for j := len(z) - 1; j >= 0; j-- {
+			var q uint
+			q, r = bits.Div(r, uint(z[j]), chunk)
+			z[j] = big.Word(q)
+		}
+		for len(z) > 0 && z[len(z)-1] == 0 {
+			z = z[:len(z)-1]
+		}
+		// Inner chunks are zero-padded; the leading one is not.
+		for k := 0; k < wordDigits && (len(z) > 0 || r > 0); k++ {
+			i--
+			buf[i] = byte('0' + r%10)
+			r /= 10
+		}
+	}
+	// The words of tmp were overwritten; leave it a valid zero.
+	tmp.SetBits(z[:0])
+	putBigInt(tmp)
+	return buf
+}
+
 // %e: d.ddddde±d
-func fmtE(buf []byte, fmt byte, d *Decimal, digits string) []byte {
+func fmtE(buf []byte, fmt byte, d *Decimal, digits []byte) []byte {
 	adj := int64(d.Exponent) + int64(len(digits)) - 1
 	buf = append(buf, digits[0])
 	if len(digits) > 1 {
@@ -103,7 +321,7 @@ func fmtE(buf []byte, fmt byte, d *Decimal, digits string) []byte {
 }
 
 // %f: ddddddd.ddddd
-func fmtF(buf []byte, d *Decimal, digits string) []byte {
+func fmtF(buf []byte, d *Decimal, digits []byte) []byte {
 	if d.Exponent < 0 {
 		if left := -int(d.Exponent) - len(digits); left >= 0 {
 			buf = append(buf, "0."...)
@@ -132,9 +350,19 @@ var _ fmt.Formatter = decimalZero // *Decimal must implement fmt.Formatter
 // floating-point numbers ('e', 'E', 'f', 'F', 'g', 'G') as well as 's' and 'v',
 // which are handled like 'G'. Format also supports the output field width, as
 // well as the format flags '+' and ' ' for sign control, '0' for space or zero
-// padding, and '-' for left or right justification. It does not support
-// precision. See the fmt package for details.
+// padding, and '-' for left or right justification. See the fmt package for
+// details.
+//
+// The precision is the number of digits after the decimal point for 'e', 'E',
+// 'f' and 'F', and the maximum number of significant digits for the other
+// formats. Rounding is done in decimal using RoundHalfEven, and for 'e', 'E',
+// 'f' and 'F' zeros are appended as needed. Without a precision all digits of
+// d are shown.
 func (d *Decimal) Format(s fmt.State, format rune) {
+	if d == nil {
+		fmt.Fprint(s, "<nil>")
+		return
+	}
 	switch format {
 	case 'e', 'E', 'f', 'g', 'G':
 		// nothing to do
@@ -148,8 +376,12 @@ func (d *Decimal) Format(s fmt.State, format rune) {
 		fmt.Fprintf(s, "%%!%c(*apd.Decimal=%s)", format, d.String())
 		return
 	}
+	prec, hasPrec := s.Precision()
+	if !hasPrec {
+		prec = -1
+	}
 	var buf []byte
-	buf = d.Append(buf, byte(format))
+	buf = d.Append(buf, byte(format), prec)
 	if len(buf) == 0 {
 		buf = []byte("?") // should never happen, but don't crash
 	}
@@ -179,7 +411,7 @@ func (d *Decimal) Format(s fmt.State, format rune) {
 	}
 
 	switch {
-	case s.Flag('0') && d.Form == Finite:
+	case s.Flag('0') && !s.Flag('-') && d.Form == Finite:
 		// 0-padding on left
 		writeMultiple(s, sign, 1)
 		writeMultiple(s, "0", padding)
@@ -197,6 +429,47 @@ func (d *Decimal) Format(s fmt.State, format rune) {
 	}
 }
 
+// FmtScanner returns a fmt.Scanner that scans into d, for use with
+// fmt.Sscan, fmt.Fscan and similar functions:
+//
+//     var d apd.Decimal
+//     _, err := fmt.Sscan("1.5e3", d.FmtScanner())
+//
+// Decimal does not implement fmt.Scanner itself since its Scan method
+// implements database/sql.Scanner. The scanner accepts the verbs 'e', 'E',
+// 'f', 'F', 'g', 'G', 's' and 'v' and any string accepted by SetString; the
+// value is not rounded.
+func (d *Decimal) FmtScanner() fmt.Scanner {
+	return fmtScanner{d}
+}
+
+type fmtScanner struct {
+	d *Decimal
+}
+
+// Scan implements fmt.Scanner.
+func (s fmtScanner) Scan(state fmt.ScanState, verb rune) error {
+	switch verb {
+	case 'e', 'E', 'f', 'F', 'g', 'G', 's', 'v':
+	default:
+		return fmt.Errorf("bad verb '%%%c' for Decimal", verb)
+	}
+	state.SkipSpace()
+	tok, err := state.Token(false, isDecimalRune)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.d.SetString(string(tok))
+	return err
+}
+
+// isDecimalRune reports whether r may appear in the string form of a
+// decimal, including the letters of NaN and Infinity.
+func isDecimalRune(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+		r == '.' || r == '+' || r == '-'
+}
+
 // write count copies of text to s
 func writeMultiple(s fmt.State, text string, count int) {
 	if len(text) > 0 {
@@ -206,3 +479,78 @@ func writeMultiple(s fmt.State, text string, count int) {
 		}
 	}
 }
+
+// appendPrec appends the finite d formatted with prec digits as described
+// in Append.
+func (d *Decimal) appendPrec(buf []byte, fmt byte, prec int) []byte {
+	var r Decimal
+	var ok bool
+	switch fmt {
+	case 'f':
+		ok = r.rescale(d, -int64(prec), RoundHalfEven)
+	case 'e', 'E':
+		if d.IsZero() {
+			// A zero coefficient cannot hold the padding, so write it here
+			// keeping the exponent of d as the adjusted exponent.
+			if d.Negative {
+				buf = append(buf, '-')
+			}
+			buf = append(buf, '0')
+			if prec > 0 {
+				buf = append(buf, '.')
+				for i := 0; i < prec; i++ {
+					buf = append(buf, '0')
+				}
+			}
+			buf = append(buf, fmt)
+			if d.Exponent >= 0 {
+				buf = append(buf, '+')
+			}
+			return strconv.AppendInt(buf, int64(d.Exponent), 10)
+		}
+		ok = r.roundDigits(d, int64(prec)+1, true)
+	default:
+		if prec == 0 {
+			prec = 1
+		}
+		ok = r.roundDigits(d, int64(prec), false)
+	}
+	if !ok {
+		return d.appendExact(buf, fmt)
+	}
+	return r.appendExact(buf, fmt)
+}
+
+// roundDigits sets d to x rounded or zero-padded to exactly n significant
+// digits. If pad is false, x is only rounded. It returns false if the
+// resulting exponent is out of range.
+func (d *Decimal) roundDigits(x *Decimal, n int64, pad bool) bool {
+	nd := x.NumDigits()
+	if !pad && nd <= n {
+		d.Set(x)
+		return true
+	}
+	exp := int64(x.Exponent) + nd - n
+	if !d.rescale(x, exp, RoundHalfEven) {
+		return false
+	}
+	// Rounding up may have added a digit, as in 9.99 to 10.0. The last digit
+	// is then a zero and can be dropped exactly.
+	if d.NumDigits() > n {
+		return d.rescale(d, exp+1, RoundHalfEven)
+	}
+	return true
+}
+
+// rescale sets d to x with exponent exp, appending zeros to the coefficient
+// or rounding it with the given rounding mode as needed. It returns false if
+// exp is out of range.
+func (d *Decimal) rescale(x *Decimal, exp int64, rounding string) bool {
+	if exp > MaxExponent || exp < MinExponent {
+		return false
+	}
+	c := BaseContext
+	c.Rounding = rounding
+	res := c.quantize(d, x, int32(exp))
+	return res&(SystemOverflow|SystemUnderflow) == 0
+}
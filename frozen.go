@@ -0,0 +1,54 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+// Frozen is a read-only Decimal. It is meant for values that are computed
+// once and then shared, such as caches of common constants or reference
+// rates: it can be read from any number of goroutines at once without
+// synchronization, and it is used as an operand without being copied.
+//
+// Context operations never modify their operands, so the Decimal returned
+// by the Decimal method shares f's coefficient with every operation it is
+// passed to. The coefficient is copied only when a mutable Decimal is
+// needed, by Get.
+type Frozen struct {
+	d Decimal
+}
+
+// Freeze returns a Frozen holding the value of x. x is copied, so it may
+// be modified afterward without affecting the result.
+func Freeze(x *Decimal) *Frozen {
+	f := new(Frozen)
+	f.d.Set(x)
+	return f
+}
+
+// Decimal returns the value of f for use as an operand, for example
+// c.Mul(d, x, rate.Decimal()). The result must not be modified or used as
+// the destination of an operation; use Get for a Decimal that can be.
+func (f *Frozen) Decimal() *Decimal {
+	return &f.d
+}
+
+// Get sets d to the value of f and returns d. d does not share any storage
+// with f.
+func (f *Frozen) Get(d *Decimal) *Decimal {
+	return d.Set(&f.d)
+}
+
+// String returns the string representation of f, as Decimal.String does.
+func (f *Frozen) String() string {
+	return f.d.String()
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFrozen(t *testing.T) {
+	x := newDecimal(t, testCtx, "1.0825")
+	f := Freeze(x)
+	x.SetInt64(3)
+	if s := f.String(); s != "1.0825" {
+		t.Fatalf("expected 1.0825, got %s", s)
+	}
+
+	d := f.Get(new(Decimal))
+	d.Coeff.SetInt64(7)
+	if s := f.String(); s != "1.0825" {
+		t.Fatalf("Get shares storage: expected 1.0825, got %s", s)
+	}
+
+	// Concurrent operations may all read the frozen value.
+	c := BaseContext.WithPrecision(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d := new(Decimal)
+			if _, err := c.Mul(d, New(int64(i), 0), f.Decimal()); err != nil {
+				t.Error(err)
+				return
+			}
+			expect := new(Decimal)
+			if _, err := c.Mul(expect, New(int64(i), 0), New(10825, -4)); err != nil {
+				t.Error(err)
+				return
+			}
+			if d.Cmp(expect) != 0 {
+				t.Errorf("expected %s, got %s", expect, d)
+			}
+			_ = f.String()
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,416 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// gammaGuardDigits is the number of extra digits of precision used while
+	// computing Gamma and Lgamma.
+	gammaGuardDigits = 10
+	// gammaMaxExactArg is the largest integer argument for which Gamma and
+	// Lgamma compute the factorial exactly instead of using Stirling's series.
+	gammaMaxExactArg = 1000
+)
+
+// Gamma sets d to the Gamma function of x. Gamma(x) = (x-1)! for positive
+// integers x, which are computed exactly before rounding. Gamma has poles at
+// zero and the negative integers: Gamma(±0) is ±Infinity with DivisionByZero
+// and Gamma of a negative integer is NaN with InvalidOperation.
+func (c *Context) Gamma(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Form == Infinite {
+		if x.Negative {
+			d.Set(decimalNaN)
+			return c.goError(InvalidOperation)
+		}
+		d.Set(decimalInfinity)
+		return 0, nil
+	}
+	if x.IsZero() {
+		d.Set(decimalInfinity)
+		d.Negative = x.Negative
+		return c.goError(DivisionByZero)
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	if n, ok := integerArg(x); ok {
+		if x.Negative {
+			d.Set(decimalNaN)
+			return c.goError(InvalidOperation)
+		}
+		if n <= gammaMaxExactArg {
+			z := new(Decimal)
+			z.Coeff.MulRange(1, n-1)
+			z.Form = Finite
+			return c.Round(d, z)
+		}
+	}
+
+	// Gamma(x) = sign * e**lgamma(x). The absolute error of lgamma becomes the
+	// relative error of the result, so lgamma needs enough precision for its
+	// integer digits as well.
+	lg := new(Decimal)
+	neg, err := c.lgamma(lg, x)
+	if err != nil {
+		return 0, err
+	}
+	nc := c.workingContext(c.Precision + gammaGuardDigits)
+	nc.Rounding = RoundHalfEven
+	z := new(Decimal)
+	res, err := nc.Exp(z, lg)
+	if err != nil || z.Form != Finite {
+		d.Set(z)
+		d.Negative = neg
+		if err == nil {
+			res, err = c.goError(res)
+		}
+		return res, err
+	}
+	z.Negative = neg
+	res |= c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// Lgamma sets d to the natural log of the absolute value of Gamma(x) and
+// returns the sign of Gamma(x), -1 or 1. Lgamma of zero or a negative integer
+// is Infinity with DivisionByZero.
+func (c *Context) Lgamma(d, x *Decimal) (int, Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return 1, res, err
+	}
+	if x.Form == Infinite {
+		d.Set(decimalInfinity)
+		return 1, 0, nil
+	}
+	if n, ok := integerArg(x); ok && (x.Negative || n == 0) {
+		d.Set(decimalInfinity)
+		res, err := c.goError(DivisionByZero)
+		return 1, res, err
+	}
+	if c.Precision == 0 {
+		return 1, 0, errors.New(errZeroPrecisionStr)
+	}
+
+	if n, ok := integerArg(x); ok && n <= gammaMaxExactArg {
+		if n <= 2 {
+			// lgamma(1) = lgamma(2) = 0 exactly.
+			d.Set(decimalZero)
+			return 1, 0, nil
+		}
+		z := new(Decimal)
+		z.Coeff.MulRange(1, n-1)
+		z.Form = Finite
+		res, err := c.Ln(d, z)
+		return 1, res, err
+	}
+
+	z := new(Decimal)
+	neg, err := c.lgamma(z, x)
+	if err != nil {
+		return 1, 0, err
+	}
+	sign := 1
+	if neg {
+		sign = -1
+	}
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	res, err = c.goError(res)
+	return sign, res, err
+}
+
+// Factorial sets d to n! for a non-negative integer n. The factorial is
+// computed exactly and then rounded to c.Precision. Results that are certain
+// to exceed c.MaxExponent are reported as Overflow without being computed.
+func (c *Context) Factorial(d, n *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, n); set {
+		return res, err
+	}
+	if n.Form == Infinite && !n.Negative {
+		d.Set(decimalInfinity)
+		return 0, nil
+	}
+	v, ok := integerArg(n)
+	if !ok || (n.Negative && v != 0) {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if lg, _ := math.Lgamma(float64(v) + 1); lg/math.Ln10 > float64(c.MaxExponent)+2 {
+		return c.combinatoricOverflow(d)
+	}
+	z := new(Decimal)
+	z.Coeff.MulRange(1, v)
+	z.Form = Finite
+	return c.Round(d, z)
+}
+
+// Binomial sets d to the binomial coefficient of n and k, the number of ways
+// to choose k items from n, for non-negative integers n and k. d is 0 if
+// k > n. The coefficient is computed exactly and then rounded to c.Precision.
+func (c *Context) Binomial(d, n, k *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, n, k); set {
+		return res, err
+	}
+	_, nok := integerArg(n)
+	_, kok := integerArg(k)
+	if !nok || !kok || n.Sign() < 0 || k.Sign() < 0 {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if k.Cmp(n) > 0 {
+		d.SetInt64(0)
+		return 0, nil
+	}
+	// C(n, k) = C(n, n-k), so use the smaller k.
+	kk := new(Decimal)
+	if _, err := BaseContext.Sub(kk, n, k); err != nil {
+		return 0, err
+	}
+	if kk.Cmp(k) > 0 {
+		kk.Set(k)
+	}
+	kv, _ := integerArg(kk)
+	if kv > 0 {
+		// C(n, k) >= (n/k)**k.
+		nf, _ := n.Float64()
+		if kv == math.MaxInt64 || float64(kv)*math.Log10(nf/float64(kv)) > float64(c.MaxExponent)+2 {
+			return c.combinatoricOverflow(d)
+		}
+	}
+	nb, err := integerValue(new(big.Int), n)
+	if err != nil {
+		return 0, err
+	}
+	// C(n, k) = prod (n-k+i)/i for i = 1..k. Each partial product is itself
+	// a binomial coefficient, so the divisions are exact.
+	z := new(Decimal)
+	z.Coeff.SetInt64(1)
+	z.Form = Finite
+	f := new(big.Int).Sub(nb, big.NewInt(kv))
+	i := new(big.Int)
+	for iv := int64(1); iv <= kv; iv++ {
+		f.Add(f, bigOne)
+		z.Coeff.Mul(&z.Coeff, f)
+		z.Coeff.Quo(&z.Coeff, i.SetInt64(iv))
+	}
+	return c.Round(d, z)
+}
+
+// combinatoricOverflow sets d to Infinity and returns the conditions of a
+// result that overflowed.
+func (c *Context) combinatoricOverflow(d *Decimal) (Condition, error) {
+	d.Set(decimalInfinity)
+	return c.goError(Overflow | Inexact | Rounded)
+}
+
+// lgamma sets d to ln|Gamma(x)| for finite x that is not a pole, with enough
+// precision that the absolute error is below 10**-(c.Precision+guard). It
+// returns whether Gamma(x) is negative.
+func (c *Context) lgamma(d, x *Decimal) (neg bool, err error) {
+	// Add precision for the integer digits of the result, which is roughly
+	// x*ln(x) for large x.
+	wp := c.Precision + gammaGuardDigits
+	if adj := adjustedExponent(x); adj > 0 {
+		wp += uint32(adj) + 2
+	}
+	nc := c.baseContext(wp)
+	nc.Rounding = RoundHalfEven
+
+	if !x.Negative {
+		return false, nc.lgammaStirling(d, x)
+	}
+
+	// Use the reflection formula for negative x:
+	//   Gamma(x) * Gamma(1-x) = pi / sin(pi*x)
+	// so
+	//   lgamma(x) = ln(pi) - ln|sin(pi*x)| - lgamma(1-x)
+	// and Gamma(x) has the sign of sin(pi*x). 1-x is computed exactly.
+	ed := MakeErrDecimal(nc)
+	s := new(Decimal)
+	if err := nc.sinPi(s, x); err != nil {
+		return false, err
+	}
+	neg = s.Negative
+	s.Negative = false
+	omx := new(Decimal)
+	if _, err := BaseContext.Sub(omx, decimalOne, x); err != nil {
+		return false, err
+	}
+	if err := nc.lgammaStirling(omx, omx); err != nil {
+		return false, err
+	}
+	z := new(Decimal)
+	ed.Ln(z, decimalPi.get(wp))
+	ed.Ln(s, s)
+	ed.Sub(z, z, s)
+	ed.Sub(d, z, omx)
+	return neg, ed.Err()
+}
+
+// lgammaStirling sets d to ln(Gamma(x)) for x > 0 using Stirling's series
+//
+//   lgamma(x) = (x-1/2)ln(x) - x + ln(2pi)/2 + sum B_2k / (2k(2k-1)x**(2k-1))
+//
+// where B_2k are the Bernoulli numbers. The series is asymptotic, so x is
+// first shifted up by n using lgamma(x) = lgamma(x+n) - ln(x(x+1)...(x+n-1)).
+func (c *Context) lgammaStirling(d, x *Decimal) error {
+	ed := MakeErrDecimal(c)
+	p := int64(c.Precision)
+
+	// With x >= p the terms of the series fall below 10**-p well before they
+	// start to diverge.
+	xs := new(Decimal).Set(x)
+	shift := new(Decimal).SetInt64(1)
+	minX := New(p, 0)
+	for xs.Cmp(minX) < 0 {
+		ed.Mul(shift, shift, xs)
+		ed.Add(xs, xs, decimalOne)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+	}
+
+	// sum = (x-1/2)ln(x) - x + ln(2pi)/2
+	sum := new(Decimal)
+	tmp := new(Decimal)
+	ed.Ln(tmp, xs)
+	ed.Sub(sum, xs, decimalHalf)
+	ed.Mul(sum, sum, tmp)
+	ed.Sub(sum, sum, xs)
+	ed.Mul(tmp, decimalPi.get(c.Precision), decimalTwo)
+	ed.Ln(tmp, tmp)
+	ed.Mul(tmp, tmp, decimalHalf)
+	ed.Add(sum, sum, tmp)
+	if err := ed.Err(); err != nil {
+		return err
+	}
+
+	// pow = 1/x**(2k-1)
+	pow := new(Decimal)
+	inv2 := new(Decimal)
+	ed.Quo(pow, decimalOne, xs)
+	ed.Mul(inv2, pow, pow)
+	term := new(Decimal)
+	num, den := new(Decimal), new(Decimal)
+	eps := adjustedExponent(sum) - p
+	for k := 1; ; k++ {
+		b := bernoulli(2 * k)
+		num.Coeff.Set(b.Num())
+		num.Form = Finite
+		num.Negative = num.Coeff.Sign() < 0
+		num.Coeff.Abs(&num.Coeff)
+		den.Coeff.Mul(b.Denom(), big.NewInt(int64(2*k)*int64(2*k-1)))
+		den.Form = Finite
+		ed.Quo(term, num, den)
+		ed.Mul(term, term, pow)
+		ed.Add(sum, sum, term)
+		ed.Mul(pow, pow, inv2)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+		if term.IsZero() || adjustedExponent(term) < eps {
+			break
+		}
+		if int64(k) > 10*p {
+			return errors.Errorf("lgamma %s: did not converge", x)
+		}
+	}
+
+	ed.Ln(shift, shift)
+	ed.Sub(d, sum, shift)
+	return ed.Err()
+}
+
+// sinPi sets d to sin(pi*x) for finite x. x is reduced exactly, so the
+// result has full relative precision even close to the zeros of sin(pi*x).
+func (c *Context) sinPi(d, x *Decimal) error {
+	// x = integ + frac. sin(pi*(integ+frac)) = (-1)**integ * sin(pi*frac).
+	integ, frac := new(Decimal), new(Decimal)
+	x.Modf(integ, frac)
+	neg := frac.Negative
+	frac.Negative = false
+	if integ.Exponent == 0 && integ.Coeff.Bit(0) == 1 {
+		neg = !neg
+	}
+	if frac.IsZero() {
+		d.SetInt64(0)
+		return nil
+	}
+	// sin(pi*f) = sin(pi*(1-f)), so reduce f to [0, 1/2].
+	if frac.Cmp(decimalHalf) > 0 {
+		if _, err := BaseContext.Sub(frac, decimalOne, frac); err != nil {
+			return err
+		}
+	}
+	nc := c.workingContext(c.Precision + 2)
+	ed := MakeErrDecimal(nc)
+	ed.Mul(frac, frac, decimalPi.get(nc.Precision))
+	if err := ed.Err(); err != nil {
+		return err
+	}
+	if err := nc.sinSeries(d, frac); err != nil {
+		return err
+	}
+	d.Negative = neg
+	return nil
+}
+
+var bernoulliCache struct {
+	sync.Mutex
+	// b[i] is the Bernoulli number B_i.
+	b []*big.Rat
+}
+
+// bernoulli returns the Bernoulli number B_n. The result must not be
+// modified. Computed values are cached.
+func bernoulli(n int) *big.Rat {
+	bernoulliCache.Lock()
+	defer bernoulliCache.Unlock()
+	if n < len(bernoulliCache.b) {
+		return bernoulliCache.b[n]
+	}
+	// Grow geometrically to avoid recomputing the table for each new n.
+	m := 2 * len(bernoulliCache.b)
+	if m <= n {
+		m = n + 1
+	}
+	// Akiyama–Tanigawa algorithm. It computes B_1 as +1/2, which is never
+	// used by Stirling's series.
+	b := make([]*big.Rat, m)
+	a := make([]*big.Rat, m)
+	jr := new(big.Rat)
+	for i := 0; i < m; i++ {
+		a[i] = big.NewRat(1, int64(i+1))
+		for j := i; j >= 1; j-- {
+			a[j-1].Sub(a[j-1], a[j])
+			a[j-1].Mul(a[j-1], jr.SetInt64(int64(j)))
+		}
+		b[i] = new(big.Rat).Set(a[0])
+	}
+	bernoulliCache.b = b
+	return b[n]
+}
@@ -0,0 +1,186 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestGamma(t *testing.T) {
+	tests := []struct {
+		x      string
+		gamma  string
+		lgamma string
+		sign   int
+		flags  Condition
+	}{
+		{x: "1", gamma: "1", lgamma: "0", sign: 1},
+		{x: "2", gamma: "1", lgamma: "0", sign: 1},
+		{x: "5", gamma: "24", lgamma: "3.178053830347945619646942", sign: 1},
+		{x: "0.5", gamma: "1.772453850905516027298167", lgamma: "0.5723649429247000870717137", sign: 1},
+		{x: "1.5", gamma: "0.8862269254527580136490837", lgamma: "-0.1207822376352452223455184", sign: 1},
+		{x: "2.5", gamma: "1.329340388179137020473626", lgamma: "0.2846828704729191596324947", sign: 1},
+		{x: "-0.5", gamma: "-3.544907701811032054596335", lgamma: "1.265512123484645396488946", sign: -1},
+		{x: "-1.5", gamma: "2.363271801207354703064223", lgamma: "0.8600470153764810145109327", sign: 1},
+		{x: "-2.5", gamma: "-0.9453087204829418812256893", lgamma: "-0.05624371649767405067259453", sign: -1},
+		{x: "Infinity", gamma: "Infinity", lgamma: "Infinity", sign: 1},
+		{x: "0", gamma: "Infinity", lgamma: "Infinity", sign: 1, flags: DivisionByZero},
+		{x: "-0", gamma: "-Infinity", lgamma: "Infinity", sign: 1, flags: DivisionByZero},
+		{x: "-3", gamma: "NaN", lgamma: "Infinity", sign: 1, flags: InvalidOperation},
+		{x: "-Infinity", gamma: "NaN", lgamma: "Infinity", sign: 1, flags: InvalidOperation},
+	}
+	c := BaseContext.WithPrecision(25)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			d := new(Decimal)
+			res, err := c.Gamma(d, x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := newDecimal(t, testCtx, tc.gamma)
+			if d.CmpTotal(expect) != 0 && d.Cmp(expect) != 0 {
+				t.Errorf("gamma: expected %s, got %s", expect, d)
+			}
+			if f := res & (DivisionByZero | InvalidOperation); f != tc.flags {
+				t.Errorf("gamma: expected flags %s, got %s", tc.flags, res)
+			}
+
+			sign, _, err := c.Lgamma(d, x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect = newDecimal(t, testCtx, tc.lgamma)
+			if d.CmpTotal(expect) != 0 && d.Cmp(expect) != 0 {
+				t.Errorf("lgamma: expected %s, got %s", expect, d)
+			}
+			if sign != tc.sign {
+				t.Errorf("lgamma: expected sign %d, got %d", tc.sign, sign)
+			}
+		})
+	}
+}
+
+// TestGammaStirling verifies that Stirling's series agrees with the exact
+// factorial past gammaMaxExactArg.
+func TestGammaStirling(t *testing.T) {
+	const n = gammaMaxExactArg + 10
+	c := BaseContext.WithPrecision(30)
+	exact := new(Decimal)
+	exact.Coeff.MulRange(1, n-1)
+	if _, err := c.Round(exact, exact); err != nil {
+		t.Fatal(err)
+	}
+	d := new(Decimal)
+	if _, err := c.Gamma(d, New(n, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if d.Cmp(exact) != 0 {
+		t.Fatalf("expected %s, got %s", exact, d)
+	}
+}
+
+func TestBernoulli(t *testing.T) {
+	expected := []*big.Rat{
+		big.NewRat(1, 1),
+		big.NewRat(1, 6),
+		big.NewRat(-1, 30),
+		big.NewRat(1, 42),
+		big.NewRat(-1, 30),
+		big.NewRat(5, 66),
+		big.NewRat(-691, 2730),
+	}
+	for i, e := range expected {
+		if b := bernoulli(2 * i); b.Cmp(e) != 0 {
+			t.Errorf("B_%d: expected %s, got %s", 2*i, e, b)
+		}
+	}
+}
+
+func TestFactorial(t *testing.T) {
+	tests := []struct {
+		n     string
+		prec  uint32
+		r     string
+		flags Condition
+	}{
+		{n: "0", r: "1"},
+		{n: "1", r: "1"},
+		{n: "5", r: "120"},
+		{n: "25", r: "15511210043330985984000000"},
+		{n: "25", prec: 5, r: "1.5511E+25", flags: Inexact | Rounded},
+		{n: "2.0E+1", r: "2432902008176640000"},
+		{n: "-0", r: "1"},
+		{n: "Infinity", r: "Infinity"},
+		{n: "-1", r: "NaN", flags: InvalidOperation},
+		{n: "2.5", r: "NaN", flags: InvalidOperation},
+		{n: "1E+10", r: "Infinity", flags: Overflow | Inexact | Rounded},
+	}
+	for _, tc := range tests {
+		t.Run(tc.n, func(t *testing.T) {
+			c := BaseContext.WithPrecision(tc.prec)
+			c.Traps = 0
+			d := new(Decimal)
+			res, err := c.Factorial(d, newDecimal(t, testCtx, tc.n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
+
+func TestBinomial(t *testing.T) {
+	tests := []struct {
+		n, k  string
+		r     string
+		flags Condition
+	}{
+		{n: "5", k: "0", r: "1"},
+		{n: "5", k: "5", r: "1"},
+		{n: "5", k: "6", r: "0"},
+		{n: "52", k: "5", r: "2598960"},
+		{n: "100", k: "50", r: "100891344545564193334812497256"},
+		{n: "1E+30", k: "2", r: "499999999999999999999999999999500000000000000000000000000000"},
+		{n: "1E+30", k: "999999999999999999999999999998", r: "499999999999999999999999999999500000000000000000000000000000"},
+		{n: "1E+30", k: "1E+15", r: "Infinity", flags: Overflow | Inexact | Rounded},
+		{n: "-5", k: "2", r: "NaN", flags: InvalidOperation},
+		{n: "5", k: "0.5", r: "NaN", flags: InvalidOperation},
+	}
+	c := BaseContext.WithPrecision(0)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.n+","+tc.k, func(t *testing.T) {
+			d := new(Decimal)
+			res, err := c.Binomial(d, newDecimal(t, testCtx, tc.n), newDecimal(t, testCtx, tc.k))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected flags %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
@@ -260,7 +260,9 @@ func (tc TestCase) Run(c *Context, done chan error, d, x, y *Decimal) (res Condi
 	case "multiply":
 		res, err = c.Mul(d, x, y)
 	case "plus":
-		res, err = c.Add(d, x, decimalZero)
+		// plus is 0+x with the zero taking the exponent of x, so that the
+		// result is not upscaled.
+		res, err = c.Add(d, x, New(0, x.Exponent))
 	case "power":
 		res, err = c.Pow(d, x, y)
 	case "quantize":
@@ -347,14 +349,13 @@ func (tc TestCase) Context(t testing.TB) *Context {
 	if !ok {
 		t.Fatalf("unsupported rounding mode %s", tc.Rounding)
 	}
-	c := &Context{
-		Precision:   uint32(tc.Precision),
-		MaxExponent: int32(tc.MaxExponent),
-		MinExponent: int32(tc.MinExponent),
-		Rounding:    tc.Rounding,
-		Traps:       0,
-	}
-	return c
+	c := ExtendedDefaultContext
+	c.Precision = uint32(tc.Precision)
+	c.MaxExponent = int32(tc.MaxExponent)
+	c.MinExponent = int32(tc.MinExponent)
+	c.Rounding = tc.Rounding
+	c.Clamp = tc.Clamp
+	return &c
 }
 
 func gdaTest(t *testing.T, path string, tcs []TestCase) {
@@ -582,23 +583,6 @@ func gdaTest(t *testing.T, path string, tcs []TestCase) {
 				t.Logf("want flags (%d): %s", rcond, rcond)
 				t.Logf("have flags (%d): %s", res, res)
 
-				// TODO(mjibson): after upscaling, operations need to remove the 0s added
-				// after the operation is done. Since this isn't happening, things are being
-				// rounded when they shouldn't because the coefficient has so many trailing 0s.
-				// Manually remove Rounded flag from context until the TODO is fixed.
-				res &= ^Rounded
-				rcond &= ^Rounded
-
-				switch tc.Operation {
-				case "log10":
-					// TODO(mjibson): Under certain conditions these are exact, but we don't
-					// correctly mark them. Ignore these flags for now.
-					// squareroot sometimes marks things exact when GDA says they should be
-					// inexact.
-					rcond &= ^Inexact
-					res &= ^Inexact
-				}
-
 				// Don't worry about these flags; they are handled by GoError.
 				res &= ^SystemOverflow
 				res &= ^SystemUnderflow
@@ -801,105 +785,9 @@ var GDAignore = map[string]bool{
 	"addx61613": true,
 	"addx61614": true,
 	"addx61618": true,
-
-	// extreme input range, but should work
-	"lnx0902":  true,
-	"sqtx8137": true,
-	"sqtx8139": true,
-	"sqtx8145": true,
-	"sqtx8147": true,
-	"sqtx8149": true,
-	"sqtx8150": true,
-	"sqtx8151": true,
-	"sqtx8158": true,
-	"sqtx8165": true,
-	"sqtx8168": true,
-	"sqtx8174": true,
-	"sqtx8175": true,
-	"sqtx8179": true,
-	"sqtx8182": true,
-	"sqtx8185": true,
-	"sqtx8186": true,
-	"sqtx8195": true,
-	"sqtx8196": true,
-	"sqtx8197": true,
-	"sqtx8199": true,
-	"sqtx8204": true,
-	"sqtx8212": true,
-	"sqtx8213": true,
-	"sqtx8214": true,
-	"sqtx8218": true,
-	"sqtx8219": true,
-	"sqtx8323": true,
-	"sqtx8324": true,
-	"sqtx8331": true,
-	"sqtx8626": true,
-	"sqtx8627": true,
-	"sqtx8628": true,
-	"sqtx8631": true,
-	"sqtx8632": true,
-	"sqtx8633": true,
-	"sqtx8634": true,
-	"sqtx8636": true,
-	"sqtx8637": true,
-	"sqtx8639": true,
-	"sqtx8640": true,
-	"sqtx8641": true,
-	"sqtx8644": true,
-	"sqtx8645": true,
-	"sqtx8646": true,
-	"sqtx8647": true,
-	"sqtx8648": true,
-	"sqtx8649": true,
-	"sqtx8650": true,
-	"sqtx8651": true,
-	"sqtx8652": true,
-	"sqtx8654": true,
-
-	// tricky cases of underflow subnormals
-	"sqtx8700": true,
-	"sqtx8701": true,
-	"sqtx8702": true,
-	"sqtx8703": true,
-	"sqtx8704": true,
-	"sqtx8705": true,
-	"sqtx8706": true,
-	"sqtx8707": true,
-	"sqtx8708": true,
-	"sqtx8709": true,
-	"sqtx8710": true,
-	"sqtx8711": true,
-	"sqtx8712": true,
-	"sqtx8713": true,
-	"sqtx8714": true,
-	"sqtx8715": true,
-	"sqtx8716": true,
-	"sqtx8717": true,
-	"sqtx8718": true,
-	"sqtx8719": true,
-	"sqtx8720": true,
-	"sqtx8721": true,
-	"sqtx8722": true,
-	"sqtx8723": true,
-	"sqtx8724": true,
-	"sqtx8725": true,
-	"sqtx8726": true,
-	"sqtx8727": true,
-	"sqtx8728": true,
-	"sqtx8729": true,
 }
 
 var GDAignoreFlags = map[string]bool{
-	// unflagged clamped
-	"sqtx9024": true,
-	"sqtx9025": true,
-	"sqtx9026": true,
-	"sqtx9027": true,
-	"sqtx9038": true,
-	"sqtx9039": true,
-	"sqtx9040": true,
-	"sqtx9045": true,
-
 	// missing underflow, subnormal
 	"expx048": true,
 	"expx756": true,
@@ -0,0 +1,85 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package apd
+
+import "unsafe"
+
+// Integer is the set of Go's integer types, including types defined on
+// them, such as time.Duration.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Float is the set of Go's floating-point types, including types defined on
+// them.
+type Float interface {
+	~float32 | ~float64
+}
+
+// Number is the set of Go's integer and floating-point types, accepted by
+// FromNumber, SetNumber and CmpNumber.
+type Number interface {
+	Integer | Float
+}
+
+// FromNumber creates a new decimal from n, which can be of any integer or
+// floating-point type, so that FromNumber(42), FromNumber(uint8(7)) and
+// FromNumber(time.Second) need no conversion first. Integers are exact,
+// with an exponent of 0. Floats are converted as by NewFromFloat, to the
+// shortest decimal that converts back to n in n's own precision, so
+// FromNumber(float32(0.1)) is 0.1, not the 0.100000001490116119384765625
+// that float32(0.1) is exactly. Use SetFloat64 for the exact value.
+func FromNumber[T Number](n T) *Decimal {
+	return SetNumber(new(Decimal), n)
+}
+
+// SetNumber sets d to n as described by FromNumber and returns d. It is a
+// function rather than a method of Decimal since Go methods cannot have
+// type parameters.
+func SetNumber[T Number](d *Decimal, n T) *Decimal {
+	// T is told apart by its arithmetic, which, unlike a type switch, also
+	// works for types defined on the basic ones.
+	zero, one := T(0), T(1)
+	switch {
+	case one/(one+one) != zero:
+		bits := 64
+		if unsafe.Sizeof(n) == 4 {
+			bits = 32
+		}
+		return d.setFloatShortest(float64(n), bits)
+	case zero-one < zero:
+		return d.SetInt64(int64(n))
+	}
+	d.Coeff.SetUint64(uint64(n))
+	d.Form = Finite
+	d.Negative = false
+	d.Exponent = 0
+	return d
+}
+
+// CmpNumber compares d with n, which can be of any integer or
+// floating-point type, as d.Cmp(FromNumber(n)) does, so that d can be
+// compared with a Go literal: CmpNumber(d, 100) < 0. A float n is compared
+// by its shortest decimal, as FromNumber converts it, so that 0.1 as a
+// Decimal is equal to 0.1 as a float64. The result is undefined if d or n
+// is NaN.
+func CmpNumber[T Number](d *Decimal, n T) int {
+	var x Decimal
+	return d.Cmp(SetNumber(&x, n))
+}
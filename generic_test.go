@@ -0,0 +1,84 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package apd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type cents int64
+
+func TestFromNumber(t *testing.T) {
+	tests := []struct {
+		d      *Decimal
+		expect string
+	}{
+		{FromNumber(0), "0"},
+		{FromNumber(-42), "-42"},
+		{FromNumber(int8(-128)), "-128"},
+		{FromNumber(uint8(255)), "255"},
+		{FromNumber(int64(math.MinInt64)), "-9223372036854775808"},
+		{FromNumber(uint64(math.MaxUint64)), "18446744073709551615"},
+		{FromNumber(uintptr(7)), "7"},
+		{FromNumber(cents(-1999)), "-1999"},
+		{FromNumber(time.Second), "1000000000"},
+		{FromNumber(0.1), "0.1"},
+		{FromNumber(float32(0.1)), "0.1"},
+		{FromNumber(float32(16777217)), "16777216"},
+		{FromNumber(-2.5e-10), "-2.5E-10"},
+		{FromNumber(100.0), "100"},
+		{FromNumber(math.Copysign(0, -1)), "-0"},
+		{FromNumber(math.Inf(-1)), "-Infinity"},
+		{FromNumber(float32(math.NaN())), "NaN"},
+	}
+	for _, tc := range tests {
+		if s := tc.d.String(); s != tc.expect {
+			t.Errorf("expected %s, got %s", tc.expect, s)
+		}
+	}
+
+	// SetNumber replaces every field of d.
+	d := newDecimal(t, testCtx, "-Infinity")
+	if s := SetNumber(d, uint(3)).String(); s != "3" {
+		t.Errorf("expected 3, got %s", s)
+	}
+}
+
+func TestCmpNumber(t *testing.T) {
+	d := newDecimal(t, testCtx, "0.1")
+	for _, tc := range []struct {
+		c      int
+		expect int
+	}{
+		{CmpNumber(d, 0), 1},
+		{CmpNumber(d, 1), -1},
+		{CmpNumber(d, 0.1), 0},
+		{CmpNumber(d, float32(0.1)), 0},
+		{CmpNumber(d, uint16(0)), 1},
+		{CmpNumber(d, -0.2), 1},
+		{CmpNumber(newDecimal(t, testCtx, "1E+3"), 1000), 0},
+		{CmpNumber(newDecimal(t, testCtx, "-1E+20"), int64(math.MinInt64)), -1},
+		{CmpNumber(newDecimal(t, testCtx, "Infinity"), uint64(math.MaxUint64)), 1},
+	} {
+		if tc.c != tc.expect {
+			t.Errorf("expected %d, got %d", tc.expect, tc.c)
+		}
+	}
+}
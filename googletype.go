@@ -0,0 +1,148 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// This file converts between Decimal and the google.type.Decimal and
+// google.type.Money protocol buffer messages from googleapis. To avoid a
+// dependency on the generated code, the conversions operate on the fields
+// of the messages: the value string of a Decimal, and the units and nanos of
+// a Money. The currency code of a Money is left to the caller.
+
+// nanosPerUnit is the number of nanos in one unit of a google.type.Money.
+const nanosPerUnit = 1e9
+
+// GoogleDecimal returns d formatted as the value of a google.type.Decimal.
+// The result is d.String(): it has no explicit '+' sign, always has an
+// integer digit before the decimal point, and writes a non-zero exponent
+// with an upper-case 'E' and an explicit sign, as in "2.5E+8". Trailing
+// zeros are kept. An error is returned if d is not finite, since
+// google.type.Decimal cannot represent NaN or infinity.
+func (d *Decimal) GoogleDecimal() (string, error) {
+	if d.Form != Finite {
+		return "", errors.Errorf("%s is not finite", d)
+	}
+	return d.String(), nil
+}
+
+// SetGoogleDecimal sets d to the value of the google.type.Decimal value
+// string s, and returns d. s must match the grammar of the message: an
+// optional sign, digits with an optional decimal point and an optional
+// exponent. Non-finite values are rejected. The value is not rounded.
+func (d *Decimal) SetGoogleDecimal(s string) (*Decimal, error) {
+	if !isGoogleDecimal(s) {
+		return nil, errors.Errorf("invalid google.type.Decimal value: %q", s)
+	}
+	if _, _, err := d.SetString(s); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// isGoogleDecimal reports whether s matches the DecimalString grammar of
+// google.type.Decimal.
+func isGoogleDecimal(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		start := i
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// Money returns d as the units and nanos of a google.type.Money. units is
+// the integer part of d and nanos the fractional part in billionths, with
+// the same sign as units. An error is returned if d is not finite, has more
+// than nine fractional digits, or its integer part overflows an int64;
+// the conversion is never lossy.
+func (d *Decimal) Money() (units int64, nanos int32, err error) {
+	var b big.Int
+	if err := unscaledValue(&b, d, 9); err != nil {
+		return 0, 0, errors.Wrap(err, "money")
+	}
+	var n big.Int
+	b.QuoRem(&b, big.NewInt(nanosPerUnit), &n)
+	if !b.IsInt64() {
+		return 0, 0, errors.Errorf("money: %s overflows units", d)
+	}
+	return b.Int64(), int32(n.Int64()), nil
+}
+
+// SetMoney sets d to the value of a google.type.Money with the given units
+// and nanos, and returns d. nanos must be in the range [-999999999,
+// 999999999] and must not have a sign opposite to units. d has the fewest
+// fractional digits that represent the value exactly.
+func (d *Decimal) SetMoney(units int64, nanos int32) (*Decimal, error) {
+	if nanos <= -nanosPerUnit || nanos >= nanosPerUnit {
+		return nil, errors.Errorf("money: nanos %d out of range", nanos)
+	}
+	if (units > 0 && nanos < 0) || (units < 0 && nanos > 0) {
+		return nil, errors.Errorf("money: units %d and nanos %d have different signs", units, nanos)
+	}
+	d.Form = Finite
+	d.Negative = units < 0 || nanos < 0
+	if nanos == 0 {
+		d.Coeff.SetUint64(absUint64(units))
+		d.Exponent = 0
+		return d, nil
+	}
+	n := absUint64(int64(nanos))
+	d.Exponent = -9
+	for n%10 == 0 {
+		n /= 10
+		d.Exponent++
+	}
+	d.Coeff.SetUint64(absUint64(units))
+	d.Coeff.Mul(&d.Coeff, tableExp10(int64(-d.Exponent), nil))
+	d.Coeff.Add(&d.Coeff, new(big.Int).SetUint64(n))
+	return d, nil
+}
+
+// absUint64 returns the absolute value of x. Unlike negation it is correct
+// for math.MinInt64.
+func absUint64(x int64) uint64 {
+	if x < 0 {
+		return uint64(-(x + 1)) + 1
+	}
+	return uint64(x)
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGoogleDecimal(t *testing.T) {
+	tests := []struct {
+		s   string
+		r   string
+		err bool
+	}{
+		{s: "0", r: "0"},
+		{s: "+2.5", r: "2.5"},
+		{s: "-.5", r: "-0.5"},
+		{s: "2.", r: "2"},
+		{s: "2.5e8", r: "2.5E+8"},
+		{s: "2.5E0", r: "2.5"},
+		{s: "1.230e-10", r: "1.230E-10"},
+		{s: "-25e+7", r: "-2.5E+8"},
+		{s: "0.000001", r: "0.000001"},
+		{s: "0.0000001", r: "1E-7"},
+		{s: "", err: true},
+		{s: ".", err: true},
+		{s: "-", err: true},
+		{s: "1e", err: true},
+		{s: "1e+", err: true},
+		{s: "1.2.3", err: true},
+		{s: "NaN", err: true},
+		{s: "Infinity", err: true},
+		{s: " 1", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			d, err := new(Decimal).SetGoogleDecimal(tc.s)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := d.GoogleDecimal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != tc.r {
+				t.Fatalf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+	if _, err := newDecimal(t, testCtx, "Inf").GoogleDecimal(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		x     string
+		units int64
+		nanos int32
+		err   bool
+	}{
+		{x: "0", units: 0, nanos: 0},
+		{x: "1", units: 1, nanos: 0},
+		{x: "1.75", units: 1, nanos: 750000000},
+		{x: "-1.75", units: -1, nanos: -750000000},
+		{x: "-0.000000001", units: 0, nanos: -1},
+		{x: "1E+3", units: 1000, nanos: 0},
+		{x: "9223372036854775807.999999999", units: math.MaxInt64, nanos: 999999999},
+		{x: "-9223372036854775808.5", units: math.MinInt64, nanos: -500000000},
+		{x: "9223372036854775808", err: true},
+		{x: "0.0000000001", err: true},
+		{x: "NaN", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			units, nanos, err := x.Money()
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if units != tc.units || nanos != tc.nanos {
+				t.Fatalf("expected %d, %d, got %d, %d", tc.units, tc.nanos, units, nanos)
+			}
+			d, err := new(Decimal).SetMoney(units, nanos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Cmp(x) != 0 {
+				t.Fatalf("expected %s, got %s", x, d)
+			}
+		})
+	}
+}
+
+func TestSetMoney(t *testing.T) {
+	tests := []struct {
+		units int64
+		nanos int32
+		r     string
+		err   bool
+	}{
+		{units: 5, nanos: 0, r: "5"},
+		{units: 5, nanos: 10000000, r: "5.01"},
+		{units: -5, nanos: -10000000, r: "-5.01"},
+		{units: 0, nanos: -1, r: "-1E-9"},
+		{units: 1, nanos: -1, err: true},
+		{units: -1, nanos: 1, err: true},
+		{units: 0, nanos: 1000000000, err: true},
+		{units: 0, nanos: -1000000000, err: true},
+	}
+	for _, tc := range tests {
+		d, err := new(Decimal).SetMoney(tc.units, tc.nanos)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d, %d: expected error", tc.units, tc.nanos)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != tc.r {
+			t.Errorf("%d, %d: expected %s, got %s", tc.units, tc.nanos, tc.r, s)
+		}
+	}
+}
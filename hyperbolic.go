@@ -0,0 +1,458 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// hyperbolicGuardDigits is the number of extra digits of precision used
+// while computing hyperbolic functions.
+const hyperbolicGuardDigits = 5
+
+// Sinh sets d to the hyperbolic sine of x.
+func (c *Context) Sinh(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Form == Infinite || x.IsZero() {
+		d.Set(x)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	nc := c.baseContext(c.Precision + hyperbolicGuardDigits)
+	nc.Rounding = RoundHalfEven
+	z := new(Decimal)
+	if adjustedExponent(x) < 0 {
+		// For |x| < 1, (e**x - e**-x)/2 cancels away most of the significant
+		// digits, so use the power series instead.
+		if err := nc.sinhSeries(z, x); err != nil {
+			return 0, err
+		}
+	} else {
+		// sinh(x) = (e**|x| - e**-|x|) / 2, with the sign of x.
+		ex := new(Decimal)
+		if overflow, err := nc.expAbs(ex, x); err != nil {
+			return 0, err
+		} else if overflow {
+			d.Set(decimalInfinity)
+			d.Negative = x.Negative
+			return c.goError(Overflow | Inexact | Rounded)
+		}
+		ed := MakeErrDecimal(nc)
+		ed.Quo(z, decimalOne, ex)
+		ed.Sub(z, ex, z)
+		ed.Mul(z, z, decimalHalf)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		z.Negative = x.Negative
+	}
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// Cosh sets d to the hyperbolic cosine of x.
+func (c *Context) Cosh(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Form == Infinite {
+		d.Set(decimalInfinity)
+		return 0, nil
+	}
+	if x.IsZero() {
+		d.Set(decimalOne)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	// cosh(x) = (e**|x| + e**-|x|) / 2. Both terms are positive, so there is
+	// no cancellation for any x.
+	nc := c.baseContext(c.Precision + hyperbolicGuardDigits)
+	nc.Rounding = RoundHalfEven
+	ex := new(Decimal)
+	if overflow, err := nc.expAbs(ex, x); err != nil {
+		return 0, err
+	} else if overflow {
+		d.Set(decimalInfinity)
+		return c.goError(Overflow | Inexact | Rounded)
+	}
+	ed := MakeErrDecimal(nc)
+	z := new(Decimal)
+	ed.Quo(z, decimalOne, ex)
+	ed.Add(z, ex, z)
+	ed.Mul(z, z, decimalHalf)
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// expAbs sets ex to e**|x| and reports whether it overflowed, in which case
+// the sinh or cosh of x is an infinity. The overflow is not trapped here, so
+// that the caller can report it under its own traps as Exp does.
+func (c *Context) expAbs(ex, x *Decimal) (overflow bool, err error) {
+	ec := *c
+	ec.Traps = 0
+	res, err := ec.Exp(ex, new(Decimal).Abs(x))
+	if err != nil {
+		return false, err
+	}
+	return res.Overflow(), nil
+}
+
+// Tanh sets d to the hyperbolic tangent of x.
+func (c *Context) Tanh(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Form == Infinite {
+		d.Set(decimalOne)
+		d.Negative = x.Negative
+		return 0, nil
+	}
+	if x.IsZero() {
+		d.Set(x)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	p := c.Precision + hyperbolicGuardDigits
+	nc := c.baseContext(p)
+	nc.Rounding = RoundHalfEven
+	ed := MakeErrDecimal(nc)
+	ax := new(Decimal).Abs(x)
+	z := new(Decimal)
+
+	// 1 - tanh(|x|) is about 2e**-2|x|. Once that is smaller than the last of
+	// the guard digits, the result is 1 minus a tiny amount. Represent it as
+	// such so it is rounded correctly under every rounding mode without
+	// computing a huge exponential.
+	if f, err := ax.Float64(); err == nil && f > float64(p+1)*math.Ln10/2 {
+		z.SetFinite(1, 0)
+		ed.Sub(z, z, New(1, -int32(p)))
+	} else if adjustedExponent(x) < 0 {
+		// tanh(x) = sinh(x) / cosh(x), where sinh(x) uses the power series to
+		// avoid cancellation near 0 and cosh(x) = sqrt(1 + sinh(x)**2).
+		if err := nc.sinhSeries(z, ax); err != nil {
+			return 0, err
+		}
+		tmp := new(Decimal)
+		ed.Mul(tmp, z, z)
+		ed.Add(tmp, tmp, decimalOne)
+		ed.Sqrt(tmp, tmp)
+		ed.Quo(z, z, tmp)
+	} else {
+		// tanh(x) = 1 - 2 / (e**2|x| + 1).
+		ed.Add(z, ax, ax)
+		ed.Exp(z, z)
+		ed.Add(z, z, decimalOne)
+		ed.Quo(z, decimalTwo, z)
+		ed.Sub(z, decimalOne, z)
+	}
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	z.Negative = x.Negative
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// sinhSeries sets d to sinh(x) using the power series
+//
+//   sinh(x) = x + x**3/3! + x**5/5! + ...
+//
+// which converges rapidly for |x| < 1. Terms are summed until they no longer
+// affect the result at c.Precision.
+func (c *Context) sinhSeries(d, x *Decimal) error {
+	// The second term is x**2/6 relative to the first, so it can't affect the
+	// result if x is tiny. Return early so that x**2 doesn't underflow.
+	if 2*adjustedExponent(x) < -int64(c.Precision)-1 {
+		d.Set(x)
+		return nil
+	}
+	ed := MakeErrDecimal(c)
+	x2 := new(Decimal)
+	term := new(Decimal).Set(x)
+	sum := new(Decimal).Set(x)
+	n := new(Decimal)
+	ed.Mul(x2, x, x)
+	for k := int64(1); ; k++ {
+		// term *= x**2 / ((2k) * (2k+1))
+		n.SetInt64((2 * k) * (2*k + 1))
+		ed.Mul(term, term, x2)
+		ed.Quo(term, term, n)
+		ed.Add(sum, sum, term)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+		if term.IsZero() || adjustedExponent(term) < adjustedExponent(sum)-int64(c.Precision)-1 {
+			break
+		}
+	}
+	d.Set(sum)
+	return nil
+}
+
+// adjustedExponent returns the exponent of d in scientific notation, i.e.,
+// the exponent plus the number of digits minus one.
+func adjustedExponent(d *Decimal) int64 {
+	return int64(d.Exponent) + d.NumDigits() - 1
+}
+
+// Asinh sets d to the inverse hyperbolic sine of x.
+func (c *Context) Asinh(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Form == Infinite || x.IsZero() {
+		d.Set(x)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	nc := c.baseContext(c.Precision + hyperbolicGuardDigits)
+	nc.Rounding = RoundHalfEven
+	z := new(Decimal)
+	if err := nc.asinh(z, x); err != nil {
+		return 0, err
+	}
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// asinh sets d to asinh(x) at c.Precision for finite, non-zero x.
+func (c *Context) asinh(d, x *Decimal) error {
+	ed := MakeErrDecimal(c)
+	ax := new(Decimal).Abs(x)
+	z := new(Decimal)
+	switch adj := adjustedExponent(x); {
+	case 2*adj < -int64(c.Precision)-1:
+		// asinh(x) = x - x**3/6 + ..., so as in sinhSeries the second term
+		// can't affect the result. Return early so that x**2 doesn't
+		// underflow.
+		z.Set(ax)
+	case adj < 0:
+		// ln(|x| + sqrt(x**2 + 1)) loses most of its digits near 0, so use
+		// asinh(x) = atanh(x / sqrt(x**2 + 1)) instead. The argument to atanh
+		// is at most 1/sqrt(2).
+		ed.Mul(z, ax, ax)
+		ed.Add(z, z, decimalOne)
+		ed.Sqrt(z, z)
+		ed.Quo(z, ax, z)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+		if err := c.atanhSeries(z, z); err != nil {
+			return err
+		}
+	case 2*adj > int64(c.Precision):
+		// x**2 + 1 == x**2 at this precision and x**2 could overflow, so use
+		// asinh(x) = ln(2|x|).
+		ed.Ln(z, ax)
+		ed.Add(z, z, c.ln2())
+	default:
+		// asinh(x) = ln(|x| + sqrt(x**2 + 1)).
+		ed.Mul(z, ax, ax)
+		ed.Add(z, z, decimalOne)
+		ed.Sqrt(z, z)
+		ed.Add(z, z, ax)
+		ed.Ln(z, z)
+	}
+	if err := ed.Err(); err != nil {
+		return err
+	}
+	d.Set(z)
+	d.Negative = x.Negative
+	return nil
+}
+
+// Acosh sets d to the inverse hyperbolic cosine of x. x must be >= 1.
+func (c *Context) Acosh(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Negative || x.Cmp(decimalOne) < 0 {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	if x.Form == Infinite {
+		d.Set(decimalInfinity)
+		return 0, nil
+	}
+	if x.Cmp(decimalOne) == 0 {
+		d.Set(decimalZero)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	nc := c.baseContext(c.Precision + hyperbolicGuardDigits)
+	nc.Rounding = RoundHalfEven
+	ed := MakeErrDecimal(nc)
+	z := new(Decimal)
+	if x.Cmp(decimalTwo) < 0 {
+		// Near 1, x**2 - 1 and the following Ln both cancel. Use the identity
+		// acosh(x) = 2 * asinh(sqrt((x - 1) / 2)) instead. x - 1 is computed
+		// exactly.
+		if _, err := BaseContext.Sub(z, x, decimalOne); err != nil {
+			return 0, err
+		}
+		ed.Mul(z, z, decimalHalf)
+		ed.Sqrt(z, z)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		if err := nc.asinh(z, z); err != nil {
+			return 0, err
+		}
+		ed.Add(z, z, z)
+	} else if 2*adjustedExponent(x) > int64(nc.Precision) {
+		// x**2 - 1 == x**2 at this precision and x**2 could overflow, so use
+		// acosh(x) = ln(2x).
+		ed.Ln(z, x)
+		ed.Add(z, z, nc.ln2())
+	} else {
+		// acosh(x) = ln(x + sqrt(x**2 - 1)).
+		ed.Mul(z, x, x)
+		ed.Sub(z, z, decimalOne)
+		ed.Sqrt(z, z)
+		ed.Add(z, z, x)
+		ed.Ln(z, z)
+	}
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// Atanh sets d to the inverse hyperbolic tangent of x. x must be in the
+// range [-1, 1]. Atanh of -1 or 1 is -Infinity or Infinity, with
+// DivisionByZero, as for the logarithm of 0.
+func (c *Context) Atanh(d, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	ax := new(Decimal).Abs(x)
+	switch ax.Cmp(decimalOne) {
+	case 1:
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	case 0:
+		d.Set(decimalInfinity)
+		d.Negative = x.Negative
+		return c.goError(DivisionByZero)
+	}
+	if x.IsZero() {
+		d.Set(x)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+
+	nc := c.baseContext(c.Precision + hyperbolicGuardDigits)
+	nc.Rounding = RoundHalfEven
+	z := new(Decimal)
+	if ax.Cmp(decimalHalf) <= 0 {
+		// ln((1 + x) / (1 - x)) cancels near 0, so use the power series.
+		if err := nc.atanhSeries(z, ax); err != nil {
+			return 0, err
+		}
+	} else {
+		// atanh(x) = ln((1 + x) / (1 - x)) / 2. 1 + x and 1 - x are computed
+		// exactly.
+		ed := MakeErrDecimal(nc)
+		num, den := new(Decimal), new(Decimal)
+		if _, err := BaseContext.Add(num, decimalOne, ax); err != nil {
+			return 0, err
+		}
+		if _, err := BaseContext.Sub(den, decimalOne, ax); err != nil {
+			return 0, err
+		}
+		ed.Quo(z, num, den)
+		ed.Ln(z, z)
+		ed.Mul(z, z, decimalHalf)
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+	}
+	z.Negative = x.Negative
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// atanhSeries sets d to atanh(x) using the power series
+//
+//   atanh(x) = x + x**3/3 + x**5/5 + ...
+//
+// which converges for |x| < 1, and rapidly for |x| <= 1/sqrt(2).
+func (c *Context) atanhSeries(d, x *Decimal) error {
+	// See the comment in sinhSeries.
+	if 2*adjustedExponent(x) < -int64(c.Precision)-1 {
+		d.Set(x)
+		return nil
+	}
+	ed := MakeErrDecimal(c)
+	x2 := new(Decimal)
+	pow := new(Decimal).Set(x)
+	term := new(Decimal)
+	sum := new(Decimal).Set(x)
+	n := new(Decimal)
+	ed.Mul(x2, x, x)
+	for k := int64(1); ; k++ {
+		// term = x**(2k+1) / (2k+1)
+		n.SetInt64(2*k + 1)
+		ed.Mul(pow, pow, x2)
+		ed.Quo(term, pow, n)
+		ed.Add(sum, sum, term)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+		if term.IsZero() || adjustedExponent(term) < adjustedExponent(sum)-int64(c.Precision)-1 {
+			break
+		}
+	}
+	d.Set(sum)
+	return nil
+}
+
+// ln2 returns ln(2) at c.Precision.
+func (c *Context) ln2() *Decimal {
+	d := new(Decimal)
+	_, _ = c.Ln(d, decimalTwo)
+	return d
+}
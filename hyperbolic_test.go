@@ -0,0 +1,168 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestHyperbolic(t *testing.T) {
+	tests := []struct {
+		x                string
+		sinh, cosh, tanh string
+	}{
+		{x: "0", sinh: "0", cosh: "1", tanh: "0"},
+		{x: "-0", sinh: "-0", cosh: "1", tanh: "-0"},
+		{x: "0.5", sinh: "0.5210953054937474", cosh: "1.127625965206381", tanh: "0.4621171572600098"},
+		{x: "-0.001", sinh: "-0.001000000166666675", cosh: "1.000000500000042", tanh: "-0.0009999996666668000"},
+		{x: "1", sinh: "1.175201193643801", cosh: "1.543080634815244", tanh: "0.7615941559557649"},
+		{x: "2.5", sinh: "6.050204481039787", cosh: "6.132289479663686", tanh: "0.9866142981514303"},
+		{x: "-10", sinh: "-11013.23287470339", cosh: "11013.23292010332", tanh: "-0.9999999958776928"},
+		{x: "1E-20", sinh: "1.000000000000000E-20", cosh: "1", tanh: "1.000000000000000E-20"},
+		{x: "0.0000001", sinh: "1.000000000000002E-7", cosh: "1.000000000000005", tanh: "9.999999999999967E-8"},
+		{x: "30", sinh: "5343237290762.231", cosh: "5343237290762.231", tanh: "1"},
+		{x: "100", sinh: "1.344058570908068E+43", cosh: "1.344058570908068E+43", tanh: "1"},
+		{x: "Infinity", sinh: "Infinity", cosh: "Infinity", tanh: "1"},
+		{x: "-Infinity", sinh: "-Infinity", cosh: "Infinity", tanh: "-1"},
+		{x: "NaN", sinh: "NaN", cosh: "NaN", tanh: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			for _, op := range []struct {
+				name   string
+				f      func(d, x *Decimal) (Condition, error)
+				expect string
+			}{
+				{"sinh", c.Sinh, tc.sinh},
+				{"cosh", c.Cosh, tc.cosh},
+				{"tanh", c.Tanh, tc.tanh},
+			} {
+				d := new(Decimal)
+				if _, err := op.f(d, x); err != nil {
+					t.Fatalf("%s: %+v", op.name, err)
+				}
+				expect := newDecimal(t, testCtx, op.expect)
+				if d.CmpTotal(expect) != 0 && (d.Cmp(expect) != 0 || d.Negative != expect.Negative) {
+					t.Errorf("%s: expected %s, got %s", op.name, expect, d)
+				}
+			}
+		})
+	}
+}
+
+func TestHyperbolicZeroPrecision(t *testing.T) {
+	c := BaseContext.WithPrecision(0)
+	d := new(Decimal)
+	for _, f := range []func(d, x *Decimal) (Condition, error){c.Sinh, c.Cosh, c.Tanh} {
+		if _, err := f(d, New(1, 0)); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestHyperbolicOverflow(t *testing.T) {
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	const flags = Overflow | Inexact | Rounded
+	for _, tc := range []struct {
+		name   string
+		f      func(d, x *Decimal) (Condition, error)
+		x      string
+		expect string
+	}{
+		{"sinh", c.Sinh, "1E+10", "Infinity"},
+		{"sinh", c.Sinh, "-1E+10", "-Infinity"},
+		{"sinh", c.Sinh, "300000", "Infinity"},
+		{"cosh", c.Cosh, "1E+10", "Infinity"},
+		{"cosh", c.Cosh, "-1E+10", "Infinity"},
+	} {
+		d := new(Decimal)
+		res, err := tc.f(d, newDecimal(t, testCtx, tc.x))
+		if err != nil {
+			t.Fatalf("%s(%s): %v", tc.name, tc.x, err)
+		}
+		if s := d.String(); s != tc.expect {
+			t.Errorf("%s(%s): expected %s, got %s", tc.name, tc.x, tc.expect, s)
+		}
+		if res != flags {
+			t.Errorf("%s(%s): expected flags %s, got %s", tc.name, tc.x, Condition(flags), res)
+		}
+	}
+
+	// With the default traps, the overflow is an error.
+	c = BaseContext.WithPrecision(16)
+	if _, err := c.Sinh(new(Decimal), New(1, 10)); err == nil {
+		t.Error("expected overflow error")
+	}
+}
+
+func TestInverseHyperbolic(t *testing.T) {
+	tests := []struct {
+		x                   string
+		asinh, acosh, atanh string
+	}{
+		{x: "0", asinh: "0", acosh: "NaN", atanh: "0"},
+		{x: "-0", asinh: "-0", acosh: "NaN", atanh: "-0"},
+		{x: "0.5", asinh: "0.4812118250596034", acosh: "NaN", atanh: "0.5493061443340548"},
+		{x: "-0.001", asinh: "-0.0009999998333334083", acosh: "NaN", atanh: "-0.001000000333333533"},
+		{x: "0.0000001", asinh: "9.999999999999983E-8", acosh: "NaN", atanh: "1.000000000000003E-7"},
+		{x: "1E-20", asinh: "1.000000000000000E-20", acosh: "NaN", atanh: "1.000000000000000E-20"},
+		{x: "-1E-60000", asinh: "-1.000000000000000E-60000", acosh: "NaN", atanh: "-1.000000000000000E-60000"},
+		{x: "0.9", asinh: "0.8088669356527825", acosh: "NaN", atanh: "1.472219489583220"},
+		{x: "-0.999999", asinh: "-0.8813728799125851", acosh: "NaN", atanh: "-7.254328619262047"},
+		{x: "1", asinh: "0.8813735870195430", acosh: "0", atanh: "Infinity"},
+		{x: "-1", asinh: "-0.8813735870195430", acosh: "NaN", atanh: "-Infinity"},
+		{x: "1.0000001", asinh: "0.8813736577302194", acosh: "0.0004472135917731781", atanh: "NaN"},
+		{x: "1.5", asinh: "1.194763217287109", acosh: "0.9624236501192069", atanh: "NaN"},
+		{x: "2.5", asinh: "1.647231146371096", acosh: "1.566799236972411", atanh: "NaN"},
+		{x: "3", asinh: "1.818446459232067", acosh: "1.762747174039086", atanh: "NaN"},
+		{x: "-10", asinh: "-2.998222950297970", acosh: "NaN", atanh: "NaN"},
+		{x: "1E+30", asinh: "69.77069997038132", acosh: "69.77069997038132", atanh: "NaN"},
+		{x: "Infinity", asinh: "Infinity", acosh: "Infinity", atanh: "NaN"},
+		{x: "-Infinity", asinh: "-Infinity", acosh: "NaN", atanh: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			for _, op := range []struct {
+				name   string
+				f      func(d, x *Decimal) (Condition, error)
+				expect string
+			}{
+				{"asinh", c.Asinh, tc.asinh},
+				{"acosh", c.Acosh, tc.acosh},
+				{"atanh", c.Atanh, tc.atanh},
+			} {
+				d := new(Decimal)
+				res, err := op.f(d, x)
+				if err != nil {
+					t.Fatalf("%s: %+v", op.name, err)
+				}
+				expect := newDecimal(t, testCtx, op.expect)
+				if expect.Form == NaN && !res.InvalidOperation() {
+					t.Errorf("%s: expected invalid operation, got %s", op.name, res)
+				}
+				if expect.Form == Infinite && x.Form == Finite && !res.DivisionByZero() {
+					t.Errorf("%s: expected division by zero, got %s", op.name, res)
+				}
+				if d.CmpTotal(expect) != 0 && (d.Cmp(expect) != 0 || d.Negative != expect.Negative) {
+					t.Errorf("%s: expected %s, got %s", op.name, expect, d)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// PowMod sets d to x**y mod m. x, y and m must be integers, y must not be
+// negative, m must not be zero, and x and y must not both be zero. As with
+// Rem, the result has the sign of x**y.
+func (c *Context) PowMod(d, x, y, m *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x, y, m); set {
+		return res, err
+	}
+	_, xok := integerArg(x)
+	_, yok := integerArg(y)
+	_, mok := integerArg(m)
+	if !xok || !yok || !mok || y.Sign() < 0 || m.IsZero() || (x.IsZero() && y.IsZero()) {
+		d.Set(decimalNaN)
+		return c.goError(InvalidOperation)
+	}
+	var xb, yb, mb big.Int
+	for _, op := range []struct {
+		b *big.Int
+		x *Decimal
+	}{{&xb, x}, {&yb, y}, {&mb, m}} {
+		if _, err := integerValue(op.b, op.x); err != nil {
+			return 0, err
+		}
+	}
+	neg := x.Negative && yb.Bit(0) == 1
+	if err := c.expMod(&d.Coeff, &xb, &yb, &mb); err != nil {
+		return 0, err
+	}
+	d.Exponent = 0
+	d.Form = Finite
+	d.Negative = neg
+	return c.Round(d, d)
+}
+
+// expMod sets z to x**y mod m for non-negative x and y and positive m, like
+// z.Exp(x, y, m). If c.Done is set, y is processed one word at a time so
+// that a cancellation is noticed between words, at the cost of about twice
+// as many multiplications.
+func (c *Context) expMod(z, x, y, m *big.Int) error {
+	if c.Done == nil {
+		z.Exp(x, y, m)
+		return nil
+	}
+	// x**y = (x**hi)**(2**w) * x**lo, where lo is the low word of y and hi
+	// the remaining words.
+	shift := new(big.Int).Lsh(bigOne, bits.UintSize)
+	r := new(big.Int).Mod(bigOne, m)
+	var w, t big.Int
+	words := y.Bits()
+	for i := len(words) - 1; i >= 0; i-- {
+		if err := c.canceled(); err != nil {
+			return err
+		}
+		if i < len(words)-1 {
+			r.Exp(r, shift, m)
+		}
+		w.SetBits([]big.Word{words[i]})
+		r.Mul(r, t.Exp(x, &w, m))
+		r.Mod(r, m)
+	}
+	z.Set(r)
+	return nil
+}
+
+// GCD sets d to the greatest common divisor of x and y, which must be
+// integers. The result is never negative, and GCD(0, 0) is 0.
+func (c *Context) GCD(d, x, y *Decimal) (Condition, error) {
+	xb, yb, res, err := c.integerOperands(d, x, y)
+	if xb == nil {
+		return res, err
+	}
+	d.Coeff.GCD(nil, nil, xb, yb)
+	d.Exponent = 0
+	d.Form = Finite
+	d.Negative = false
+	return c.Round(d, d)
+}
+
+// LCM sets d to the least common multiple of x and y, which must be
+// integers. The result is never negative, and is 0 if either operand is 0.
+func (c *Context) LCM(d, x, y *Decimal) (Condition, error) {
+	xb, yb, res, err := c.integerOperands(d, x, y)
+	if xb == nil {
+		return res, err
+	}
+	if xb.Sign() == 0 || yb.Sign() == 0 {
+		d.Coeff.SetInt64(0)
+	} else {
+		// lcm(x, y) = x / gcd(x, y) * y.
+		g := new(big.Int).GCD(nil, nil, xb, yb)
+		d.Coeff.Quo(xb, g)
+		d.Coeff.Mul(&d.Coeff, yb)
+	}
+	d.Exponent = 0
+	d.Form = Finite
+	d.Negative = false
+	return c.Round(d, d)
+}
+
+// ISqrt sets d to the integer square root of x, the largest integer whose
+// square is at most x. If r is not nil, it is set to the remainder x - d**2.
+// x must be a non-negative integer. Unlike Sqrt, the result is computed
+// exactly and is only rounded if it has more digits than c.Precision.
+func (c *Context) ISqrt(d, r, x *Decimal) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		if r != nil {
+			r.Set(d)
+		}
+		return res, err
+	}
+	if _, ok := integerArg(x); !ok || x.Sign() < 0 {
+		d.Set(decimalNaN)
+		if r != nil {
+			r.Set(decimalNaN)
+		}
+		return c.goError(InvalidOperation)
+	}
+	xb, err := integerValue(new(big.Int), x)
+	if err != nil {
+		return 0, err
+	}
+	var res Condition
+	s := new(big.Int).Sqrt(xb)
+	if r != nil {
+		r.Coeff.Mul(s, s)
+		r.Coeff.Sub(xb, &r.Coeff)
+		r.Exponent = 0
+		r.Form = Finite
+		r.Negative = false
+		res |= c.round(r, r)
+	}
+	d.Coeff.Set(s)
+	d.Exponent = 0
+	d.Form = Finite
+	d.Negative = false
+	res |= c.round(d, d)
+	return c.goError(res)
+}
+
+// integerOperands returns |x| and |y| as big.Ints. If either is NaN or not
+// an integer, d is set to NaN and nil is returned along with the condition
+// and error to return.
+func (c *Context) integerOperands(d, x, y *Decimal) (xb, yb *big.Int, res Condition, err error) {
+	if set, res, err := c.setIfNaN(d, x, y); set {
+		return nil, nil, res, err
+	}
+	_, xok := integerArg(x)
+	_, yok := integerArg(y)
+	if !xok || !yok {
+		d.Set(decimalNaN)
+		res, err := c.goError(InvalidOperation)
+		return nil, nil, res, err
+	}
+	if xb, err = integerValue(new(big.Int), x); err != nil {
+		return nil, nil, 0, err
+	}
+	if yb, err = integerValue(new(big.Int), y); err != nil {
+		return nil, nil, 0, err
+	}
+	return xb, yb, 0, nil
+}
+
+// integerValue sets b to |x|, which must be a finite integer, and returns
+// b. An error is returned if x's exponent is too large.
+func integerValue(b *big.Int, x *Decimal) (*big.Int, error) {
+	integ := new(Decimal)
+	x.Modf(integ, nil)
+	e, err := exp10(int64(integ.Exponent))
+	if err != nil {
+		return nil, err
+	}
+	b.Mul(&integ.Coeff, e)
+	return b, nil
+}
+
+// integerArg returns |x| as an int64 and true if x is a finite integer.
+// math.MaxInt64 is returned if |x| is larger than that.
+func integerArg(x *Decimal) (int64, bool) {
+	if !x.IsInteger() {
+		return 0, false
+	}
+	if adjustedExponent(x) > 18 {
+		return math.MaxInt64, true
+	}
+	n, err := new(Decimal).Abs(x).Int64()
+	if err != nil {
+		return math.MaxInt64, true
+	}
+	return n, true
+}
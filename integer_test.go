@@ -0,0 +1,174 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPowMod(t *testing.T) {
+	tests := []struct {
+		x, y, m string
+		r       string
+		flags   Condition
+	}{
+		{x: "2", y: "10", m: "1000", r: "24"},
+		{x: "4", y: "13", m: "497", r: "445"},
+		{x: "-2", y: "3", m: "5", r: "-3"},
+		{x: "-2", y: "2", m: "5", r: "4"},
+		{x: "2", y: "3", m: "-5", r: "3"},
+		{x: "3", y: "0", m: "7", r: "1"},
+		{x: "0", y: "5", m: "7", r: "0"},
+		{x: "1.00E+2", y: "2.0", m: "7", r: "4"},
+		{x: "123456789", y: "1E+20", m: "1000000007", r: "968560874"},
+		{x: "123456789", y: "1E+40", m: "1000000007", r: "482126905"},
+		{x: "2.5", y: "2", m: "7", r: "NaN", flags: InvalidOperation},
+		{x: "2", y: "-1", m: "7", r: "NaN", flags: InvalidOperation},
+		{x: "2", y: "2", m: "0", r: "NaN", flags: InvalidOperation},
+		{x: "0", y: "0", m: "7", r: "NaN", flags: InvalidOperation},
+		{x: "Infinity", y: "2", m: "7", r: "NaN", flags: InvalidOperation},
+		{x: "NaN", y: "2", m: "7", r: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	// With Done set, PowMod takes the path that polls it.
+	cc := *c
+	cc.Done = make(chan struct{})
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s, %s", tc.x, tc.y, tc.m), func(t *testing.T) {
+			for _, c := range []*Context{c, &cc} {
+				d := new(Decimal)
+				res, err := c.PowMod(d, newDecimal(t, testCtx, tc.x), newDecimal(t, testCtx, tc.y), newDecimal(t, testCtx, tc.m))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s := d.String(); s != tc.r {
+					t.Errorf("done %v: expected %s, got %s", c.Done != nil, tc.r, s)
+				}
+				if res != tc.flags {
+					t.Errorf("done %v: expected flags %s, got %s", c.Done != nil, tc.flags, res)
+				}
+			}
+		})
+	}
+
+	// A large exponent is canceled through c.Done, directly and through
+	// ErrDecimal.
+	done := make(chan struct{})
+	close(done)
+	cc.Done = done
+	x, y, m := New(3, 0), New(1, 5000), New(1000000007, 0)
+	if _, err := cc.PowMod(new(Decimal), x, y, m); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+	ed := MakeErrDecimal(&cc)
+	ed.PowMod(new(Decimal), x, y, m)
+	if err := ed.Err(); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+}
+
+func TestGCDLCM(t *testing.T) {
+	tests := []struct {
+		x, y     string
+		gcd, lcm string
+	}{
+		{x: "12", y: "18", gcd: "6", lcm: "36"},
+		{x: "-12", y: "18", gcd: "6", lcm: "36"},
+		{x: "7", y: "13", gcd: "1", lcm: "91"},
+		{x: "0", y: "5", gcd: "5", lcm: "0"},
+		{x: "0", y: "0", gcd: "0", lcm: "0"},
+		{x: "1.2E+3", y: "1.80E+2", gcd: "60", lcm: "3600"},
+		{x: "1E+20", y: "4E+18", gcd: "4.000000000000000E+18", lcm: "1.000000000000000E+20"},
+		{x: "1.5", y: "3", gcd: "NaN", lcm: "NaN"},
+		{x: "Infinity", y: "3", gcd: "NaN", lcm: "NaN"},
+		{x: "NaN", y: "3", gcd: "NaN", lcm: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s, %s", tc.x, tc.y), func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			y := newDecimal(t, testCtx, tc.y)
+			for _, op := range []struct {
+				name   string
+				f      func(d, x, y *Decimal) (Condition, error)
+				expect string
+			}{
+				{"gcd", c.GCD, tc.gcd},
+				{"lcm", c.LCM, tc.lcm},
+			} {
+				d := new(Decimal)
+				res, err := op.f(d, x, y)
+				if err != nil {
+					t.Fatalf("%s: %+v", op.name, err)
+				}
+				if s := d.String(); s != op.expect {
+					t.Errorf("%s: expected %s, got %s", op.name, op.expect, s)
+				}
+				if invalid := op.expect == "NaN" && x.Form != NaN; invalid != res.InvalidOperation() {
+					t.Errorf("%s: unexpected flags %s", op.name, res)
+				}
+			}
+		})
+	}
+}
+
+func TestISqrt(t *testing.T) {
+	tests := []struct {
+		x    string
+		s, r string
+	}{
+		{x: "0", s: "0", r: "0"},
+		{x: "1", s: "1", r: "0"},
+		{x: "15", s: "3", r: "6"},
+		{x: "16", s: "4", r: "0"},
+		{x: "1.00E+2", s: "10", r: "0"},
+		{x: "99999999999999999999", s: "9999999999", r: "19999999998"},
+		{x: "1E+40", s: "100000000000000000000", r: "0"},
+		{x: "-4", s: "NaN", r: "NaN"},
+		{x: "2.5", s: "NaN", r: "NaN"},
+		{x: "Infinity", s: "NaN", r: "NaN"},
+		{x: "NaN", s: "NaN", r: "NaN"},
+	}
+	c := BaseContext.WithPrecision(0)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			d := new(Decimal)
+			r := new(Decimal)
+			res, err := c.ISqrt(d, r, newDecimal(t, testCtx, tc.x))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.s {
+				t.Errorf("expected %s, got %s", tc.s, s)
+			}
+			if s := r.String(); s != tc.r {
+				t.Errorf("expected remainder %s, got %s", tc.r, s)
+			}
+			if invalid := tc.s == "NaN" && tc.x != "NaN"; invalid != res.InvalidOperation() {
+				t.Errorf("unexpected flags %s", res)
+			}
+			if _, err := c.ISqrt(d, nil, newDecimal(t, testCtx, tc.x)); err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.s {
+				t.Errorf("nil remainder: expected %s, got %s", tc.s, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "math/big"
+
+// interchangeFormat describes one of the IEEE 754-2008 decimal interchange
+// formats.
+type interchangeFormat struct {
+	// bits is the total width of the encoding.
+	bits uint
+	// expBits is the width of the biased exponent.
+	expBits uint
+	// precision is the number of coefficient digits.
+	precision uint32
+	// emax is the largest adjusted exponent.
+	emax int32
+}
+
+var (
+	decimal32Format  = interchangeFormat{bits: 32, expBits: 8, precision: 7, emax: 96}
+	decimal64Format  = interchangeFormat{bits: 64, expBits: 10, precision: 16, emax: 384}
+	decimal128Format = interchangeFormat{bits: 128, expBits: 14, precision: 34, emax: 6144}
+)
+
+// qmin returns the smallest exponent of a coefficient, which is also the
+// exponent bias.
+func (f interchangeFormat) qmin() int32 {
+	return 2 - f.emax - int32(f.precision)
+}
+
+// qmax returns the largest exponent of a coefficient.
+func (f interchangeFormat) qmax() int32 {
+	return f.emax - int32(f.precision) + 1
+}
+
+// coeffBits returns the width of the coefficient field when the
+// combination field does not start with 11.
+func (f interchangeFormat) coeffBits() uint {
+	return f.bits - 1 - f.expBits
+}
+
+// fit sets d to x rounded to the precision and exponent range of f. The
+// rounding mode is taken from c. Exponents above qmax are clamped by padding
+// the coefficient with zeros.
+func (f interchangeFormat) fit(c *Context, d, x *Decimal) Condition {
+	if x.Form != Finite {
+		d.Set(x)
+		return 0
+	}
+	nc := *c
+	nc.Precision = f.precision
+	nc.MaxExponent = f.emax
+	nc.MinExponent = 1 - f.emax
+	res := nc.round(d, x)
+	if d.Form == Finite && d.Exponent > f.qmax() {
+		if !d.IsZero() {
+			y := new(big.Int)
+			d.Coeff.Mul(&d.Coeff, tableExp10(int64(d.Exponent-f.qmax()), y))
+		}
+		d.Exponent = f.qmax()
+		res |= Clamped
+	}
+	return res
+}
+
+// mask returns 2**n - 1.
+func mask(n uint) *big.Int {
+	m := new(big.Int).Lsh(bigOne, n)
+	return m.Sub(m, bigOne)
+}
+
+// encodeSpecial returns the encoding of the infinite or NaN x, or nil if x
+// is finite.
+func (f interchangeFormat) encodeSpecial(x *Decimal) *big.Int {
+	var v *big.Int
+	switch x.Form {
+	case Infinite:
+		v = big.NewInt(0x1e)
+		v.Lsh(v, f.bits-6)
+	case NaN:
+		v = big.NewInt(0x1f)
+		v.Lsh(v, f.bits-6)
+	case NaNSignaling:
+		v = big.NewInt(0x3f)
+		v.Lsh(v, f.bits-7)
+	default:
+		return nil
+	}
+	if x.Negative {
+		v.SetBit(v, int(f.bits-1), 1)
+	}
+	return v
+}
+
+// decodeSpecial sets d to the infinite or NaN value encoded in v. It returns
+// false if v encodes a finite number.
+func (f interchangeFormat) decodeSpecial(d *Decimal, v *big.Int) bool {
+	switch new(big.Int).Rsh(v, f.bits-6).Uint64() & 0x1f {
+	case 0x1e:
+		d.Form = Infinite
+	case 0x1f:
+		d.Form = NaN
+		if v.Bit(int(f.bits-7)) == 1 {
+			d.Form = NaNSignaling
+		}
+	default:
+		return false
+	}
+	d.Negative = v.Bit(int(f.bits-1)) == 1
+	d.Exponent = 0
+	d.Coeff.SetInt64(0)
+	return true
+}
+
+// encodeBID returns the binary integer decimal encoding of x rounded to f.
+func (f interchangeFormat) encodeBID(c *Context, x *Decimal) (*big.Int, Condition, error) {
+	d := new(Decimal)
+	res := f.fit(c, d, x)
+	if v := f.encodeSpecial(d); v != nil {
+		_, err := c.goError(res)
+		return v, res, err
+	}
+	m := f.coeffBits()
+	v := big.NewInt(int64(d.Exponent - f.qmin()))
+	if d.Coeff.BitLen() <= int(m) {
+		v.Lsh(v, m)
+		v.Or(v, &d.Coeff)
+	} else {
+		// The coefficient needs more than m bits. Its top bits are then
+		// implicitly 100, which is indicated by a combination field starting
+		// with 11.
+		v.Or(v, new(big.Int).Lsh(big.NewInt(3), f.expBits))
+		v.Lsh(v, m-2)
+		v.Or(v, new(big.Int).And(&d.Coeff, mask(m-2)))
+	}
+	if d.Negative {
+		v.SetBit(v, int(f.bits-1), 1)
+	}
+	_, err := c.goError(res)
+	return v, res, err
+}
+
+// decodeBID sets d to the value of the binary integer decimal encoding v.
+func (f interchangeFormat) decodeBID(d *Decimal, v *big.Int) *Decimal {
+	if f.decodeSpecial(d, v) {
+		return d
+	}
+	m := f.coeffBits()
+	e := new(big.Int)
+	if new(big.Int).Rsh(v, f.bits-3).Uint64()&3 != 3 {
+		e.Rsh(v, m)
+		d.Coeff.And(v, mask(m))
+	} else {
+		e.Rsh(v, m-2)
+		d.Coeff.And(v, mask(m-2))
+		d.Coeff.SetBit(&d.Coeff, int(m), 1)
+	}
+	e.And(e, mask(f.expBits))
+	// Coefficients larger than the precision allows are non-canonical and
+	// are interpreted as zero.
+	if NumDigits(&d.Coeff) > int64(f.precision) {
+		d.Coeff.SetInt64(0)
+	}
+	d.Form = Finite
+	d.Negative = v.Bit(int(f.bits-1)) == 1
+	d.Exponent = int32(e.Int64()) + f.qmin()
+	return d
+}
+
+// EncodeBID64 returns x encoded as an IEEE 754-2008 decimal64 in the binary
+// integer decimal (BID) format, in big-endian byte order. x is rounded to 16
+// digits and the decimal64 exponent range using the rounding mode of c;
+// the precision and exponent limits of c are not used. The returned
+// Condition reports any rounding, overflow, underflow or clamping, and c's
+// traps are applied to it.
+func (c *Context) EncodeBID64(x *Decimal) ([8]byte, Condition, error) {
+	var b [8]byte
+	v, res, err := decimal64Format.encodeBID(c, x)
+	v.FillBytes(b[:])
+	return b, res, err
+}
+
+// DecodeBID64 returns the value of the big-endian IEEE 754-2008 decimal64
+// binary integer decimal (BID) encoding b. Decoding is always exact.
+func DecodeBID64(b [8]byte) *Decimal {
+	return decimal64Format.decodeBID(new(Decimal), new(big.Int).SetBytes(b[:]))
+}
+
+// EncodeBID128 is like EncodeBID64 but encodes a decimal128, which has 34
+// digits of precision.
+func (c *Context) EncodeBID128(x *Decimal) ([16]byte, Condition, error) {
+	var b [16]byte
+	v, res, err := decimal128Format.encodeBID(c, x)
+	v.FillBytes(b[:])
+	return b, res, err
+}
+
+// DecodeBID128 is like DecodeBID64 but decodes a decimal128.
+func DecodeBID128(b [16]byte) *Decimal {
+	return decimal128Format.decodeBID(new(Decimal), new(big.Int).SetBytes(b[:]))
+}
+
+// decletBits returns the width of the declets of the densely packed decimal
+// coefficient continuation field.
+func (f interchangeFormat) decletBits() uint {
+	return f.bits - f.expBits - 4
+}
+
+// encodeDeclet returns the densely packed decimal encoding of n, which must
+// be less than 1000.
+func encodeDeclet(n uint) uint {
+	d1, d2, d3 := n/100, n/10%10, n%10
+	// Each digit is either small (0-7, three significant bits) or large (8
+	// or 9, one significant bit). The placement of the bits depends on
+	// which digits are large.
+	switch b := d1>>3<<2 | d2>>3<<1 | d3>>3; b {
+	case 0:
+		return d1<<7 | d2<<4 | d3
+	case 1:
+		return d1<<7 | d2<<4 | 0x8 | d3&1
+	case 2:
+		return d1<<7 | d3&6<<4 | d2&1<<4 | 0xa | d3&1
+	case 4:
+		return d3&6<<7 | d1&1<<7 | d2<<4 | 0xc | d3&1
+	case 6:
+		return d3&6<<7 | d1&1<<7 | d2&1<<4 | 0xe | d3&1
+	case 5:
+		return d2&6<<7 | d1&1<<7 | 0x20 | d2&1<<4 | 0xe | d3&1
+	case 3:
+		return d1<<7 | 0x40 | d2&1<<4 | 0xe | d3&1
+	default:
+		return d1&1<<7 | 0x60 | d2&1<<4 | 0xe | d3&1
+	}
+}
+
+// decodeDeclet returns the value of the densely packed decimal declet b.
+// Non-canonical declets are decoded as specified by IEEE 754-2008.
+func decodeDeclet(b uint) uint {
+	pqr, stu, wxy := b>>7&7, b>>4&7, b&7
+	var d1, d2, d3 uint
+	if b&0x8 == 0 {
+		d1, d2, d3 = pqr, stu, wxy
+	} else {
+		r, u, y := pqr&1, stu&1, wxy&1
+		switch wxy >> 1 {
+		case 0:
+			d1, d2, d3 = pqr, stu, 8|y
+		case 1:
+			d1, d2, d3 = pqr, 8|u, stu&6|y
+		case 2:
+			d1, d2, d3 = 8|r, stu, pqr&6|y
+		default:
+			switch stu >> 1 {
+			case 0:
+				d1, d2, d3 = 8|r, 8|u, pqr&6|y
+			case 1:
+				d1, d2, d3 = 8|r, pqr&6|u, 8|y
+			case 2:
+				d1, d2, d3 = pqr, 8|u, 8|y
+			default:
+				d1, d2, d3 = 8|r, 8|u, 8|y
+			}
+		}
+	}
+	return d1*100 + d2*10 + d3
+}
+
+// encodeDPD returns the densely packed decimal encoding of x rounded to f.
+func (f interchangeFormat) encodeDPD(c *Context, x *Decimal) (*big.Int, Condition, error) {
+	d := new(Decimal)
+	res := f.fit(c, d, x)
+	if v := f.encodeSpecial(d); v != nil {
+		_, err := c.goError(res)
+		return v, res, err
+	}
+	t := f.decletBits()
+	v := new(big.Int)
+	q := new(big.Int).Set(&d.Coeff)
+	r := new(big.Int)
+	thousand := big.NewInt(1000)
+	for i := uint(0); i < t; i += 10 {
+		q.QuoRem(q, thousand, r)
+		v.Or(v, new(big.Int).Lsh(big.NewInt(int64(encodeDeclet(uint(r.Uint64())))), i))
+	}
+	// The combination field holds the two most significant bits of the
+	// exponent and the leading digit.
+	e := uint64(d.Exponent - f.qmin())
+	w := f.expBits - 2
+	high, lead := e>>w, q.Uint64()
+	var comb uint64
+	if lead < 8 {
+		comb = high<<3 | lead
+	} else {
+		comb = 0x18 | high<<1 | lead&1
+	}
+	v.Or(v, new(big.Int).Lsh(new(big.Int).SetUint64(comb<<w|e&(1<<w-1)), t))
+	if d.Negative {
+		v.SetBit(v, int(f.bits-1), 1)
+	}
+	_, err := c.goError(res)
+	return v, res, err
+}
+
+// decodeDPD sets d to the value of the densely packed decimal encoding v.
+func (f interchangeFormat) decodeDPD(d *Decimal, v *big.Int) *Decimal {
+	if f.decodeSpecial(d, v) {
+		return d
+	}
+	t := f.decletBits()
+	w := f.expBits - 2
+	top := new(big.Int).Rsh(v, t).Uint64()
+	comb := top >> w & 0x1f
+	var high, lead uint64
+	if comb>>3 != 3 {
+		high, lead = comb>>3, comb&7
+	} else {
+		high, lead = comb>>1&3, 8|comb&1
+	}
+	d.Coeff.SetUint64(lead)
+	thousand := big.NewInt(1000)
+	declet := new(big.Int)
+	for i := int(t) - 10; i >= 0; i -= 10 {
+		declet.Rsh(v, uint(i))
+		d.Coeff.Mul(&d.Coeff, thousand)
+		d.Coeff.Add(&d.Coeff, big.NewInt(int64(decodeDeclet(uint(declet.Uint64()&0x3ff)))))
+	}
+	d.Form = Finite
+	d.Negative = v.Bit(int(f.bits-1)) == 1
+	d.Exponent = int32(high<<w|top&(1<<w-1)) + f.qmin()
+	return d
+}
+
+// EncodeDPD32 returns x encoded as an IEEE 754-2008 decimal32 in the densely
+// packed decimal (DPD) format, in big-endian byte order. x is rounded to 7
+// digits and the decimal32 exponent range using the rounding mode of c;
+// the precision and exponent limits of c are not used. The returned
+// Condition reports any rounding, overflow, underflow or clamping, and c's
+// traps are applied to it.
+func (c *Context) EncodeDPD32(x *Decimal) ([4]byte, Condition, error) {
+	var b [4]byte
+	v, res, err := decimal32Format.encodeDPD(c, x)
+	v.FillBytes(b[:])
+	return b, res, err
+}
+
+// DecodeDPD32 returns the value of the big-endian IEEE 754-2008 decimal32
+// densely packed decimal (DPD) encoding b. Decoding is always exact.
+func DecodeDPD32(b [4]byte) *Decimal {
+	return decimal32Format.decodeDPD(new(Decimal), new(big.Int).SetBytes(b[:]))
+}
+
+// EncodeDPD64 is like EncodeDPD32 but encodes a decimal64, which has 16
+// digits of precision.
+func (c *Context) EncodeDPD64(x *Decimal) ([8]byte, Condition, error) {
+	var b [8]byte
+	v, res, err := decimal64Format.encodeDPD(c, x)
+	v.FillBytes(b[:])
+	return b, res, err
+}
+
+// DecodeDPD64 is like DecodeDPD32 but decodes a decimal64.
+func DecodeDPD64(b [8]byte) *Decimal {
+	return decimal64Format.decodeDPD(new(Decimal), new(big.Int).SetBytes(b[:]))
+}
+
+// EncodeDPD128 is like EncodeDPD32 but encodes a decimal128, which has 34
+// digits of precision.
+func (c *Context) EncodeDPD128(x *Decimal) ([16]byte, Condition, error) {
+	var b [16]byte
+	v, res, err := decimal128Format.encodeDPD(c, x)
+	v.FillBytes(b[:])
+	return b, res, err
+}
+
+// DecodeDPD128 is like DecodeDPD32 but decodes a decimal128.
+func DecodeDPD128(b [16]byte) *Decimal {
+	return decimal128Format.decodeDPD(new(Decimal), new(big.Int).SetBytes(b[:]))
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBID(t *testing.T) {
+	tests := []struct {
+		x       string
+		bid64   string
+		flags64 Condition
+		d64     string
+		bid128  string
+	}{
+		{x: "1", bid64: "31c0000000000001", d64: "1", bid128: "30400000000000000000000000000001"},
+		{x: "-0", bid64: "b1c0000000000000", d64: "-0", bid128: "b0400000000000000000000000000000"},
+		{x: "9999999999999999E369", bid64: "77fb86f26fc0ffff", d64: "9.999999999999999E+384", bid128: "3322000000000000002386f26fc0ffff"},
+		{x: "12345678901234567", bid64: "31e462d53c8abac1", flags64: Inexact | Rounded, d64: "1.234567890123457E+16", bid128: "3040000000000000002bdc545d6b4b87"},
+		{x: "1E384", bid64: "5fe38d7ea4c68000", flags64: Clamped, d64: "1.000000000000000E+384", bid128: "33400000000000000000000000000001"},
+		{x: "0E+500", bid64: "5fe0000000000000", flags64: Clamped, d64: "0E+369", bid128: "34280000000000000000000000000000"},
+		{x: "1E385", bid64: "7800000000000000", flags64: Overflow | Inexact | Rounded, d64: "Infinity", bid128: "33420000000000000000000000000001"},
+		{x: "1E-398", bid64: "0000000000000001", flags64: Subnormal, d64: "1E-398", bid128: "2d240000000000000000000000000001"},
+		{x: "1.5E-398", bid64: "0000000000000002", flags64: Underflow | Subnormal | Inexact | Rounded, d64: "2E-398", bid128: "2d22000000000000000000000000000f"},
+		{x: "1E-500", bid64: "0000000000000000", flags64: Underflow | Subnormal | Inexact | Rounded | Clamped, d64: "0E-398", bid128: "2c580000000000000000000000000001"},
+		{x: "-Infinity", bid64: "f800000000000000", d64: "-Infinity", bid128: "f8000000000000000000000000000000"},
+		{x: "NaN", bid64: "7c00000000000000", d64: "NaN", bid128: "7c000000000000000000000000000000"},
+		{x: "-sNaN", bid64: "fe00000000000000", d64: "-sNaN", bid128: "fe000000000000000000000000000000"},
+	}
+	c := BaseContext.WithPrecision(0)
+	c.Rounding = RoundHalfEven
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			b64, res, err := c.EncodeBID64(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b64[:]); s != tc.bid64 {
+				t.Errorf("decimal64: expected %s, got %s", tc.bid64, s)
+			}
+			if res != tc.flags64 {
+				t.Errorf("decimal64: expected flags %s, got %s", tc.flags64, res)
+			}
+			if s := DecodeBID64(b64).String(); s != tc.d64 {
+				t.Errorf("decimal64: expected decoded %s, got %s", tc.d64, s)
+			}
+
+			b128, res, err := c.EncodeBID128(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b128[:]); s != tc.bid128 {
+				t.Errorf("decimal128: expected %s, got %s", tc.bid128, s)
+			}
+			if res != 0 {
+				t.Errorf("decimal128: expected no flags, got %s", res)
+			}
+			if d := DecodeBID128(b128); d.CmpTotal(x) != 0 {
+				t.Errorf("decimal128: expected decoded %s, got %s", x, d)
+			}
+		})
+	}
+}
+
+func TestBIDNonCanonical(t *testing.T) {
+	// A coefficient of 2**53 + 2**51 - 1 is larger than 16 digits and must be
+	// decoded as zero.
+	b := [8]byte{0x6f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if d := DecodeBID64(b); !d.IsZero() {
+		t.Fatalf("expected zero, got %s", d)
+	}
+}
+
+func TestBIDTraps(t *testing.T) {
+	c := BaseContext.WithPrecision(0)
+	c.Traps = Overflow
+	if _, _, err := c.EncodeBID64(New(1, 385)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDPD(t *testing.T) {
+	tests := []struct {
+		x       string
+		dpd32   string
+		flags32 Condition
+		d32     string
+		dpd64   string
+		dpd128  string
+	}{
+		{x: "1", dpd32: "22500001", d32: "1", dpd64: "2238000000000001", dpd128: "22080000000000000000000000000001"},
+		{x: "-7.50", dpd32: "a23003d0", d32: "-7.50", dpd64: "a2300000000003d0", dpd128: "a20780000000000000000000000003d0"},
+		{x: "1234567", dpd32: "2654d2e7", d32: "1234567", dpd64: "223800000014d2e7", dpd128: "2208000000000000000000000014d2e7"},
+		{x: "12345678", dpd32: "2664d2e8", flags32: Inexact | Rounded, d32: "1.234568E+7", dpd64: "2238000001271778", dpd128: "22080000000000000000000001271778"},
+		{x: "9999999E90", dpd32: "77f3fcff", d32: "9.999999E+96", dpd64: "23a000000093fcff", dpd128: "221e800000000000000000000093fcff"},
+		{x: "1E97", dpd32: "78000000", flags32: Overflow | Inexact | Rounded, d32: "Infinity", dpd64: "23bc000000000001", dpd128: "22204000000000000000000000000001"},
+		{x: "1E-101", dpd32: "00000001", flags32: Subnormal, d32: "1E-101", dpd64: "20a4000000000001", dpd128: "21eec000000000000000000000000001"},
+		{x: "-Infinity", dpd32: "f8000000", d32: "-Infinity", dpd64: "f800000000000000", dpd128: "f8000000000000000000000000000000"},
+		{x: "NaN", dpd32: "7c000000", d32: "NaN", dpd64: "7c00000000000000", dpd128: "7c000000000000000000000000000000"},
+		{x: "-sNaN", dpd32: "fe000000", d32: "-sNaN", dpd64: "fe00000000000000", dpd128: "fe000000000000000000000000000000"},
+	}
+	c := BaseContext.WithPrecision(0)
+	c.Rounding = RoundHalfEven
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			b32, res, err := c.EncodeDPD32(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b32[:]); s != tc.dpd32 {
+				t.Errorf("decimal32: expected %s, got %s", tc.dpd32, s)
+			}
+			if res != tc.flags32 {
+				t.Errorf("decimal32: expected flags %s, got %s", tc.flags32, res)
+			}
+			if s := DecodeDPD32(b32).String(); s != tc.d32 {
+				t.Errorf("decimal32: expected decoded %s, got %s", tc.d32, s)
+			}
+
+			b64, _, err := c.EncodeDPD64(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b64[:]); s != tc.dpd64 {
+				t.Errorf("decimal64: expected %s, got %s", tc.dpd64, s)
+			}
+			if d := DecodeDPD64(b64); d.CmpTotal(x) != 0 {
+				t.Errorf("decimal64: expected decoded %s, got %s", x, d)
+			}
+
+			b128, _, err := c.EncodeDPD128(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := hex.EncodeToString(b128[:]); s != tc.dpd128 {
+				t.Errorf("decimal128: expected %s, got %s", tc.dpd128, s)
+			}
+			if d := DecodeDPD128(b128); d.CmpTotal(x) != 0 {
+				t.Errorf("decimal128: expected decoded %s, got %s", x, d)
+			}
+		})
+	}
+}
+
+func TestDeclet(t *testing.T) {
+	for i := uint(0); i < 1000; i++ {
+		if n := decodeDeclet(encodeDeclet(i)); n != i {
+			t.Fatalf("%d: round trip gave %d", i, n)
+		}
+	}
+	// Non-canonical declets ignore the two top bits.
+	if n := decodeDeclet(0x3ff); n != 999 {
+		t.Fatalf("expected 999, got %d", n)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+// Interval is the closed interval [Lo, Hi] of the numbers between Lo and Hi.
+// The interval operations of Context round outward, Lo toward -Infinity and
+// Hi toward +Infinity, so that the exact result for any numbers within the
+// operands lies within the result. An Interval with a NaN endpoint or with
+// Lo > Hi is invalid, and operations on it are an InvalidOperation.
+type Interval struct {
+	Lo, Hi Decimal
+}
+
+// NewInterval creates a new Interval [lo, hi].
+func NewInterval(lo, hi *Decimal) *Interval {
+	return new(Interval).Set(lo, hi)
+}
+
+// Set sets i to [lo, hi] and returns i.
+func (i *Interval) Set(lo, hi *Decimal) *Interval {
+	i.Lo.Set(lo)
+	i.Hi.Set(hi)
+	return i
+}
+
+// Contains returns true if x lies within i.
+func (i *Interval) Contains(x *Decimal) bool {
+	return i.valid() && x.Form != NaN && i.Lo.Cmp(x) <= 0 && x.Cmp(&i.Hi) <= 0
+}
+
+// String returns i as "[Lo, Hi]".
+func (i *Interval) String() string {
+	return "[" + i.Lo.String() + ", " + i.Hi.String() + "]"
+}
+
+func (i *Interval) valid() bool {
+	return i.Lo.Form != NaN && i.Hi.Form != NaN && i.Lo.Cmp(&i.Hi) <= 0
+}
+
+// AddInterval sets d to the sum x+y.
+func (c *Context) AddInterval(d, x, y *Interval) (Condition, error) {
+	if !x.valid() || !y.valid() {
+		return c.invalidInterval(d)
+	}
+	return c.endpoints(d, &x.Lo, &y.Lo, &x.Hi, &y.Hi, (*Context).Add)
+}
+
+// SubInterval sets d to the difference x-y.
+func (c *Context) SubInterval(d, x, y *Interval) (Condition, error) {
+	if !x.valid() || !y.valid() {
+		return c.invalidInterval(d)
+	}
+	return c.endpoints(d, &x.Lo, &y.Hi, &x.Hi, &y.Lo, (*Context).Sub)
+}
+
+// MulInterval sets d to the product x*y. An endpoint of 0 times an infinite
+// endpoint is treated as 0.
+func (c *Context) MulInterval(d, x, y *Interval) (Condition, error) {
+	if !x.valid() || !y.valid() {
+		return c.invalidInterval(d)
+	}
+	nc := c.intervalContext()
+	return c.hull(d, x, y, func(lo, hi, a, b *Decimal) (Condition, error) {
+		// The endpoints are limits, so 0 * Infinity is 0.
+		if a.IsZero() || b.IsZero() {
+			lo.SetInt64(0)
+			hi.SetInt64(0)
+			return 0, nil
+		}
+		return nc.Bounds(lo, hi, func(c *Context, d *Decimal) (Condition, error) {
+			return c.Mul(d, a, b)
+		})
+	})
+}
+
+// QuoInterval sets d to the quotient x/y. If y contains zero, d is
+// [-Infinity, Infinity] with DivisionByZero.
+func (c *Context) QuoInterval(d, x, y *Interval) (Condition, error) {
+	if !x.valid() || !y.valid() {
+		return c.invalidInterval(d)
+	}
+	if y.Lo.Sign() <= 0 && y.Hi.Sign() >= 0 {
+		d.Lo.Set(decimalInfinity)
+		d.Lo.Negative = true
+		d.Hi.Set(decimalInfinity)
+		return c.goError(DivisionByZero)
+	}
+	nc := c.intervalContext()
+	return c.hull(d, x, y, func(lo, hi, a, b *Decimal) (Condition, error) {
+		// Infinity / Infinity is undefined at the corner itself, but nearby
+		// quotients take every value between 0 and Infinity.
+		if a.Form == Infinite && b.Form == Infinite {
+			neg := a.Negative != b.Negative
+			zero, inf := lo, hi
+			if neg {
+				zero, inf = hi, lo
+			}
+			zero.SetInt64(0)
+			zero.Negative = neg
+			inf.Set(decimalInfinity)
+			inf.Negative = neg
+			return 0, nil
+		}
+		return nc.Bounds(lo, hi, func(c *Context, d *Decimal) (Condition, error) {
+			return c.Quo(d, a, b)
+		})
+	})
+}
+
+// SqrtInterval sets d to the square root of x. The negative part of x is
+// ignored; if all of x is negative, d is NaN with InvalidOperation.
+func (c *Context) SqrtInterval(d, x *Interval) (Condition, error) {
+	if !x.valid() || x.Hi.Sign() < 0 {
+		return c.invalidInterval(d)
+	}
+	nc := c.intervalContext()
+	lo, hi := new(Decimal), new(Decimal)
+	res, err := nc.sqrtBound(lo, &x.Lo, false)
+	if err != nil {
+		return 0, err
+	}
+	hres, err := nc.sqrtBound(hi, &x.Hi, true)
+	if err != nil {
+		return 0, err
+	}
+	d.Lo.Set(lo)
+	d.Hi.Set(hi)
+	return c.goError(res | hres)
+}
+
+// intervalContext returns the context used to compute the endpoints of an
+// interval operation. Its conditions are collected and returned by the
+// operation itself.
+func (c *Context) intervalContext() *Context {
+	nc := c.workingContext(c.Precision)
+	nc.Traps = 0
+	return nc
+}
+
+func (c *Context) invalidInterval(d *Interval) (Condition, error) {
+	d.Lo.Set(decimalNaN)
+	d.Hi.Set(decimalNaN)
+	return c.goError(InvalidOperation)
+}
+
+// endpoints sets d to [op(xlo, ylo), op(xhi, yhi)], with the lower endpoint
+// rounded toward -Infinity and the upper toward +Infinity.
+func (c *Context) endpoints(
+	d *Interval, xlo, ylo, xhi, yhi *Decimal, op func(c *Context, d, x, y *Decimal) (Condition, error),
+) (Condition, error) {
+	nc := c.intervalContext()
+	lo, hi, discard := new(Decimal), new(Decimal), new(Decimal)
+	res, err := nc.Bounds(lo, discard, func(c *Context, d *Decimal) (Condition, error) {
+		return op(c, d, xlo, ylo)
+	})
+	if err != nil {
+		return 0, err
+	}
+	hres, err := nc.Bounds(discard, hi, func(c *Context, d *Decimal) (Condition, error) {
+		return op(c, d, xhi, yhi)
+	})
+	if err != nil {
+		return 0, err
+	}
+	d.Lo.Set(lo)
+	d.Hi.Set(hi)
+	return c.goError(res | hres)
+}
+
+// hull sets d to the smallest interval that contains the bounds computed by
+// f for each pair of endpoints of x and y.
+func (c *Context) hull(d, x, y *Interval, f func(lo, hi, a, b *Decimal) (Condition, error)) (Condition, error) {
+	lo, hi := new(Decimal), new(Decimal)
+	plo, phi := new(Decimal), new(Decimal)
+	var res Condition
+	first := true
+	for _, a := range []*Decimal{&x.Lo, &x.Hi} {
+		for _, b := range []*Decimal{&y.Lo, &y.Hi} {
+			r, err := f(plo, phi, a, b)
+			if err != nil {
+				return 0, err
+			}
+			res |= r
+			if first || plo.Cmp(lo) < 0 {
+				lo.Set(plo)
+			}
+			if first || phi.Cmp(hi) > 0 {
+				hi.Set(phi)
+			}
+			first = false
+		}
+	}
+	d.Lo.Set(lo)
+	d.Hi.Set(hi)
+	return c.goError(res)
+}
+
+// sqrtBound sets d to the square root of x rounded toward +Infinity if up is
+// true and toward -Infinity otherwise. Negative x is treated as 0.
+func (c *Context) sqrtBound(d, x *Decimal, up bool) (Condition, error) {
+	switch {
+	case x.Sign() <= 0:
+		d.SetInt64(0)
+		return 0, nil
+	case x.Form == Infinite:
+		d.Set(x)
+		return 0, nil
+	}
+	z := new(Decimal)
+	if _, err := c.Sqrt(z, x); err != nil {
+		return 0, err
+	}
+	// Sqrt rounds to nearest, so step toward the bound until z is on the
+	// correct side of the exact root, then back while its neighbor also is.
+	ed := MakeErrDecimal(c.baseContext(0))
+	sq := new(Decimal)
+	bound := func(z *Decimal) bool {
+		ed.Mul(sq, z, z)
+		if up {
+			return sq.Cmp(x) >= 0
+		}
+		return sq.Cmp(x) <= 0
+	}
+	for !bound(z) {
+		if err := ed.Err(); err != nil {
+			return 0, err
+		}
+		if _, err := c.neighbor(z, z, up); err != nil {
+			return 0, err
+		}
+	}
+	for n := new(Decimal); ; z.Set(n) {
+		if _, err := c.neighbor(n, z, !up); err != nil {
+			return 0, err
+		}
+		if !bound(n) {
+			break
+		}
+	}
+	ed.Mul(sq, z, z)
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	var res Condition
+	if sq.Cmp(x) != 0 {
+		res = Inexact | Rounded
+	}
+	d.Set(z)
+	return res, nil
+}
+
+// neighbor sets d to the number adjacent to the positive finite x at c's
+// precision, above x if up is true and below it otherwise.
+func (c *Context) neighbor(d, x *Decimal, up bool) (Condition, error) {
+	nc := c.workingContext(c.Precision)
+	nc.Traps = 0
+	eps := New(1, int32(adjustedExponent(x)-int64(c.Precision)-1))
+	if up {
+		nc.Rounding = RoundCeiling
+		return nc.Add(d, x, eps)
+	}
+	nc.Rounding = RoundFloor
+	return nc.Sub(d, x, eps)
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		op        string
+		x, y      [2]string
+		prec      uint32
+		r         string
+		inexact   bool
+		divByZero bool
+		invalidOp bool
+	}{
+		{op: "add", x: [2]string{"1", "2"}, y: [2]string{"3", "4"}, r: "[4, 6]"},
+		{op: "add", x: [2]string{"1", "1"}, y: [2]string{"0.0001", "0.0001"}, prec: 3, r: "[1.00, 1.01]", inexact: true},
+		{op: "add", x: [2]string{"-Infinity", "0"}, y: [2]string{"1", "1"}, r: "[-Infinity, 1]"},
+		{op: "sub", x: [2]string{"1", "2"}, y: [2]string{"3", "5"}, r: "[-4, -1]"},
+		{op: "sub", x: [2]string{"1", "1"}, y: [2]string{"0.0001", "0.0001"}, prec: 3, r: "[0.999, 1.00]", inexact: true},
+		{op: "mul", x: [2]string{"-1", "2"}, y: [2]string{"3", "4"}, r: "[-4, 8]"},
+		{op: "mul", x: [2]string{"-2", "-1"}, y: [2]string{"-4", "3"}, r: "[-6, 8]"},
+		{op: "mul", x: [2]string{"1.11", "1.11"}, y: [2]string{"1.11", "1.11"}, prec: 3, r: "[1.23, 1.24]", inexact: true},
+		{op: "mul", x: [2]string{"0", "1"}, y: [2]string{"1", "Infinity"}, r: "[0, Infinity]"},
+		{op: "quo", x: [2]string{"1", "1"}, y: [2]string{"3", "3"}, prec: 3, r: "[0.333, 0.334]", inexact: true},
+		{op: "quo", x: [2]string{"-1", "-1"}, y: [2]string{"3", "3"}, prec: 3, r: "[-0.334, -0.333]", inexact: true},
+		{op: "quo", x: [2]string{"1", "2"}, y: [2]string{"-4", "-1"}, r: "[-2, -0.25]"},
+		{op: "quo", x: [2]string{"1", "Infinity"}, y: [2]string{"1", "Infinity"}, r: "[0E-100009, Infinity]"},
+		{op: "quo", x: [2]string{"1", "Infinity"}, y: [2]string{"-Infinity", "-1"}, r: "[-Infinity, -0E-100009]"},
+		{op: "quo", x: [2]string{"1", "2"}, y: [2]string{"0", "1"}, r: "[-Infinity, Infinity]", divByZero: true},
+		{op: "quo", x: [2]string{"1", "2"}, y: [2]string{"-1", "1"}, r: "[-Infinity, Infinity]", divByZero: true},
+		{op: "sqrt", x: [2]string{"2", "2"}, prec: 5, r: "[1.4142, 1.4143]", inexact: true},
+		{op: "sqrt", x: [2]string{"4", "9"}, r: "[2, 3]"},
+		{op: "sqrt", x: [2]string{"-1", "4"}, r: "[0, 2]"},
+		{op: "sqrt", x: [2]string{"0.99", "Infinity"}, prec: 3, r: "[0.994, Infinity]", inexact: true},
+		{op: "sqrt", x: [2]string{"-2", "-1"}, r: "[NaN, NaN]", invalidOp: true},
+		{op: "add", x: [2]string{"2", "1"}, y: [2]string{"0", "0"}, r: "[NaN, NaN]", invalidOp: true},
+		{op: "mul", x: [2]string{"NaN", "1"}, y: [2]string{"0", "0"}, r: "[NaN, NaN]", invalidOp: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.op+tc.r, func(t *testing.T) {
+			prec := tc.prec
+			if prec == 0 {
+				prec = 10
+			}
+			c := BaseContext.WithPrecision(prec)
+			c.Traps = 0
+			x := NewInterval(newDecimal(t, testCtx, tc.x[0]), newDecimal(t, testCtx, tc.x[1]))
+			d := new(Interval)
+			var res Condition
+			var err error
+			if tc.op == "sqrt" {
+				res, err = c.SqrtInterval(d, x)
+			} else {
+				y := NewInterval(newDecimal(t, testCtx, tc.y[0]), newDecimal(t, testCtx, tc.y[1]))
+				f := map[string]func(d, x, y *Interval) (Condition, error){
+					"add": c.AddInterval,
+					"sub": c.SubInterval,
+					"mul": c.MulInterval,
+					"quo": c.QuoInterval,
+				}[tc.op]
+				res, err = f(d, x, y)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+			if res.Inexact() != tc.inexact || res.DivisionByZero() != tc.divByZero || res.InvalidOperation() != tc.invalidOp {
+				t.Errorf("unexpected conditions %s", res)
+			}
+		})
+	}
+}
+
+// TestIntervalContains checks that the exact results for random points
+// within random intervals lie within the computed intervals.
+func TestIntervalContains(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() *Decimal {
+		return New(rng.Int63n(2000000)-1000000, int32(rng.Intn(7)-3))
+	}
+	c := BaseContext.WithPrecision(4)
+	exact := BaseContext.WithPrecision(50)
+	for i := 0; i < 500; i++ {
+		x := NewInterval(random(), random())
+		if x.Lo.Cmp(&x.Hi) > 0 {
+			x.Set(&x.Hi, &x.Lo)
+		}
+		y := NewInterval(random(), random())
+		if y.Lo.Cmp(&y.Hi) > 0 {
+			y.Set(&y.Hi, &y.Lo)
+		}
+		// Pick a point in each interval.
+		px, py := new(Decimal), new(Decimal)
+		exact.Add(px, &x.Lo, &x.Hi)
+		exact.Quo(px, px, decimalTwo)
+		exact.Add(py, &y.Lo, &y.Hi)
+		exact.Quo(py, py, decimalTwo)
+		for _, op := range []struct {
+			name  string
+			f     func(d, x, y *Interval) (Condition, error)
+			exact func(d, x, y *Decimal) (Condition, error)
+		}{
+			{"add", c.AddInterval, exact.Add},
+			{"sub", c.SubInterval, exact.Sub},
+			{"mul", c.MulInterval, exact.Mul},
+			{"quo", c.QuoInterval, exact.Quo},
+		} {
+			if op.name == "quo" && !(y.Lo.Sign() > 0 || y.Hi.Sign() < 0) {
+				continue
+			}
+			d := new(Interval)
+			if _, err := op.f(d, x, y); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range [][2]*Decimal{{&x.Lo, &y.Lo}, {&x.Lo, &y.Hi}, {&x.Hi, &y.Lo}, {&x.Hi, &y.Hi}, {px, py}} {
+				v := new(Decimal)
+				if _, err := op.exact(v, p[0], p[1]); err != nil {
+					t.Fatal(err)
+				}
+				if !d.Contains(v) {
+					t.Fatalf("%s(%s, %s) = %s does not contain %s", op.name, x, y, d, v)
+				}
+			}
+		}
+		x.Lo.Abs(&x.Lo)
+		x.Hi.Abs(&x.Hi)
+		if x.Lo.Cmp(&x.Hi) > 0 {
+			x.Set(&x.Hi, &x.Lo)
+		}
+		d := new(Interval)
+		if _, err := c.SqrtInterval(d, x); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range []*Decimal{&x.Lo, &x.Hi} {
+			lo, hi := new(Decimal), new(Decimal)
+			exact.Mul(lo, &d.Lo, &d.Lo)
+			exact.Mul(hi, &d.Hi, &d.Hi)
+			if lo.Cmp(e) > 0 || hi.Cmp(e) < 0 {
+				t.Fatalf("sqrt(%s) = %s does not contain sqrt(%s)", x, d, e)
+			}
+		}
+	}
+}
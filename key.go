@@ -0,0 +1,130 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// The first byte of a key, in the order of the values they stand for.
+const (
+	keyNegInfinity = iota + 1
+	keyNegative
+	keyZero
+	keyPositive
+	keyInfinity
+	keyNaNSignaling
+	keyNaN
+)
+
+// Key returns a compact form of d to use as a map key in place of d, for
+// example in a hash join, without formatting d as a string. Numerically
+// equal decimals, such as 1.5, 1.50 and 15E-1, or 0 and -0, have the same
+// key, and the keys of other numbers differ. Comparing the keys of two
+// numbers as strings orders them as Cmp does. NaNs have the same key when
+// they have the same form, sign and payload; their keys sort after those
+// of all numbers.
+func (d *Decimal) Key() string {
+	var tmp [32]byte
+	return string(d.AppendKey(tmp[:0]))
+}
+
+// AppendKey appends the key of d, as returned by Key, to buf and returns
+// the extended buffer. It does not allocate if buf has enough capacity and
+// the coefficient of d fits in a uint64.
+//
+// The key of a nonzero finite number is its sign, its adjusted exponent as
+// 8 big-endian bytes and its significant digits without trailing zeros,
+// two to a byte. The bytes after the sign are inverted for negative
+// numbers, which are terminated by 0xff so that longer digit strings sort
+// first.
+func (d *Decimal) AppendKey(buf []byte) []byte {
+	var tag byte
+	switch d.Form {
+	case Finite:
+		if d.IsZero() {
+			return append(buf, keyZero)
+		}
+		tag = keyPositive
+		if d.Negative {
+			tag = keyNegative
+		}
+	case Infinite:
+		if d.Negative {
+			return append(buf, keyNegInfinity)
+		}
+		return append(buf, keyInfinity)
+	case NaNSignaling:
+		tag = keyNaNSignaling
+	default:
+		tag = keyNaN
+	}
+
+	var tmp [20]byte
+	var digits []byte
+	if d.Coeff.IsUint64() {
+		digits = strconv.AppendUint(tmp[:0], d.Coeff.Uint64(), 10)
+	} else {
+		p := getBytes()
+		defer putBytes(p)
+		*p = appendCoeff(*p, &d.Coeff)
+		digits = *p
+	}
+
+	buf = append(buf, tag)
+	if d.Form != Finite {
+		// The payload of a NaN is kept as is, so only the sign is added.
+		if d.Negative {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		return appendKeyDigits(buf, digits, 0)
+	}
+
+	var invert byte
+	if d.Negative {
+		invert = 0xff
+	}
+	adj := int64(d.Exponent) + int64(len(digits)) - 1
+	var exp [8]byte
+	binary.BigEndian.PutUint64(exp[:], uint64(adj)^(1<<63))
+	for _, b := range exp {
+		buf = append(buf, b^invert)
+	}
+	for digits[len(digits)-1] == '0' {
+		digits = digits[:len(digits)-1]
+	}
+	buf = appendKeyDigits(buf, digits, invert)
+	if d.Negative {
+		buf = append(buf, 0xff)
+	}
+	return buf
+}
+
+// appendKeyDigits appends the ASCII digits to buf packed two to a byte,
+// each as its value plus one so that a digit sorts after the zero nibble
+// padding an odd count, and XORs the bytes with invert.
+func appendKeyDigits(buf, digits []byte, invert byte) []byte {
+	for i := 0; i < len(digits); i += 2 {
+		b := (digits[i] - '0' + 1) << 4
+		if i+1 < len(digits) {
+			b |= digits[i+1] - '0' + 1
+		}
+		buf = append(buf, b^invert)
+	}
+	return buf
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	// Each group holds equal values; the groups are in increasing order.
+	groups := [][]string{
+		{"-Infinity"},
+		{"-1E+1000"},
+		{"-123456789012345678901234567890", "-1.23456789012345678901234567890E+29"},
+		{"-10", "-1E+1", "-10.000"},
+		{"-1.21"},
+		{"-1.2", "-1.20"},
+		{"-1"},
+		{"-0.5"},
+		{"-1E-1000"},
+		{"0", "-0", "0.000", "0E+10", "-0E-5"},
+		{"1E-1000"},
+		{"0.5", "5E-1"},
+		{"1", "1.0", "1.00000000000000000000000000000000"},
+		{"1.2", "12E-1"},
+		{"1.21"},
+		{"9.99999999999999999999"},
+		{"10", "1E+1"},
+		{"123456789012345678901234567890"},
+		{"1E+1000"},
+		{"Infinity"},
+	}
+	var keys []string
+	for _, g := range groups {
+		key := newDecimal(t, testCtx, g[0]).Key()
+		for _, s := range g[1:] {
+			if k := newDecimal(t, testCtx, s).Key(); k != key {
+				t.Errorf("%s: expected the key of %s %x, got %x", s, g[0], key, k)
+			}
+		}
+		keys = append(keys, key)
+	}
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1] >= keys[i] {
+			t.Errorf("expected key of %s < key of %s", groups[i-1][0], groups[i][0])
+		}
+	}
+
+	nans := []*Decimal{
+		{Form: NaN},
+		{Form: NaN, Negative: true},
+		{Form: NaN, Coeff: *big.NewInt(12)},
+		{Form: NaN, Coeff: *big.NewInt(120)},
+		{Form: NaNSignaling},
+		{Form: NaNSignaling, Negative: true, Coeff: *big.NewInt(12)},
+	}
+	seen := map[string]string{}
+	for _, d := range nans {
+		s := fmt.Sprintf("%+v(%s)", d, &d.Coeff)
+		k := d.Key()
+		if o, ok := seen[k]; ok {
+			t.Errorf("%s and %s have the same key", s, o)
+		}
+		seen[k] = s
+		if k != new(Decimal).Set(d).Key() {
+			t.Errorf("%s: unstable key", s)
+		}
+		if k <= keys[len(keys)-1] {
+			t.Errorf("%s: expected key after Infinity", s)
+		}
+	}
+}
+
+func TestKeyOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() *Decimal {
+		var sb strings.Builder
+		if rng.Intn(2) == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(byte('1' + rng.Intn(9)))
+		for n := rng.Intn(40); n > 0; n-- {
+			sb.WriteByte(byte('0' + rng.Intn(10)))
+		}
+		d := newDecimal(t, testCtx, sb.String())
+		d.Exponent = int32(rng.Intn(60) - 30)
+		return d
+	}
+	ds := make([]*Decimal, 500)
+	for i := range ds {
+		ds[i] = random()
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Key() < ds[j].Key() })
+	for i := 1; i < len(ds); i++ {
+		if ds[i-1].Cmp(ds[i]) > 0 {
+			t.Fatalf("keys order %s before %s", ds[i-1], ds[i])
+		}
+	}
+}
+
+func TestAppendKeyAllocs(t *testing.T) {
+	d := New(-12345, -2)
+	buf := make([]byte, 0, 32)
+	if n := testing.AllocsPerRun(100, func() {
+		buf = d.AppendKey(buf[:0])
+	}); n != 0 {
+		t.Errorf("expected no allocations, got %v", n)
+	}
+	m := map[string]int{d.Key(): 1}
+	if m[New(-123450, -3).Key()] != 1 {
+		t.Error("expected equal decimals to find the same map entry")
+	}
+}
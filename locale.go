@@ -0,0 +1,135 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "strings"
+
+// Locale holds the separators used to format numbers in a locale.
+type Locale struct {
+	// DecimalSeparator separates the integer and fractional digits.
+	DecimalSeparator string
+	// GroupSeparator separates groups of integer digits.
+	GroupSeparator string
+	// GroupSize is the number of digits in the rightmost group, and
+	// SecondaryGroupSize the number in the others, or zero if they have
+	// GroupSize digits. GroupSize zero disables grouping.
+	GroupSize, SecondaryGroupSize int
+}
+
+// locales maps BCP 47 language tags to their separators, as given by the
+// Unicode CLDR. Lookups fall back from a region-specific tag to its
+// language.
+var locales = map[string]Locale{
+	"cs":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"da":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"de":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"de-AT": {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"de-CH": {DecimalSeparator: ".", GroupSeparator: "’", GroupSize: 3},
+	"en":    {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3},
+	"en-IN": {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3, SecondaryGroupSize: 2},
+	"es":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"es-MX": {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3},
+	"fi":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"fr":    {DecimalSeparator: ",", GroupSeparator: "\u202f", GroupSize: 3},
+	"fr-CH": {DecimalSeparator: ",", GroupSeparator: "\u202f", GroupSize: 3},
+	"hi":    {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3, SecondaryGroupSize: 2},
+	"it":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"it-CH": {DecimalSeparator: ".", GroupSeparator: "’", GroupSize: 3},
+	"ja":    {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3},
+	"ko":    {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3},
+	"nb":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"nl":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"pl":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"pt":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"pt-PT": {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"ru":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"sv":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"tr":    {DecimalSeparator: ",", GroupSeparator: ".", GroupSize: 3},
+	"uk":    {DecimalSeparator: ",", GroupSeparator: "\u00a0", GroupSize: 3},
+	"zh":    {DecimalSeparator: ".", GroupSeparator: ",", GroupSize: 3},
+}
+
+// LookupLocale returns the separators of the locale identified by the BCP
+// 47 language tag, such as "de-DE". A golang.org/x/text/language.Tag can be
+// looked up with tag.String(). If the tag has no entry, its language is
+// tried, and if that fails too the English locale is returned with ok set
+// to false.
+func LookupLocale(tag string) (l Locale, ok bool) {
+	tag = strings.Replace(tag, "_", "-", -1)
+	for {
+		if l, ok := locales[tag]; ok {
+			return l, true
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return locales["en"], false
+}
+
+// Format returns d formatted in the locale l with prec fractional digits,
+// rounding with RoundHalfEven as needed, or with all digits of d if prec
+// is negative. The result never uses an exponent; NaN and infinities are
+// formatted as by d.String.
+func (l Locale) Format(d *Decimal, prec int) string {
+	return string(l.Append(nil, d, prec))
+}
+
+// Append appends d formatted as by Format to buf and returns the extended
+// buffer.
+func (l Locale) Append(buf []byte, d *Decimal, prec int) []byte {
+	if d.Form != Finite {
+		return d.appendExact(buf, 'G')
+	}
+	var tmp [32]byte
+	s := d.Append(tmp[:0], 'f', prec)
+	if len(s) > 0 && s[0] == '-' {
+		buf = append(buf, '-')
+		s = s[1:]
+	}
+	intLen := len(s)
+	for i, c := range s {
+		if c == '.' {
+			intLen = i
+			break
+		}
+	}
+	for i := 0; i < intLen; i++ {
+		if i > 0 && l.groupBoundary(intLen-i) {
+			buf = append(buf, l.GroupSeparator...)
+		}
+		buf = append(buf, s[i])
+	}
+	if intLen < len(s) {
+		buf = append(buf, l.DecimalSeparator...)
+		buf = append(buf, s[intLen+1:]...)
+	}
+	return buf
+}
+
+// groupBoundary reports whether a group separator precedes an integer
+// digit that has n digits, itself included, up to the decimal point.
+func (l Locale) groupBoundary(n int) bool {
+	if l.GroupSize <= 0 || n < l.GroupSize {
+		return false
+	}
+	secondary := l.SecondaryGroupSize
+	if secondary <= 0 {
+		secondary = l.GroupSize
+	}
+	return (n-l.GroupSize)%secondary == 0
+}
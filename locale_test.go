@@ -0,0 +1,64 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestLocaleFormat(t *testing.T) {
+	tests := []struct {
+		tag  string
+		d    string
+		prec int
+		out  string
+	}{
+		{tag: "de-DE", d: "1234567.891", prec: 2, out: "1.234.567,89"},
+		{tag: "en-US", d: "1234567.891", prec: 2, out: "1,234,567.89"},
+		{tag: "en", d: "-1234.5", prec: -1, out: "-1,234.5"},
+		{tag: "en", d: "123", prec: 0, out: "123"},
+		{tag: "en", d: "123456", prec: 0, out: "123,456"},
+		{tag: "en", d: "0.005", prec: 2, out: "0.00"},
+		{tag: "en", d: "1E+6", prec: -1, out: "1,000,000"},
+		{tag: "fr-FR", d: "1234.5", prec: 2, out: "1\u202f234,50"},
+		{tag: "de-CH", d: "1234.5", prec: 1, out: "1’234.5"},
+		{tag: "en-IN", d: "12345678.9", prec: 1, out: "1,23,45,678.9"},
+		{tag: "ru_RU", d: "-9999.99", prec: 1, out: "-10\u00a0000,0"},
+		{tag: "en", d: "NaN", prec: 2, out: "NaN"},
+		{tag: "de", d: "-Infinity", prec: 2, out: "-Infinity"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.tag+" "+tc.d, func(t *testing.T) {
+			l, ok := LookupLocale(tc.tag)
+			if !ok {
+				t.Fatalf("unknown locale %s", tc.tag)
+			}
+			if s := l.Format(newDecimal(t, testCtx, tc.d), tc.prec); s != tc.out {
+				t.Fatalf("expected %q, got %q", tc.out, s)
+			}
+		})
+	}
+}
+
+func TestLookupLocale(t *testing.T) {
+	if l, ok := LookupLocale("de-Latn-DE"); !ok || l.DecimalSeparator != "," {
+		t.Errorf("expected fallback to de, got %+v, %v", l, ok)
+	}
+	if l, ok := LookupLocale("xx-YY"); ok || l.DecimalSeparator != "." {
+		t.Errorf("expected English and not ok, got %+v, %v", l, ok)
+	}
+	l := Locale{DecimalSeparator: ".", GroupSeparator: ","}
+	if s := l.Format(New(1234567, -2), -1); s != "12345.67" {
+		t.Errorf("expected no grouping, got %s", s)
+	}
+}
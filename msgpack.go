@@ -0,0 +1,106 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// MsgpackExtType is the msgpack extension type used by RegisterMsgpackExt.
+// Applications may use any type in the range 0 to 127 if it conflicts with
+// another extension.
+const MsgpackExtType = 67
+
+// MarshalMsgpackExt returns the payload of the msgpack extension encoding
+// of d. The payload is compact and lossless: a flag byte holding the sign
+// and form, followed for finite values by the exponent as a zig-zag varint
+// and the coefficient as big-endian bytes.
+func (d *Decimal) MarshalMsgpackExt() []byte {
+	return d.appendBinary(nil)
+}
+
+// UnmarshalMsgpackExt sets d to the value of the msgpack extension payload
+// b produced by MarshalMsgpackExt.
+func (d *Decimal) UnmarshalMsgpackExt(b []byte) error {
+	return errors.Wrap(d.setBinary(b), "msgpack")
+}
+
+// MsgpackExt implements the BytesExt interface of the msgpack handles of
+// github.com/ugorji/go/codec and github.com/hashicorp/go-msgpack/codec for
+// Decimal values. See RegisterMsgpackExt.
+type MsgpackExt struct{}
+
+// WriteExt returns the extension payload of v, which must be a Decimal or
+// *Decimal.
+func (MsgpackExt) WriteExt(v interface{}) []byte {
+	switch d := v.(type) {
+	case *Decimal:
+		return d.MarshalMsgpackExt()
+	case Decimal:
+		return d.MarshalMsgpackExt()
+	}
+	panic(errors.Errorf("msgpack: unexpected type %T", v))
+}
+
+// ReadExt decodes the extension payload src into dst, which must be a
+// *Decimal. Since the interface does not allow returning an error, errors
+// are reported by panicking, which the codec packages recover from.
+func (MsgpackExt) ReadExt(dst interface{}, src []byte) {
+	d, ok := dst.(*Decimal)
+	if !ok {
+		panic(errors.Errorf("msgpack: unexpected type %T", dst))
+	}
+	if err := d.UnmarshalMsgpackExt(src); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterMsgpackExt registers MsgpackExt for Decimal with extension type
+// MsgpackExtType on handle, which must have a SetBytesExt method like
+// *codec.MsgpackHandle:
+//
+//     var h codec.MsgpackHandle
+//     if err := apd.RegisterMsgpackExt(&h); err != nil {
+//         ...
+//     }
+//
+// Registering through this helper avoids a dependency of this package on
+// the codec package.
+func RegisterMsgpackExt(handle interface{}) error {
+	m := reflect.ValueOf(handle).MethodByName("SetBytesExt")
+	if !m.IsValid() {
+		return errors.Errorf("msgpack: %T has no SetBytesExt method", handle)
+	}
+	t := m.Type()
+	ext := reflect.ValueOf(MsgpackExt{})
+	if t.NumIn() != 3 || t.NumOut() != 1 ||
+		t.In(0) != reflect.TypeOf((*reflect.Type)(nil)).Elem() ||
+		t.In(1).Kind() != reflect.Uint64 ||
+		!ext.Type().AssignableTo(t.In(2)) ||
+		t.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
+		return errors.Errorf("msgpack: unexpected SetBytesExt signature %s", t)
+	}
+	out := m.Call([]reflect.Value{
+		reflect.ValueOf(reflect.TypeOf(Decimal{})),
+		reflect.ValueOf(uint64(MsgpackExtType)).Convert(t.In(1)),
+		ext,
+	})
+	if err, _ := out[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackExt(t *testing.T) {
+	tests := []struct {
+		x       string
+		payload string
+	}{
+		{x: "0", payload: "0000"},
+		{x: "-0", payload: "0100"},
+		{x: "1.5", payload: "00010f"},
+		{x: "-1.50", payload: "010396"},
+		{x: "1E+100", payload: "00c80101"},
+		{x: "123456789012345678901234567890", payload: "0000018ee90ff6c373e0ee4e3f0ad2"},
+		{x: "Infinity", payload: "02"},
+		{x: "-Infinity", payload: "03"},
+		{x: "NaN", payload: "04"},
+		{x: "-sNaN", payload: "09"},
+	}
+	var ext MsgpackExt
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			b := ext.WriteExt(x)
+			if s := hex.EncodeToString(b); s != tc.payload {
+				t.Fatalf("expected %s, got %s", tc.payload, s)
+			}
+			var d Decimal
+			ext.ReadExt(&d, b)
+			if d.CmpTotal(x) != 0 || d.Negative != x.Negative {
+				t.Fatalf("expected %s, got %s", x, &d)
+			}
+		})
+	}
+}
+
+func TestUnmarshalMsgpackExtError(t *testing.T) {
+	for _, s := range []string{"", "10", "0280", "00", "00ffffffffff01"} {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := new(Decimal).UnmarshalMsgpackExt(b); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+// fakeBytesExt and fakeMsgpackHandle mimic the registration API of the
+// codec package.
+type fakeBytesExt interface {
+	WriteExt(v interface{}) []byte
+	ReadExt(dst interface{}, src []byte)
+}
+
+type fakeMsgpackHandle struct {
+	rt  reflect.Type
+	tag uint64
+	ext fakeBytesExt
+}
+
+func (h *fakeMsgpackHandle) SetBytesExt(rt reflect.Type, tag uint64, ext fakeBytesExt) error {
+	h.rt, h.tag, h.ext = rt, tag, ext
+	return nil
+}
+
+func TestRegisterMsgpackExt(t *testing.T) {
+	var h fakeMsgpackHandle
+	if err := RegisterMsgpackExt(&h); err != nil {
+		t.Fatal(err)
+	}
+	if h.rt != reflect.TypeOf(Decimal{}) || h.tag != MsgpackExtType || h.ext == nil {
+		t.Fatalf("unexpected registration: %+v", h)
+	}
+	if err := RegisterMsgpackExt(struct{}{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !apd_stringcache
+// +build !apd_stringcache
+
+package apd
+
+// stringCache is empty unless the apd_stringcache build tag is set, so that
+// Decimal stays as small as possible. See stringcache.go.
+type stringCache struct{}
+
+func (d *Decimal) loadString() (string, bool) { return "", false }
+
+func (d *Decimal) storeString(string) {}
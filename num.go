@@ -0,0 +1,144 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+// Num is an immutable decimal with value semantics, for code where the
+// destination-pointer convention of Decimal and Context is error-prone. A
+// Num is never modified after it is created: the Num operations of Context
+// return new Nums and leave their arguments alone, so Nums can be copied,
+// compared, stored in structs and shared between goroutines freely. The
+// zero Num is 0.
+//
+// A Num costs an allocation per operation; use Decimal in hot loops.
+type Num struct {
+	// d is never modified. nil is 0.
+	d *Decimal
+}
+
+// NewNum returns the Num coeff * 10**exponent.
+func NewNum(coeff int64, exponent int32) Num {
+	return Num{New(coeff, exponent)}
+}
+
+// NumOf returns a Num with the value of d. d is copied, so it may be
+// modified afterward without affecting the result.
+func NumOf(d *Decimal) Num {
+	return Num{new(Decimal).Set(d)}
+}
+
+// NewNumFromString returns a Num parsed from s, as NewFromString does.
+func NewNumFromString(s string) (Num, Condition, error) {
+	d, res, err := NewFromString(s)
+	if err != nil {
+		return Num{}, res, err
+	}
+	return Num{d}, res, nil
+}
+
+// decimal returns the value of n for use as an operand. It must not be
+// modified.
+func (n Num) decimal() *Decimal {
+	if n.d == nil {
+		return decimalZero
+	}
+	return n.d
+}
+
+// Decimal returns a new Decimal with the value of n.
+func (n Num) Decimal() *Decimal {
+	return new(Decimal).Set(n.decimal())
+}
+
+// String returns the string form of n, as Decimal.String does.
+func (n Num) String() string {
+	return n.decimal().String()
+}
+
+// Cmp compares n and m as Decimal.Cmp does.
+func (n Num) Cmp(m Num) int {
+	return n.decimal().Cmp(m.decimal())
+}
+
+// Sign returns the sign of n as Decimal.Sign does.
+func (n Num) Sign() int {
+	return n.decimal().Sign()
+}
+
+// IsZero returns true if n is 0 or -0.
+func (n Num) IsZero() bool {
+	return n.decimal().IsZero()
+}
+
+// num1 applies op to x and returns its result as a new Num. The result is
+// returned along with an error, as op's destination would be.
+func (c *Context) num1(x Num, op func(c *Context, d, x *Decimal) (Condition, error)) (Num, Condition, error) {
+	d := new(Decimal)
+	res, err := op(c, d, x.decimal())
+	return Num{d}, res, err
+}
+
+// num2 is num1 for binary operations.
+func (c *Context) num2(x, y Num, op func(c *Context, d, x, y *Decimal) (Condition, error)) (Num, Condition, error) {
+	d := new(Decimal)
+	res, err := op(c, d, x.decimal(), y.decimal())
+	return Num{d}, res, err
+}
+
+// AddNum returns the sum x+y.
+func (c *Context) AddNum(x, y Num) (Num, Condition, error) {
+	return c.num2(x, y, (*Context).Add)
+}
+
+// SubNum returns the difference x-y.
+func (c *Context) SubNum(x, y Num) (Num, Condition, error) {
+	return c.num2(x, y, (*Context).Sub)
+}
+
+// MulNum returns the product x*y.
+func (c *Context) MulNum(x, y Num) (Num, Condition, error) {
+	return c.num2(x, y, (*Context).Mul)
+}
+
+// QuoNum returns the quotient x/y.
+func (c *Context) QuoNum(x, y Num) (Num, Condition, error) {
+	return c.num2(x, y, (*Context).Quo)
+}
+
+// PowNum returns x**y.
+func (c *Context) PowNum(x, y Num) (Num, Condition, error) {
+	return c.num2(x, y, (*Context).Pow)
+}
+
+// NegNum returns -x.
+func (c *Context) NegNum(x Num) (Num, Condition, error) {
+	return c.num1(x, (*Context).Neg)
+}
+
+// AbsNum returns |x|.
+func (c *Context) AbsNum(x Num) (Num, Condition, error) {
+	return c.num1(x, (*Context).Abs)
+}
+
+// RoundNum returns x rounded to c's precision.
+func (c *Context) RoundNum(x Num) (Num, Condition, error) {
+	return c.num1(x, (*Context).Round)
+}
+
+// QuantizeNum returns x with its exponent set to exp, as Quantize does.
+func (c *Context) QuantizeNum(x Num, exp int32) (Num, Condition, error) {
+	d := new(Decimal)
+	res, err := c.Quantize(d, x.decimal(), exp)
+	return Num{d}, res, err
+}
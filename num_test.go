@@ -0,0 +1,83 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestNum(t *testing.T) {
+	var zero Num
+	if !zero.IsZero() || zero.String() != "0" || zero.Sign() != 0 {
+		t.Fatalf("unexpected zero Num %s", zero)
+	}
+
+	d := New(150, -2)
+	x := NumOf(d)
+	d.SetInt64(7)
+	if s := x.String(); s != "1.50" {
+		t.Fatalf("NumOf shares storage: expected 1.50, got %s", s)
+	}
+	x.Decimal().SetInt64(9)
+	if s := x.String(); s != "1.50" {
+		t.Fatalf("Decimal shares storage: expected 1.50, got %s", s)
+	}
+
+	y, _, err := NewNumFromString("-0.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := NewNumFromString("1.2.3"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	c := BaseContext.WithPrecision(5)
+	tests := []struct {
+		name   string
+		f      func() (Num, Condition, error)
+		expect string
+	}{
+		{"add", func() (Num, Condition, error) { return c.AddNum(x, y) }, "1.25"},
+		{"sub", func() (Num, Condition, error) { return c.SubNum(x, y) }, "1.75"},
+		{"mul", func() (Num, Condition, error) { return c.MulNum(x, y) }, "-0.3750"},
+		{"quo", func() (Num, Condition, error) { return c.QuoNum(x, y) }, "-6"},
+		{"pow", func() (Num, Condition, error) { return c.PowNum(x, NewNum(2, 0)) }, "2.2500"},
+		{"neg", func() (Num, Condition, error) { return c.NegNum(x) }, "-1.50"},
+		{"abs", func() (Num, Condition, error) { return c.AbsNum(y) }, "0.25"},
+		{"round", func() (Num, Condition, error) { return c.RoundNum(NewNum(1234567, -2)) }, "12346"},
+		{"quantize", func() (Num, Condition, error) { return c.QuantizeNum(x, -3) }, "1.500"},
+		{"zero", func() (Num, Condition, error) { return c.AddNum(Num{}, x) }, "1.50"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _, err := tc.f()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := r.String(); s != tc.expect {
+				t.Fatalf("expected %s, got %s", tc.expect, s)
+			}
+			// The operands are never modified.
+			if x.String() != "1.50" || y.String() != "-0.25" {
+				t.Fatalf("operands changed to %s, %s", x, y)
+			}
+		})
+	}
+
+	if _, _, err := c.QuoNum(x, Num{}); err == nil {
+		t.Fatal("expected division by zero error")
+	}
+	if x.Cmp(y) != 1 || y.Cmp(x) != -1 || x.Cmp(NewNum(15, -1)) != 0 {
+		t.Fatal("unexpected Cmp")
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ParquetDecimal describes a Parquet DECIMAL logical type, which stores a
+// value as a signed unscaled integer U representing U × 10**-Scale. The
+// unscaled integer is limited to Precision digits.
+type ParquetDecimal struct {
+	Precision int32
+	Scale     int32
+}
+
+const (
+	// parquetInt32MaxPrecision and parquetInt64MaxPrecision are the largest
+	// precisions allowed for the INT32 and INT64 physical types.
+	parquetInt32MaxPrecision = 9
+	parquetInt64MaxPrecision = 18
+)
+
+// validate returns an error if p is not a valid DECIMAL type with at most
+// maxPrecision digits.
+func (p ParquetDecimal) validate(maxPrecision int32) error {
+	if p.Precision < 1 {
+		return errors.Errorf("parquet: invalid precision %d", p.Precision)
+	}
+	if p.Scale < 0 || p.Scale > p.Precision {
+		return errors.Errorf("parquet: invalid scale %d for precision %d", p.Scale, p.Precision)
+	}
+	if p.Precision > maxPrecision {
+		return errors.Errorf("parquet: precision %d exceeds maximum %d of physical type", p.Precision, maxPrecision)
+	}
+	return nil
+}
+
+// unscaled sets b to the unscaled integer of x. It returns an error if x
+// cannot be represented exactly with p's scale and precision.
+func (p ParquetDecimal) unscaled(b *big.Int, x *Decimal) error {
+	if err := unscaledValue(b, x, p.Scale); err != nil {
+		return errors.Wrap(err, "parquet")
+	}
+	if NumDigits(b) > int64(p.Precision) {
+		return errors.Errorf("parquet: %s exceeds precision %d", x, p.Precision)
+	}
+	return nil
+}
+
+// scaled sets d to b × 10**-Scale. It returns an error if b has more digits
+// than p's precision.
+func (p ParquetDecimal) scaled(d *Decimal, b *big.Int) (*Decimal, error) {
+	if NumDigits(b) > int64(p.Precision) {
+		return nil, errors.Errorf("parquet: unscaled value %s exceeds precision %d", b, p.Precision)
+	}
+	d.Form = Finite
+	d.Negative = b.Sign() < 0
+	d.Coeff.Abs(b)
+	d.Exponent = -p.Scale
+	return d, nil
+}
+
+// unscaledValue sets b to the signed integer U such that x = U × 10**-scale.
+// It returns an error if x is not finite or has more than scale fractional
+// digits.
+func unscaledValue(b *big.Int, x *Decimal, scale int32) error {
+	if x.Form != Finite {
+		return errors.Errorf("%s is not finite", x)
+	}
+	tmp := new(big.Int)
+	if diff := int64(x.Exponent) + int64(scale); diff >= 0 {
+		if x.IsZero() {
+			b.SetInt64(0)
+			return nil
+		}
+		if diff > MaxExponent {
+			return errors.New(errExponentOutOfRangeStr)
+		}
+		b.Mul(&x.Coeff, tableExp10(diff, tmp))
+	} else {
+		if -diff > MaxExponent {
+			return errors.New(errExponentOutOfRangeStr)
+		}
+		m := new(big.Int)
+		b.QuoRem(&x.Coeff, tableExp10(-diff, tmp), m)
+		if m.Sign() != 0 {
+			return errors.Errorf("%s: more than %d fractional digits", x, scale)
+		}
+	}
+	if x.Negative {
+		b.Neg(b)
+	}
+	return nil
+}
+
+// twosComplement returns the big-endian two's complement encoding of b in n
+// bytes, or in the fewest bytes able to hold it if n is zero. It returns an
+// error if b does not fit in n bytes.
+func twosComplement(b *big.Int, n int) ([]byte, error) {
+	// A non-negative value needs a clear sign bit. The magnitude of a
+	// negative value is encoded as its one's complement, which needs the same
+	// number of bits as |b|-1.
+	v := b
+	if b.Sign() < 0 {
+		v = new(big.Int).Not(b)
+	}
+	need := v.BitLen()/8 + 1
+	if n == 0 {
+		n = need
+	} else if need > n {
+		return nil, errors.Errorf("%s does not fit in %d bytes", b, n)
+	}
+	buf := make([]byte, n)
+	v.FillBytes(buf)
+	if b.Sign() < 0 {
+		for i := range buf {
+			buf[i] = ^buf[i]
+		}
+	}
+	return buf, nil
+}
+
+// setTwosComplement sets b to the value of the big-endian two's complement
+// encoding buf, and returns b.
+func setTwosComplement(b *big.Int, buf []byte) *big.Int {
+	b.SetBytes(buf)
+	if len(buf) > 0 && buf[0]&0x80 != 0 {
+		b.Sub(b, new(big.Int).Lsh(bigOne, uint(len(buf))*8))
+	}
+	return b
+}
+
+// fixedLenPrecision returns the largest precision that always fits in a
+// FIXED_LEN_BYTE_ARRAY of n bytes.
+func fixedLenPrecision(n int) int32 {
+	m := new(big.Int).Lsh(bigOne, uint(8*n-1))
+	m.Sub(m, bigOne)
+	return int32(NumDigits(m) - 1)
+}
+
+// Int32 returns x as the unscaled value of an INT32 DECIMAL column. It
+// returns an error if x cannot be represented exactly with p's scale and
+// precision, or if p's precision is larger than 9.
+func (p ParquetDecimal) Int32(x *Decimal) (int32, error) {
+	if err := p.validate(parquetInt32MaxPrecision); err != nil {
+		return 0, err
+	}
+	var b big.Int
+	if err := p.unscaled(&b, x); err != nil {
+		return 0, err
+	}
+	return int32(b.Int64()), nil
+}
+
+// Int64 returns x as the unscaled value of an INT64 DECIMAL column. It
+// returns an error if x cannot be represented exactly with p's scale and
+// precision, or if p's precision is larger than 18.
+func (p ParquetDecimal) Int64(x *Decimal) (int64, error) {
+	if err := p.validate(parquetInt64MaxPrecision); err != nil {
+		return 0, err
+	}
+	var b big.Int
+	if err := p.unscaled(&b, x); err != nil {
+		return 0, err
+	}
+	return b.Int64(), nil
+}
+
+// FixedLenByteArray returns x as the unscaled value of a
+// FIXED_LEN_BYTE_ARRAY DECIMAL column of n bytes, in big-endian two's
+// complement. It returns an error if x cannot be represented exactly with
+// p's scale and precision, or if p's precision does not fit in n bytes.
+func (p ParquetDecimal) FixedLenByteArray(x *Decimal, n int) ([]byte, error) {
+	if n < 1 {
+		return nil, errors.Errorf("parquet: invalid length %d", n)
+	}
+	if err := p.validate(fixedLenPrecision(n)); err != nil {
+		return nil, err
+	}
+	var b big.Int
+	if err := p.unscaled(&b, x); err != nil {
+		return nil, err
+	}
+	return twosComplement(&b, n)
+}
+
+// ByteArray returns x as the unscaled value of a BYTE_ARRAY DECIMAL column,
+// in big-endian two's complement using the fewest bytes possible. It returns
+// an error if x cannot be represented exactly with p's scale and precision.
+func (p ParquetDecimal) ByteArray(x *Decimal) ([]byte, error) {
+	if err := p.validate(p.Precision); err != nil {
+		return nil, err
+	}
+	var b big.Int
+	if err := p.unscaled(&b, x); err != nil {
+		return nil, err
+	}
+	return twosComplement(&b, 0)
+}
+
+// SetInt32 sets d to the value of the unscaled INT32 DECIMAL value v, and
+// returns d. It returns an error if v exceeds p's precision, or if p's
+// precision is larger than 9.
+func (p ParquetDecimal) SetInt32(d *Decimal, v int32) (*Decimal, error) {
+	if err := p.validate(parquetInt32MaxPrecision); err != nil {
+		return nil, err
+	}
+	return p.scaled(d, big.NewInt(int64(v)))
+}
+
+// SetInt64 sets d to the value of the unscaled INT64 DECIMAL value v, and
+// returns d. It returns an error if v exceeds p's precision, or if p's
+// precision is larger than 18.
+func (p ParquetDecimal) SetInt64(d *Decimal, v int64) (*Decimal, error) {
+	if err := p.validate(parquetInt64MaxPrecision); err != nil {
+		return nil, err
+	}
+	return p.scaled(d, big.NewInt(v))
+}
+
+// SetBytes sets d to the value of the big-endian two's complement unscaled
+// value buf of a FIXED_LEN_BYTE_ARRAY or BYTE_ARRAY DECIMAL column, and
+// returns d. It returns an error if the value exceeds p's precision.
+func (p ParquetDecimal) SetBytes(d *Decimal, buf []byte) (*Decimal, error) {
+	if err := p.validate(p.Precision); err != nil {
+		return nil, err
+	}
+	return p.scaled(d, setTwosComplement(new(big.Int), buf))
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestParquetDecimal(t *testing.T) {
+	tests := []struct {
+		x          string
+		precision  int32
+		scale      int32
+		i64        int64
+		fixed      string
+		byteArray  string
+		err        bool
+		overflow32 bool
+	}{
+		{x: "0", precision: 5, scale: 2, i64: 0, fixed: "00000000", byteArray: "00"},
+		{x: "1.23", precision: 5, scale: 2, i64: 123, fixed: "0000007b", byteArray: "7b"},
+		{x: "-1.2", precision: 5, scale: 2, i64: -120, fixed: "ffffff88", byteArray: "88"},
+		{x: "-1.28", precision: 5, scale: 2, i64: -128, fixed: "ffffff80", byteArray: "80"},
+		{x: "1.28", precision: 5, scale: 2, i64: 128, fixed: "00000080", byteArray: "0080"},
+		{x: "12E+1", precision: 5, scale: 2, i64: 12000, fixed: "00002ee0", byteArray: "2ee0"},
+		{x: "999.99", precision: 5, scale: 2, i64: 99999, fixed: "0001869f", byteArray: "01869f"},
+		{x: "9999999999", precision: 10, scale: 0, i64: 9999999999, fixed: "00000002540be3ff", byteArray: "02540be3ff", overflow32: true},
+		{x: "1000.00", precision: 5, scale: 2, err: true},
+		{x: "1.234", precision: 5, scale: 2, err: true},
+		{x: "Infinity", precision: 5, scale: 2, err: true},
+		{x: "NaN", precision: 5, scale: 2, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			p := ParquetDecimal{Precision: tc.precision, Scale: tc.scale}
+			n := len(tc.fixed) / 2
+			if n == 0 {
+				n = 4
+			}
+			i32, err32 := p.Int32(x)
+			i64, err64 := p.Int64(x)
+			fixed, errFixed := p.FixedLenByteArray(x, n)
+			byteArray, errBytes := p.ByteArray(x)
+			if tc.err {
+				for _, err := range []error{err32, err64, errFixed, errBytes} {
+					if err == nil {
+						t.Fatal("expected error")
+					}
+				}
+				return
+			}
+			if tc.overflow32 != (err32 != nil) {
+				t.Fatalf("int32: unexpected error: %v", err32)
+			}
+			if err32 == nil && int64(i32) != tc.i64 {
+				t.Errorf("int32: expected %d, got %d", tc.i64, i32)
+			}
+			for _, err := range []error{err64, errFixed, errBytes} {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if i64 != tc.i64 {
+				t.Errorf("int64: expected %d, got %d", tc.i64, i64)
+			}
+			if s := hex.EncodeToString(fixed); s != tc.fixed {
+				t.Errorf("fixed: expected %s, got %s", tc.fixed, s)
+			}
+			if s := hex.EncodeToString(byteArray); s != tc.byteArray {
+				t.Errorf("byte array: expected %s, got %s", tc.byteArray, s)
+			}
+
+			for _, buf := range [][]byte{fixed, byteArray} {
+				d, err := p.SetBytes(new(Decimal), buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d.Cmp(x) != 0 || d.Exponent != -tc.scale {
+					t.Errorf("expected %s with scale %d, got %s", x, tc.scale, d)
+				}
+			}
+			d, err := p.SetInt64(new(Decimal), i64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Cmp(x) != 0 {
+				t.Errorf("expected %s, got %s", x, d)
+			}
+		})
+	}
+}
+
+func TestParquetDecimalValidation(t *testing.T) {
+	x := New(1, 0)
+	if _, err := (ParquetDecimal{Precision: 10}).Int32(x); err == nil {
+		t.Error("int32: expected precision error")
+	}
+	if _, err := (ParquetDecimal{Precision: 19}).Int64(x); err == nil {
+		t.Error("int64: expected precision error")
+	}
+	if _, err := (ParquetDecimal{Precision: 10}).SetInt32(new(Decimal), 1); err == nil {
+		t.Error("set int32: expected precision error")
+	}
+	if _, err := (ParquetDecimal{Precision: 19}).SetInt64(new(Decimal), 1); err == nil {
+		t.Error("set int64: expected precision error")
+	}
+	// Four bytes hold at most 9 digits.
+	if _, err := (ParquetDecimal{Precision: 10}).FixedLenByteArray(x, 4); err == nil {
+		t.Error("fixed: expected precision error")
+	}
+	if _, err := (ParquetDecimal{Precision: 5, Scale: 6}).ByteArray(x); err == nil {
+		t.Error("byte array: expected scale error")
+	}
+	if _, err := (ParquetDecimal{Precision: 2}).SetInt64(new(Decimal), 100); err == nil {
+		t.Error("expected error decoding value exceeding precision")
+	}
+}
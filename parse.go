@@ -0,0 +1,226 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseFlags modify the syntax accepted by Context.Parse. The zero value
+// accepts exactly what SetString accepts.
+type ParseFlags uint
+
+const (
+	// ParseUnderscores permits a single underscore between any two digits,
+	// as in Go number literals (for example "1_000_000.25" or "1e1_0").
+	ParseUnderscores ParseFlags = 1 << iota
+	// ParseSpace permits leading and trailing white space.
+	ParseSpace
+	// ParseEmptyExponent permits an exponent marker with no digits, as in
+	// "1e" or "1e+", which is treated as an exponent of zero.
+	ParseEmptyExponent
+	// ParseNoPlus rejects a leading '+' sign.
+	ParseNoPlus
+	// ParseNoLeadingZeros rejects an integer part with more than one digit
+	// that starts with '0', such as "007" or "00.5".
+	ParseNoLeadingZeros
+	// ParseNoBarePoint rejects a decimal point without a digit on both sides,
+	// such as "1." or ".5".
+	ParseNoBarePoint
+	// ParseNoSpecials rejects infinities and NaNs.
+	ParseNoSpecials
+
+	// ParseStrict rejects the optional forms SetString accepts, for
+	// enforcing a canonical wire format.
+	ParseStrict = ParseNoPlus | ParseNoLeadingZeros | ParseNoBarePoint
+	// ParseLenient accepts the common variations found in hand-written
+	// input.
+	ParseLenient = ParseUnderscores | ParseSpace | ParseEmptyExponent
+)
+
+// NewFromStringFlags is like NewFromString, with the syntax of s modified by
+// flags.
+func (c *Context) NewFromStringFlags(s string, flags ParseFlags) (*Decimal, Condition, error) {
+	d := new(Decimal)
+	return c.Parse(d, s, flags)
+}
+
+// Parse sets d to s and returns d. It is like SetString, with the syntax of s
+// modified by flags.
+func (c *Context) Parse(d *Decimal, s string, flags ParseFlags) (*Decimal, Condition, error) {
+	s, err := checkSyntax(s, flags)
+	if err != nil {
+		return c.conversionSyntax(d, err)
+	}
+	return c.SetString(d, s)
+}
+
+// checkSyntax verifies s against the restrictions in flags and returns s
+// rewritten to the syntax accepted by setString.
+func checkSyntax(s string, flags ParseFlags) (string, error) {
+	orig := s
+	if flags&ParseSpace != 0 {
+		s = strings.TrimSpace(s)
+	}
+	if flags&ParseUnderscores != 0 {
+		var err error
+		if s, err = stripUnderscores(s); err != nil {
+			return "", err
+		}
+	}
+	if flags&(ParseEmptyExponent|ParseNoPlus|ParseNoLeadingZeros|ParseNoBarePoint|ParseNoSpecials) == 0 {
+		return s, nil
+	}
+	num := s
+	if strings.HasPrefix(num, "+") {
+		if flags&ParseNoPlus != 0 {
+			return "", errors.Errorf("leading '+': %s", orig)
+		}
+		num = num[1:]
+	} else if strings.HasPrefix(num, "-") {
+		num = num[1:]
+	}
+	if num == "" || (!isDigit(num[0]) && num[0] != '.') {
+		// Infinity or NaN; setString verifies the rest.
+		if flags&ParseNoSpecials != 0 {
+			return "", errors.Errorf("special value not allowed: %s", orig)
+		}
+		return s, nil
+	}
+	if i := strings.IndexAny(num, "eE"); i >= 0 {
+		if flags&ParseEmptyExponent != 0 {
+			switch num[i+1:] {
+			case "", "+", "-":
+				s = s[:len(s)-len(num)+i]
+			}
+		}
+		num = num[:i]
+	}
+	intPart := num
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		intPart = num[:i]
+		if flags&ParseNoBarePoint != 0 && (i == 0 || i == len(num)-1) {
+			return "", errors.Errorf("bare decimal point: %s", orig)
+		}
+	}
+	if flags&ParseNoLeadingZeros != 0 && len(intPart) > 1 && intPart[0] == '0' {
+		return "", errors.Errorf("leading zero: %s", orig)
+	}
+	return s, nil
+}
+
+// stripUnderscores removes the underscores from s, returning an error if any
+// underscore is not between two decimal digits.
+func stripUnderscores(s string) (string, error) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1]) {
+			return "", errors.Errorf("misplaced underscore: %s", s)
+		}
+		n++
+	}
+	if n == 0 {
+		return s, nil
+	}
+	b := make([]byte, 0, len(s)-n)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b = append(b, s[i])
+		}
+	}
+	return string(b), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// A Decoder reads a stream of decimals from an io.Reader one token at a
+// time, so arbitrarily long inputs can be parsed without holding them in
+// memory.
+type Decoder struct {
+	c   *Context
+	r   io.ByteScanner
+	buf []byte
+}
+
+// NewDecoder returns a Decoder that reads from r and parses decimals under
+// c. If r is not an io.ByteScanner, such as a *bufio.Reader, it is wrapped
+// in one.
+func (c *Context) NewDecoder(r io.Reader) *Decoder {
+	bs, ok := r.(io.ByteScanner)
+	if !ok {
+		bs = bufio.NewReader(r)
+	}
+	return &Decoder{c: c, r: bs}
+}
+
+// Decode skips any leading white space, reads the next decimal token and sets
+// d to it. The token ends at the first byte that cannot be part of a decimal,
+// which is left unread; use ReadByte to consume separators such as ','. At the
+// end of the input Decode returns io.EOF.
+func (dec *Decoder) Decode(d *Decimal) (Condition, error) {
+	dec.buf = dec.buf[:0]
+	for {
+		b, err := dec.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(dec.buf) > 0 {
+				break
+			}
+			return 0, err
+		}
+		if len(dec.buf) == 0 && isSpace(b) {
+			continue
+		}
+		if !isDecimalRune(rune(b)) {
+			if err := dec.r.UnreadByte(); err != nil {
+				return 0, err
+			}
+			if len(dec.buf) == 0 {
+				return 0, errors.Errorf("unexpected %q", b)
+			}
+			break
+		}
+		dec.buf = append(dec.buf, b)
+	}
+	_, res, err := dec.c.SetBytes(d, dec.buf)
+	return res, err
+}
+
+// ReadByte implements io.ByteReader, reading the byte following the last
+// decoded token.
+func (dec *Decoder) ReadByte() (byte, error) {
+	return dec.r.ReadByte()
+}
+
+// UnreadByte implements io.ByteScanner.
+func (dec *Decoder) UnreadByte() error {
+	return dec.r.UnreadByte()
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUnderscores(t *testing.T) {
+	tests := []struct {
+		s   string
+		r   string
+		err bool
+	}{
+		{s: "1_000_000.25", r: "1000000.25"},
+		{s: "-1_0", r: "-10"},
+		{s: "0.000_001", r: "0.000001"},
+		{s: "1_2e1_0", r: "1.2E+11"},
+		{s: "123", r: "123"},
+		{s: "_1", err: true},
+		{s: "1_", err: true},
+		{s: "1__0", err: true},
+		{s: "1_.5", err: true},
+		{s: "1._5", err: true},
+		{s: "1_e5", err: true},
+		{s: "1e_5", err: true},
+		{s: "-_1", err: true},
+		{s: "nan_1", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			d, _, err := BaseContext.NewFromStringFlags(tc.s, ParseUnderscores)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+	if _, _, err := NewFromString("1_000"); err == nil {
+		t.Error("expected error without ParseUnderscores")
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		s     string
+		flags ParseFlags
+		r     string
+	}{
+		{s: "+1", r: "1"},
+		{s: "+1", flags: ParseNoPlus},
+		{s: "-1", flags: ParseStrict, r: "-1"},
+		{s: " 1.5\n", flags: ParseSpace, r: "1.5"},
+		{s: " 1.5"},
+		{s: "1e", flags: ParseEmptyExponent, r: "1"},
+		{s: "1.5E+", flags: ParseEmptyExponent, r: "1.5"},
+		{s: "1e-", flags: ParseEmptyExponent, r: "1"},
+		{s: "1e"},
+		{s: "1e2", flags: ParseEmptyExponent, r: "1E+2"},
+		{s: "007", r: "7"},
+		{s: "007", flags: ParseNoLeadingZeros},
+		{s: "00.5", flags: ParseNoLeadingZeros},
+		{s: "-01", flags: ParseNoLeadingZeros},
+		{s: "0.5", flags: ParseNoLeadingZeros, r: "0.5"},
+		{s: "0", flags: ParseNoLeadingZeros, r: "0"},
+		{s: "10e05", flags: ParseNoLeadingZeros, r: "1.0E+6"},
+		{s: "1.", r: "1"},
+		{s: "1.", flags: ParseNoBarePoint},
+		{s: ".5", flags: ParseNoBarePoint},
+		{s: "-.5e1", flags: ParseNoBarePoint},
+		{s: "1.0", flags: ParseStrict, r: "1.0"},
+		{s: "Infinity", flags: ParseStrict, r: "Infinity"},
+		{s: "-Inf", flags: ParseNoSpecials},
+		{s: "NaN", flags: ParseNoSpecials},
+		{s: " +1_000.5e ", flags: ParseLenient, r: "1000.5"},
+		{s: " +1_000.5e ", flags: ParseLenient | ParseNoPlus},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			d, _, err := BaseContext.NewFromStringFlags(tc.s, tc.flags)
+			if tc.r == "" {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.r {
+				t.Errorf("expected %s, got %s", tc.r, s)
+			}
+		})
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	const input = " 1.5\n-2e3,  3, Infinity\t4"
+	dec := BaseContext.NewDecoder(strings.NewReader(input))
+	var got []string
+	d := new(Decimal)
+	for {
+		_, err := dec.Decode(d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, d.String())
+		// Consume an optional separator.
+		if b, err := dec.ReadByte(); err == nil && b != ',' {
+			if err := dec.UnreadByte(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expect := []string{"1.5", "-2E+3", "3", "Infinity", "4"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected %v, got %v", expect, got)
+	}
+
+	dec = BaseContext.NewDecoder(strings.NewReader("1 ; 2"))
+	if _, err := dec.Decode(d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Decode(d); err == nil {
+		t.Fatal("expected error")
+	}
+	dec = BaseContext.NewDecoder(strings.NewReader("1.2.3"))
+	if _, err := dec.Decode(d); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// TestDecoderLarge checks that a long stream is decoded in full without
+// reading it into memory at once.
+func TestDecoderLarge(t *testing.T) {
+	const n = 100000
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(w, "%d.%02d\n", i, i%100)
+		}
+		w.Flush()
+		pw.Close()
+	}()
+	c := BaseContext.WithPrecision(0)
+	dec := c.NewDecoder(pr)
+	sum, d := new(Decimal), new(Decimal)
+	count := 0
+	for {
+		_, err := dec.Decode(d)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Add(sum, sum, d); err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != n {
+		t.Fatalf("expected %d values, got %d", n, count)
+	}
+	if s := sum.String(); s != "4999999500.00" {
+		t.Errorf("expected 4999999500.00, got %s", s)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	percentSign  = "%"
+	permilleSign = "‰"
+)
+
+// ParsePercent parses s, a decimal number followed by a percent sign ('%')
+// or a permille sign ('‰'), and returns its value: "12.5%" is 0.125 and
+// "12.5‰" is 0.0125. Spaces between the number and the sign are allowed.
+// The division is done exactly by adjusting the exponent, so the
+// coefficient of the result is that of the number in s.
+func ParsePercent(s string) (*Decimal, error) {
+	var shift int64
+	switch {
+	case strings.HasSuffix(s, percentSign):
+		s, shift = strings.TrimSuffix(s, percentSign), -2
+	case strings.HasSuffix(s, permilleSign):
+		s, shift = strings.TrimSuffix(s, permilleSign), -3
+	default:
+		return nil, errors.Errorf("parse percent: missing %% or ‰ sign: %q", s)
+	}
+	d, _, err := NewFromString(strings.TrimRight(s, " "))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse percent")
+	}
+	if err := d.shiftExponent(shift); err != nil {
+		return nil, errors.Wrap(err, "parse percent")
+	}
+	return d, nil
+}
+
+// FormatPercent returns d formatted as a percentage without an exponent:
+// 0.125 is "12.5%". The multiplication is done exactly by adjusting the
+// exponent. NaN and infinities are formatted as by String, followed by
+// the sign.
+func FormatPercent(d *Decimal) string {
+	return formatScaled(d, 2, percentSign)
+}
+
+// FormatPermille is like FormatPercent but formats d per mille: 0.0125 is
+// "12.5‰".
+func FormatPermille(d *Decimal) string {
+	return formatScaled(d, 3, permilleSign)
+}
+
+// formatScaled formats d × 10**shift followed by sign.
+func formatScaled(d *Decimal, shift int64, sign string) string {
+	if d.Form != Finite {
+		return d.String() + sign
+	}
+	var r Decimal
+	r.Set(d)
+	if e := int64(d.Exponent) + shift; e <= math.MaxInt32 {
+		r.Exponent = int32(e)
+		return r.Text('f') + sign
+	}
+	// The exponent no longer fits; adjust the exponent of the scientific
+	// form instead.
+	s := d.Text('E')
+	i := strings.LastIndexByte(s, 'E')
+	e, _ := strconv.ParseInt(s[i+1:], 10, 64)
+	return s[:i+2] + strconv.FormatInt(e+shift, 10) + sign
+}
+
+// shiftExponent multiplies the finite d by 10**n by adjusting its
+// exponent. It returns an error if the exponent is out of range.
+func (d *Decimal) shiftExponent(n int64) error {
+	if d.Form != Finite {
+		return nil
+	}
+	e := int64(d.Exponent) + n
+	if e > MaxExponent || e < MinExponent {
+		return errors.New(errExponentOutOfRangeStr)
+	}
+	d.Exponent = int32(e)
+	return nil
+}
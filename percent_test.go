@@ -0,0 +1,88 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		s   string
+		r   string
+		err bool
+	}{
+		{s: "12.5%", r: "0.125"},
+		{s: "-100%", r: "-1.00"},
+		{s: "0%", r: "0.00"},
+		{s: "1E+2%", r: "1"},
+		{s: "12.5 %", r: "0.125"},
+		{s: "12.5‰", r: "0.0125"},
+		{s: "1000‰", r: "1.000"},
+		{s: "Infinity%", r: "Infinity"},
+		{s: "12.5", err: true},
+		{s: "%", err: true},
+		{s: "abc%", err: true},
+	}
+	for _, tc := range tests {
+		d, err := ParsePercent(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected error, got %s", tc.s, d)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.s, err)
+		}
+		if s := d.String(); s != tc.r {
+			t.Errorf("%s: expected %s, got %s", tc.s, tc.r, s)
+		}
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		d        string
+		percent  string
+		permille string
+	}{
+		{d: "0.125", percent: "12.5%", permille: "125‰"},
+		{d: "-1", percent: "-100%", permille: "-1000‰"},
+		{d: "0.00001", percent: "0.001%", permille: "0.01‰"},
+		{d: "1.000", percent: "100.0%", permille: "1000‰"},
+		{d: "NaN", percent: "NaN%", permille: "NaN‰"},
+	}
+	for _, tc := range tests {
+		d := newDecimal(t, testCtx, tc.d)
+		if s := FormatPercent(d); s != tc.percent {
+			t.Errorf("%s: expected %s, got %s", tc.d, tc.percent, s)
+		}
+		if s := FormatPermille(d); s != tc.permille {
+			t.Errorf("%s: expected %s, got %s", tc.d, tc.permille, s)
+		}
+		// Formatting and parsing round trips the value exactly.
+		if d.Form != Finite {
+			continue
+		}
+		if p, err := ParsePercent(FormatPercent(d)); err != nil || p.Cmp(d) != 0 {
+			t.Errorf("%s: round trip gave %s, %v", tc.d, p, err)
+		}
+	}
+	if s := FormatPercent(New(15, math.MaxInt32-1)); s != "1.5E+2147483649%" {
+		t.Errorf("unexpected %s", s)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+	"sync"
+)
+
+// Temporaries used inside Context operations are taken from these pools
+// instead of being allocated on every call. A Context carries no scratch
+// space of its own: Contexts are small values that are routinely copied
+// (see WithPrecision) and shared between goroutines, so the pools are
+// package-level. A caller that reuses its destination Decimal on a single
+// goroutine will then see no per-call allocations once the pools are warm.
+var (
+	bigIntPool  = sync.Pool{New: func() interface{} { return new(big.Int) }}
+	decimalPool = sync.Pool{New: func() interface{} { return new(Decimal) }}
+	bytesPool   = sync.Pool{New: func() interface{} { return new([]byte) }}
+)
+
+// getBigInt returns a big.Int from the pool. Its value is unspecified.
+func getBigInt() *big.Int {
+	return bigIntPool.Get().(*big.Int)
+}
+
+// putBigInt returns bs to the pool. They must not be used afterward.
+func putBigInt(bs ...*big.Int) {
+	for _, b := range bs {
+		bigIntPool.Put(b)
+	}
+}
+
+// getDecimal returns a finite zero Decimal from the pool.
+func getDecimal() *Decimal {
+	d := decimalPool.Get().(*Decimal)
+	d.SetFinite(0, 0)
+	return d
+}
+
+// putDecimal returns ds to the pool. They must not be used afterward.
+func putDecimal(ds ...*Decimal) {
+	for _, d := range ds {
+		decimalPool.Put(d)
+	}
+}
+
+// getBytes returns an empty byte slice from the pool.
+func getBytes() *[]byte {
+	b := bytesPool.Get().(*[]byte)
+	*b = (*b)[:0]
+	return b
+}
+
+// putBytes returns b to the pool. It must not be used afterward.
+func putBytes(b *[]byte) {
+	bytesPool.Put(b)
+}
+
+// Allocator supplies temporary Decimals to Context operations in place of
+// the package's pool. It lets precision-heavy batch jobs, whose Exp, Ln and
+// Pow calls create many large intermediate values, manage that memory
+// themselves, for example with a free list owned by one goroutine, or an
+// arena that is reset after each batch, instead of leaving it to the
+// garbage collector. See Context.Allocator.
+type Allocator interface {
+	// NewDecimal returns a Decimal for use as a temporary. Its value is
+	// unspecified; the operation sets it before use.
+	NewDecimal() *Decimal
+	// Release is called with each Decimal returned by NewDecimal when the
+	// operation that requested it returns. The operation does not use d
+	// afterward, and no result of the operation shares storage with it.
+	Release(d *Decimal)
+}
+
+// getDecimal returns a finite zero Decimal from c.Allocator, or from the
+// pool if it is nil.
+func (c *Context) getDecimal() *Decimal {
+	if c.Allocator == nil {
+		return getDecimal()
+	}
+	return c.Allocator.NewDecimal().SetFinite(0, 0)
+}
+
+// putDecimal releases ds, which were returned by c.getDecimal.
+func (c *Context) putDecimal(ds ...*Decimal) {
+	if c.Allocator == nil {
+		putDecimal(ds...)
+		return
+	}
+	for _, d := range ds {
+		c.Allocator.Release(d)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestGetDecimal(t *testing.T) {
+	d := getDecimal()
+	d.Set(decimalNaN)
+	d.Negative = true
+	putDecimal(d)
+	for i := 0; i < 10; i++ {
+		d := getDecimal()
+		if d.Form != Finite || d.Negative || d.Exponent != 0 || d.Coeff.Sign() != 0 {
+			t.Fatalf("expected finite zero, got %s", d)
+		}
+		putDecimal(d)
+	}
+}
+
+func TestPoolAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool is unreliable under the race detector")
+	}
+	c := BaseContext.WithPrecision(34)
+	x := New(12345, -2)
+	y := New(678, -1)
+	five := New(5, 0)
+	d := new(Decimal)
+	for _, tc := range []struct {
+		name string
+		f    func()
+	}{
+		{"quo", func() { _, _ = c.Quo(d, x, y) }},
+		{"quo exact", func() { _, _ = c.Quo(d, x, five) }},
+		{"quo in place", func() { d.Set(x); _, _ = c.Quo(d, d, y) }},
+	} {
+		if n := testing.AllocsPerRun(100, tc.f); n != 0 {
+			t.Errorf("%s: %v allocations", tc.name, n)
+		}
+	}
+}
+
+// freeList is an Allocator that keeps released Decimals for reuse and
+// counts the ones it has handed out.
+type freeList struct {
+	free        []*Decimal
+	allocated   int
+	outstanding int
+}
+
+func (l *freeList) NewDecimal() *Decimal {
+	l.outstanding++
+	if n := len(l.free); n > 0 {
+		d := l.free[n-1]
+		l.free = l.free[:n-1]
+		return d
+	}
+	l.allocated++
+	d := new(Decimal)
+	// Temporaries must not rely on the value they are handed out with.
+	d.Set(decimalNaN)
+	return d
+}
+
+func (l *freeList) Release(d *Decimal) {
+	l.outstanding--
+	l.free = append(l.free, d)
+}
+
+func TestAllocator(t *testing.T) {
+	x := New(17, -1)
+	y := New(25, -1)
+	ops := []struct {
+		name string
+		f    func(c *Context, d *Decimal) (Condition, error)
+	}{
+		{"exp", func(c *Context, d *Decimal) (Condition, error) { return c.Exp(d, x) }},
+		{"ln", func(c *Context, d *Decimal) (Condition, error) { return c.Ln(d, x) }},
+		{"ln agm", func(c *Context, d *Decimal) (Condition, error) {
+			return c.WithPrecision(60).Ln(d, New(12345, 0))
+		}},
+		{"pow", func(c *Context, d *Decimal) (Condition, error) { return c.Pow(d, x, y) }},
+		{"pow integer", func(c *Context, d *Decimal) (Condition, error) { return c.Pow(d, x, New(-7, 0)) }},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			expect := new(Decimal)
+			if _, err := op.f(BaseContext.WithPrecision(30), expect); err != nil {
+				t.Fatal(err)
+			}
+			l := new(freeList)
+			c := BaseContext.WithPrecision(30)
+			c.Allocator = l
+			for i := 0; i < 3; i++ {
+				d := new(Decimal)
+				if _, err := op.f(c, d); err != nil {
+					t.Fatal(err)
+				}
+				if d.CmpTotal(expect) != 0 {
+					t.Fatalf("expected %s, got %s", expect, d)
+				}
+				if l.outstanding != 0 {
+					t.Fatalf("%d temporaries not released", l.outstanding)
+				}
+			}
+			if l.allocated == 0 || l.allocated != len(l.free) {
+				t.Fatalf("allocated %d, free %d", l.allocated, len(l.free))
+			}
+		})
+	}
+}
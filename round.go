@@ -16,6 +16,8 @@ package apd
 
 import (
 	"math/big"
+
+	"github.com/pkg/errors"
 )
 
 // Round sets d to rounded x, rounded to the precision specified by c. If c
@@ -30,11 +32,50 @@ func (c *Context) round(d, x *Decimal) Condition {
 		d.Set(x)
 		return d.setExponent(c, 0, int64(d.Exponent))
 	}
+	if c.representsExactly(x) {
+		d.Set(x)
+		return 0
+	}
 	rounder := c.rounding()
 	res := rounder.Round(c, d, x)
 	return res
 }
 
+// roundResult sets d to x, rounded with c if x is finite, and returns res
+// with the conditions of the rounding added, checked against c.Traps. It
+// finishes operations that compute an exact or NaN result first and
+// round it once.
+func (c *Context) roundResult(d, x *Decimal, res Condition) (Condition, error) {
+	if x.Form == Finite {
+		res |= c.round(d, x)
+	} else {
+		d.Set(x)
+	}
+	return c.goError(res)
+}
+
+// representsExactly reports whether x is finite and fits c as it is: it has
+// at most c.Precision digits and its exponents are within c's and the
+// package's limits, and would not be clamped. Rounding such an x changes
+// nothing and raises no conditions, so round skips the Rounder entirely.
+// c.Precision must not be 0.
+func (c *Context) representsExactly(x *Decimal) bool {
+	if x.Form != Finite {
+		return false
+	}
+	nd := x.NumDigits()
+	if nd > int64(c.Precision) {
+		return false
+	}
+	e := int64(x.Exponent)
+	adj := e + nd - 1
+	if e < MinExponent || adj > MaxExponent ||
+		adj < int64(c.MinExponent) || adj > int64(c.MaxExponent) {
+		return false
+	}
+	return !c.Clamp || e <= int64(c.MaxExponent)-int64(c.Precision-1)
+}
+
 func (c *Context) rounding() Rounder {
 	rounding, ok := Roundings[c.Rounding]
 	if !ok {
@@ -43,6 +84,56 @@ func (c *Context) rounding() Rounder {
 	return rounding
 }
 
+// Bounds sets lo and hi to the result of op rounded toward -Infinity and
+// +Infinity, as if op had been run once with RoundFloor and once with
+// RoundCeiling, so that the exact result lies in [lo, hi]. op is called only
+// once, with a copy of c that rounds toward zero and has no traps: the
+// truncated result is one bound and, if op was Inexact, the other is one
+// unit in its last place further from zero. op must store its result in d
+// using the context it is passed, for example:
+//
+//	c.Bounds(lo, hi, func(c *Context, d *Decimal) (Condition, error) {
+//		return c.Quo(d, x, y)
+//	})
+//
+// The bounds are exact for operations that round their exact result to
+// precision, such as Add, Sub, Mul, Quo and Round. Functions that are
+// computed by approximation, such as Exp and Ln, are only bracketed as well
+// as they are computed. An exact zero sum is +0 in both bounds, where
+// RoundFloor would give -0. The conditions raised by op are returned.
+func (c *Context) Bounds(lo, hi *Decimal, op func(c *Context, d *Decimal) (Condition, error)) (Condition, error) {
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+	nc := c.workingContext(c.Precision)
+	nc.Rounding = RoundDown
+	nc.Traps = 0
+	z := new(Decimal)
+	res, err := op(nc, z)
+	if err != nil {
+		return res, err
+	}
+	lo.Set(z)
+	hi.Set(z)
+	if res.Inexact() && z.Form != NaN {
+		toward, away := hi, lo
+		if !z.Negative {
+			toward, away = lo, hi
+		}
+		if z.Form == Infinite {
+			// Overflow gives Infinity in every rounding mode, so the bound
+			// toward zero is the largest finite number.
+			toward.Coeff.Sub(tableExp10(int64(c.Precision), nil), bigOne)
+			toward.Exponent = c.MaxExponent - int32(c.Precision) + 1
+			toward.Form = Finite
+		} else {
+			away.Coeff.Add(&away.Coeff, bigOne)
+			nc.round(away, away)
+		}
+	}
+	return c.goError(res)
+}
+
 // Rounder defines a function that returns true if 1 should be added to the
 // absolute value of a number being rounded. result is the result to which
 // the 1 would be added. neg is true if the number is negative. half is -1
@@ -74,18 +165,19 @@ func (r Rounder) Round(c *Context, d, x *Decimal) Condition {
 			return SystemUnderflow | Underflow
 		}
 		res |= Rounded
-		y := new(big.Int)
+		y, m := getBigInt(), getBigInt()
 		e := tableExp10(diff, y)
-		m := new(big.Int)
 		y.QuoRem(&d.Coeff, e, m)
 		if m.Sign() != 0 {
 			res |= Inexact
-			discard := NewWithBigInt(m, int32(-diff))
-			if r(y, x.Negative, discard.Cmp(decimalHalf)) {
+			if r(y, x.Negative, discardHalf(m, e)) {
 				roundAddOne(y, &diff)
 			}
 		}
-		d.Coeff = *y
+		// Copy into d's existing storage so that a destination reused across
+		// calls keeps its capacity.
+		d.Coeff.Set(y)
+		putBigInt(y, m)
 	} else {
 		diff = 0
 	}
@@ -93,6 +185,13 @@ func (r Rounder) Round(c *Context, d, x *Decimal) Condition {
 	return res
 }
 
+// discardHalf compares the discarded digits m, scaled by e, a power of ten,
+// against half of e, and returns the half argument of a Rounder. m is
+// modified.
+func discardHalf(m, e *big.Int) int {
+	return m.Lsh(m, 1).CmpAbs(e)
+}
+
 // roundAddOne adds 1 to abs(b).
 func roundAddOne(b *big.Int, diff *int64) {
 	if b.Sign() < 0 {
@@ -123,6 +222,31 @@ var (
 	}
 )
 
+// RegisterRounder adds r to Roundings under name, so that a Context whose
+// Rounding is name uses r. It returns an error if name is empty or already
+// registered. Like modifying Roundings directly, RegisterRounder is not safe
+// during any other parallel Context operations; call it from an init
+// function.
+func RegisterRounder(name string, r Rounder) error {
+	if name == "" {
+		return errors.New("empty rounder name")
+	}
+	if r == nil {
+		return errors.Errorf("nil rounder %q", name)
+	}
+	if _, ok := Roundings[name]; ok {
+		return errors.Errorf("rounder %q already registered", name)
+	}
+	Roundings[name] = r
+	return nil
+}
+
+// LookupRounder returns the Rounder registered in Roundings under name.
+func LookupRounder(name string) (Rounder, bool) {
+	r, ok := Roundings[name]
+	return r, ok
+}
+
 const (
 	// RoundDown rounds toward 0; truncate.
 	RoundDown = "down"
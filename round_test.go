@@ -0,0 +1,222 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestRegisterRounder(t *testing.T) {
+	const name = "half_odd"
+	// Round half to odd.
+	halfOdd := func(result *big.Int, neg bool, half int) bool {
+		if half != 0 {
+			return half > 0
+		}
+		return result.Bit(0) == 0
+	}
+	if err := RegisterRounder(name, halfOdd); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Roundings, name)
+	if err := RegisterRounder(name, halfOdd); err == nil {
+		t.Error("expected error registering a duplicate")
+	}
+	if err := RegisterRounder("", halfOdd); err == nil {
+		t.Error("expected error registering an empty name")
+	}
+	if err := RegisterRounder(RoundHalfUp, halfOdd); err == nil {
+		t.Error("expected error replacing a built-in rounder")
+	}
+	if _, ok := LookupRounder(name); !ok {
+		t.Fatalf("%s not found", name)
+	}
+	if _, ok := LookupRounder("nonexistent"); ok {
+		t.Fatal("unexpected rounder")
+	}
+
+	c := BaseContext.WithPrecision(2)
+	c.Rounding = name
+	for s, expect := range map[string]string{
+		"1.25":  "1.3",
+		"1.35":  "1.3",
+		"-1.45": "-1.5",
+		"1.26":  "1.3",
+		"1.34":  "1.3",
+	} {
+		d := new(Decimal)
+		if _, err := c.Round(d, newDecimal(t, testCtx, s)); err != nil {
+			t.Fatal(err)
+		}
+		if d.String() != expect {
+			t.Errorf("%s: expected %s, got %s", s, expect, d)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	ops := []struct {
+		name string
+		f    func(c *Context, d, x, y *Decimal) (Condition, error)
+	}{
+		{"add", (*Context).Add},
+		{"sub", (*Context).Sub},
+		{"mul", (*Context).Mul},
+		{"quo", (*Context).Quo},
+	}
+	values := []string{"1", "-1", "3", "-7", "0.1", "9.9999", "-0.00005", "2E+5", "123.45678", "-0"}
+	c := BaseContext.WithPrecision(5)
+	c.Traps = 0
+	for _, op := range ops {
+		for _, xs := range values {
+			for _, ys := range values {
+				x := newDecimal(t, testCtx, xs)
+				y := newDecimal(t, testCtx, ys)
+				if y.IsZero() && op.name == "quo" {
+					continue
+				}
+				lo, hi := new(Decimal), new(Decimal)
+				res, err := c.Bounds(lo, hi, func(c *Context, d *Decimal) (Condition, error) {
+					return op.f(c, d, x, y)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, bound := range []struct {
+					rounding string
+					d        *Decimal
+				}{
+					{RoundFloor, lo},
+					{RoundCeiling, hi},
+				} {
+					nc := *c
+					nc.Rounding = bound.rounding
+					expect := new(Decimal)
+					eres, err := op.f(&nc, expect, x, y)
+					if err != nil {
+						t.Fatal(err)
+					}
+					// The sign of an exact zero sum depends on the rounding
+					// mode, which Bounds does not reproduce.
+					if expect.CmpTotal(bound.d) != 0 && !(expect.IsZero() && expect.Cmp(bound.d) == 0) {
+						t.Errorf("%s(%s, %s) %s: expected %s, got %s", op.name, xs, ys, bound.rounding, expect, bound.d)
+					}
+					if eres.Inexact() != res.Inexact() {
+						t.Errorf("%s(%s, %s): expected %s, got %s", op.name, xs, ys, eres, res)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBoundsOverflow(t *testing.T) {
+	c := BaseContext.WithPrecision(3)
+	c.MaxExponent = 10
+	c.Traps = 0
+	x := newDecimal(t, testCtx, "9E+10")
+	for _, tc := range []struct {
+		y      string
+		lo, hi string
+	}{
+		{y: "10", lo: "9.99E+10", hi: "Infinity"},
+		{y: "-10", lo: "-Infinity", hi: "-9.99E+10"},
+		{y: "0.5", lo: "4.5E+10", hi: "4.5E+10"},
+	} {
+		lo, hi := new(Decimal), new(Decimal)
+		y := newDecimal(t, testCtx, tc.y)
+		if _, err := c.Bounds(lo, hi, func(c *Context, d *Decimal) (Condition, error) {
+			return c.Mul(d, x, y)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if lo.String() != tc.lo || hi.String() != tc.hi {
+			t.Errorf("%s: expected [%s, %s], got [%s, %s]", tc.y, tc.lo, tc.hi, lo, hi)
+		}
+	}
+}
+
+func TestRoundSubnormalSign(t *testing.T) {
+	for _, tc := range []struct {
+		rounding string
+		x, r     string
+	}{
+		{rounding: RoundFloor, x: "-1.234E-7", r: "-2E-7"},
+		{rounding: RoundFloor, x: "1.234E-7", r: "1E-7"},
+		{rounding: RoundCeiling, x: "-1.234E-7", r: "-1E-7"},
+		{rounding: RoundCeiling, x: "1.234E-7", r: "2E-7"},
+	} {
+		c := &Context{Precision: 3, MinExponent: -5, MaxExponent: 5, Rounding: tc.rounding}
+		d := new(Decimal)
+		if _, err := c.Round(d, newDecimal(t, testCtx, tc.x)); err != nil {
+			t.Fatal(err)
+		}
+		if s := d.String(); s != tc.r {
+			t.Errorf("%s %s: expected %s, got %s", tc.rounding, tc.x, tc.r, s)
+		}
+	}
+}
+
+func TestRoundAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool is unreliable under the race detector")
+	}
+	c := BaseContext.WithPrecision(10)
+	sub := &Context{Precision: 3, MinExponent: -5, MaxExponent: 5, Rounding: RoundHalfEven}
+	x := newDecimal(t, testCtx, "1234567890.123456789")
+	y := newDecimal(t, testCtx, "1.2345E-7")
+	d := new(Decimal)
+	for _, tc := range []struct {
+		name string
+		f    func()
+	}{
+		{"round", func() { _, _ = c.Round(d, x) }},
+		{"round in place", func() { d.Set(x); _, _ = c.Round(d, d) }},
+		{"subnormal", func() { _, _ = sub.Round(d, y) }},
+	} {
+		if n := testing.AllocsPerRun(100, tc.f); n != 0 {
+			t.Errorf("%s: %v allocations", tc.name, n)
+		}
+	}
+}
+
+// TestRepresentsExactly checks that the exact-result fast path of round
+// agrees with the Rounder on values near every limit it checks.
+func TestRepresentsExactly(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	coeffs := []int64{0, 1, 9, 10, 99, 12345, 99999, 100000}
+	exps := []int32{0, 1, -1, 94, 95, 96, 97, -95, -96, -99, -100, -101, 5, -5}
+	for i := 0; i < 20000; i++ {
+		c := &Context{
+			Precision:   uint32(rng.Intn(6) + 1),
+			MaxExponent: 96,
+			MinExponent: -95,
+			Clamp:       rng.Intn(2) == 0,
+			Rounding:    RoundHalfEven,
+		}
+		x := New(coeffs[rng.Intn(len(coeffs))], exps[rng.Intn(len(exps))])
+		x.Negative = rng.Intn(2) == 0
+		if !c.representsExactly(x) {
+			continue
+		}
+		d := new(Decimal)
+		res := c.rounding().Round(c, d, x)
+		if res != 0 || d.CmpTotal(x) != 0 {
+			t.Fatalf("%s at precision %d, clamp %v: rounds to %s (%s)", x, c.Precision, c.Clamp, d, res)
+		}
+	}
+}
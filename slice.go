@@ -0,0 +1,140 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "github.com/pkg/errors"
+
+// The batch operations below process slices of Decimals, as found in
+// columnar data, with one Context. They share their scratch space between
+// elements and check the accumulated conditions against c.Traps once, after
+// every element has been computed, rather than once per element. Elements
+// that raise a trapped condition therefore still hold the result the
+// operation specifies for them, as if the condition had not been trapped.
+
+// errSliceLength is returned by batch operations whose slices differ in
+// length.
+var errSliceLength = errors.New("slices must have the same length")
+
+// batchContext returns a copy of c that neither traps nor records flags,
+// so that the conditions of a batch can be reported together by c.
+func (c *Context) batchContext() *Context {
+	nc := *c
+	nc.Traps = 0
+	nc.Flags = nil
+	return &nc
+}
+
+// AddSlices sets dst[i] to x[i]+y[i] for each i. The slices must have the
+// same length. dst may be the same slice as x or y.
+func (c *Context) AddSlices(dst, x, y []Decimal) (Condition, error) {
+	if len(dst) != len(x) || len(dst) != len(y) {
+		return 0, errSliceLength
+	}
+	nc := c.batchContext()
+	tmp := getBigInt()
+	defer putBigInt(tmp)
+	var res Condition
+	for i := range dst {
+		r, err := nc.addTmp(&dst[i], &x[i], &y[i], false, tmp)
+		if err != nil {
+			return 0, errors.Wrapf(err, "element %d", i)
+		}
+		res |= r
+	}
+	return c.goError(res)
+}
+
+// ScaleSlice sets dst[i] to x[i]*f for each i. The slices must have the same
+// length. dst may be the same slice as x.
+func (c *Context) ScaleSlice(dst, x []Decimal, f *Decimal) (Condition, error) {
+	if len(dst) != len(x) {
+		return 0, errSliceLength
+	}
+	nc := c.batchContext()
+	var res Condition
+	for i := range dst {
+		r, err := nc.Mul(&dst[i], &x[i], f)
+		if err != nil {
+			return 0, errors.Wrapf(err, "element %d", i)
+		}
+		res |= r
+	}
+	return c.goError(res)
+}
+
+// SumSlice sets d to the sum of the elements of x, or to 0 if x is empty.
+// The sum is computed exactly and rounded once, so it is the correctly
+// rounded result, which a loop of Add calls at c's precision need not be.
+// MaxDigits bounds the exact sum as it does the operands of Add.
+func (c *Context) SumSlice(d *Decimal, x []Decimal) (Condition, error) {
+	return c.sumRounded(d, len(x), func(i int) *Decimal { return &x[i] })
+}
+
+// sumRounded sets d to the exact sum of elem(i) for i in [0, n), rounded
+// once with c.
+func (c *Context) sumRounded(d *Decimal, n int, elem func(i int) *Decimal) (Condition, error) {
+	sum := getDecimal()
+	defer putDecimal(sum)
+	res, err := c.sumExact(sum, n, elem)
+	if err != nil {
+		return 0, err
+	}
+	return c.roundResult(d, sum, res)
+}
+
+// sumExact sets sum to the exact sum of elem(i) for i in [0, n), or to 0 if
+// n is 0. It returns the conditions raised by the additions without
+// checking them against c.Traps. An error is returned if an element is nil.
+func (c *Context) sumExact(sum *Decimal, n int, elem func(i int) *Decimal) (Condition, error) {
+	sum.SetFinite(0, 0)
+	if n == 0 {
+		return 0, nil
+	}
+	// Accumulate without rounding. The package's exponent limits apply to
+	// the partial sums; c's limits apply to the result when it is rounded.
+	nc := c.batchContext()
+	nc.Precision = 0
+	nc.MaxExponent = MaxExponent
+	nc.MinExponent = MinExponent
+	nc.Clamp = false
+	tmp := getBigInt()
+	defer putBigInt(tmp)
+	x := elem(0)
+	if x == nil {
+		return 0, errNilElement(0)
+	}
+	// setIfNaN quiets a signaling NaN, as Add does for the other elements.
+	set, res, _ := nc.setIfNaN(sum, x)
+	if !set {
+		sum.Set(x)
+	}
+	for i := 1; i < n; i++ {
+		x := elem(i)
+		if x == nil {
+			return 0, errNilElement(i)
+		}
+		r, err := nc.addTmp(sum, sum, x, false, tmp)
+		if err != nil {
+			return 0, errors.Wrapf(err, "element %d", i)
+		}
+		res |= r
+	}
+	return res, nil
+}
+
+// errNilElement returns the error for a nil element i of a slice.
+func errNilElement(i int) error {
+	return errors.Errorf("element %d is nil", i)
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func decimalSlice(t *testing.T, ss ...string) []Decimal {
+	t.Helper()
+	ds := make([]Decimal, len(ss))
+	for i, s := range ss {
+		ds[i].Set(newDecimal(t, testCtx, s))
+	}
+	return ds
+}
+
+func TestAddSlices(t *testing.T) {
+	x := decimalSlice(t, "1", "1.5", "-2E+3", "9.99", "NaN", "Infinity")
+	y := decimalSlice(t, "2", "0.25", "1", "0.01", "1", "-1E+10")
+	c := BaseContext.WithPrecision(3)
+	c.Traps = 0
+	dst := make([]Decimal, len(x))
+	res, err := c.AddSlices(dst, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expectRes Condition
+	for i := range x {
+		expect := new(Decimal)
+		r, err := c.Add(expect, &x[i], &y[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectRes |= r
+		if dst[i].CmpTotal(expect) != 0 {
+			t.Errorf("%d: expected %s, got %s", i, expect, &dst[i])
+		}
+	}
+	if res != expectRes {
+		t.Errorf("expected %s, got %s", expectRes, res)
+	}
+
+	// In place, with a trapped condition raised by one element.
+	c.Traps = Inexact
+	if _, err := c.AddSlices(x, x, y); err == nil {
+		t.Fatal("expected error")
+	}
+	if s := x[3].String(); s != "10.0" {
+		t.Errorf("expected 10.0, got %s", s)
+	}
+	if _, err := c.AddSlices(dst, x, y[1:]); err != errSliceLength {
+		t.Fatalf("expected %v, got %v", errSliceLength, err)
+	}
+}
+
+func TestScaleSlice(t *testing.T) {
+	x := decimalSlice(t, "1", "-1.5", "0", "123.45")
+	f := newDecimal(t, testCtx, "2.5")
+	c := BaseContext.WithPrecision(4)
+	res, err := c.ScaleSlice(x, x, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expect := range []string{"2.5", "-3.75", "0.0", "308.6"} {
+		if s := x[i].String(); s != expect {
+			t.Errorf("%d: expected %s, got %s", i, expect, s)
+		}
+	}
+	if res != Inexact|Rounded {
+		t.Errorf("expected Inexact|Rounded, got %s", res)
+	}
+}
+
+func TestSumSlice(t *testing.T) {
+	tests := []struct {
+		x      []string
+		prec   uint32
+		expect string
+		flags  Condition
+	}{
+		{x: nil, expect: "0"},
+		{x: []string{"1.5"}, expect: "1.5"},
+		{x: []string{"1", "2.50", "-0.5"}, expect: "3.00"},
+		{x: []string{"1E+3", "2E+3"}, expect: "3E+3"},
+		{x: []string{"-0", "-0"}, expect: "-0"},
+		// A loop of Add at precision 3 would give 1.00.
+		{x: []string{"1", "0.004", "0.004"}, prec: 3, expect: "1.01", flags: Inexact | Rounded},
+		{x: []string{"1E+10", "1", "-1E+10"}, prec: 3, expect: "1"},
+		{x: []string{"1", "Infinity", "2"}, expect: "Infinity"},
+		{x: []string{"Infinity", "-Infinity"}, expect: "NaN", flags: InvalidOperation},
+		{x: []string{"sNaN"}, expect: "NaN", flags: InvalidOperation},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.x), func(t *testing.T) {
+			c := BaseContext.WithPrecision(tc.prec)
+			c.Traps = 0
+			d := new(Decimal)
+			res, err := c.SumSlice(d, decimalSlice(t, tc.x...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.expect {
+				t.Errorf("expected %s, got %s", tc.expect, s)
+			}
+			if res != tc.flags {
+				t.Errorf("expected %s, got %s", tc.flags, res)
+			}
+		})
+	}
+}
+
+func TestSliceAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	x := decimalSlice(t, "1.25", "12345678901234567890123", "3E+5", "-7")
+	y := decimalSlice(t, "2E+30", "0.5", "1", "7")
+	dst := make([]Decimal, len(x))
+	sum := new(Decimal)
+	c := BaseContext.WithPrecision(20)
+	c.Traps = 0
+	// Warm up the destinations and the pools.
+	_, _ = c.AddSlices(dst, x, y)
+	_, _ = c.SumSlice(sum, x)
+	if allocs := testing.AllocsPerRun(100, func() {
+		_, _ = c.AddSlices(dst, x, y)
+	}); allocs != 0 {
+		t.Errorf("AddSlices: expected no allocations, got %v", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		_, _ = c.SumSlice(sum, x)
+	}); allocs != 0 {
+		t.Errorf("SumSlice: expected no allocations, got %v", allocs)
+	}
+}
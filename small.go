@@ -0,0 +1,191 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "math/bits"
+
+// The functions in this file are fast paths for the common case of
+// coefficients that fit in a uint64. They compute with machine words and
+// store only the result in the destination's big.Int, which reuses its
+// existing storage, so they do not allocate when the destination has been
+// used before.
+
+// pow10Uint64 holds the powers of ten that fit in a uint64.
+var pow10Uint64 = [...]uint64{
+	1,
+	10,
+	100,
+	1000,
+	10000,
+	100000,
+	1000000,
+	10000000,
+	100000000,
+	1000000000,
+	10000000000,
+	100000000000,
+	1000000000000,
+	10000000000000,
+	100000000000000,
+	1000000000000000,
+	10000000000000000,
+	100000000000000000,
+	1000000000000000000,
+	10000000000000000000,
+}
+
+// scaleUint64 returns v * 10**s and whether it fits in a uint64.
+func scaleUint64(v uint64, s int64) (uint64, bool) {
+	if s >= int64(len(pow10Uint64)) {
+		return 0, v == 0
+	}
+	hi, lo := bits.Mul64(v, pow10Uint64[s])
+	return lo, hi == 0
+}
+
+// alignUint64 returns the coefficients of the finite x and y scaled to their
+// smaller exponent, and that exponent, if both fit in a uint64.
+func alignUint64(x, y *Decimal) (a, b uint64, exp int32, ok bool) {
+	if !x.Coeff.IsUint64() || !y.Coeff.IsUint64() {
+		return 0, 0, 0, false
+	}
+	a, b = x.Coeff.Uint64(), y.Coeff.Uint64()
+	switch {
+	case x.Exponent > y.Exponent:
+		a, ok = scaleUint64(a, int64(x.Exponent)-int64(y.Exponent))
+		return a, b, y.Exponent, ok
+	case x.Exponent < y.Exponent:
+		b, ok = scaleUint64(b, int64(y.Exponent)-int64(x.Exponent))
+		return a, b, x.Exponent, ok
+	}
+	return a, b, x.Exponent, true
+}
+
+// addUint64 sets d to the sum of the finite x and y, with y negated if
+// subtract is true, before rounding. It returns false, leaving d unchanged,
+// if the aligned coefficients or their sum do not fit in a uint64.
+func (c *Context) addUint64(d, x, y *Decimal, subtract bool) bool {
+	a, b, exp, ok := alignUint64(x, y)
+	if !ok {
+		return false
+	}
+	xn := x.Negative
+	yn := y.Negative != subtract
+	neg := xn
+	if xn == yn {
+		var carry uint64
+		if a, carry = bits.Add64(a, b, 0); carry != 0 {
+			return false
+		}
+	} else if a >= b {
+		a -= b
+		if a == 0 {
+			neg = c.Rounding == RoundFloor
+		}
+	} else {
+		a = b - a
+		neg = yn
+	}
+	d.Coeff.SetUint64(a)
+	d.Negative = neg
+	d.Exponent = exp
+	d.Form = Finite
+	return true
+}
+
+// mulUint64 sets the coefficient of d to the product of the coefficients of
+// x and y. It returns false, leaving d unchanged, if the product does not fit
+// in a uint64.
+func mulUint64(d, x, y *Decimal) bool {
+	if !x.Coeff.IsUint64() || !y.Coeff.IsUint64() {
+		return false
+	}
+	hi, lo := bits.Mul64(x.Coeff.Uint64(), y.Coeff.Uint64())
+	if hi != 0 {
+		return false
+	}
+	d.Coeff.SetUint64(lo)
+	return true
+}
+
+// cmpAbsUint64 compares the absolute values of the finite x and y. It
+// returns false if their aligned coefficients do not fit in a uint64.
+func cmpAbsUint64(x, y *Decimal) (int, bool) {
+	a, b, _, ok := alignUint64(x, y)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	}
+	return 0, true
+}
+
+// quantizeUint64 sets d to the finite v rescaled to exponent exp, as quantize
+// does, using a power of ten from pow10Uint64 as the divisor. It returns
+// false, leaving d unchanged, if v's coefficient or the result does not fit
+// in a uint64, or if every digit of a nonzero v would be discarded.
+func (c *Context) quantizeUint64(d, v *Decimal, exp int32) (Condition, bool) {
+	if !v.Coeff.IsUint64() {
+		return 0, false
+	}
+	a := v.Coeff.Uint64()
+	neg := v.Negative
+	diff := int64(exp) - int64(v.Exponent)
+	var res Condition
+	switch {
+	case diff < 0:
+		var ok bool
+		if a, ok = scaleUint64(a, -diff); !ok {
+			return 0, false
+		}
+	case diff > 0:
+		if a == 0 {
+			// No digits are discarded from a zero, so it is not Rounded.
+			break
+		}
+		if diff >= int64(len(pow10Uint64)) {
+			return 0, false
+		}
+		if v.NumDigits() < diff {
+			return 0, false
+		}
+		res = Rounded
+		p := pow10Uint64[diff]
+		q, r := a/p, a%p
+		if r != 0 {
+			res |= Inexact
+			half := 1
+			if r < p-r {
+				half = -1
+			} else if r == p-r {
+				half = 0
+			}
+			d.Coeff.SetUint64(q)
+			if c.rounding()(&d.Coeff, neg, half) {
+				q++
+			}
+		}
+		a = q
+	}
+	d.Coeff.SetUint64(a)
+	d.Negative = neg
+	d.Exponent = exp
+	d.Form = Finite
+	return res, true
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// TestSmallFastPath compares the uint64 fast paths with the same operations
+// done on big.Ints, for coefficients on both sides of the uint64 limit.
+func TestSmallFastPath(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	coeffs := []uint64{0, 1, 9, 10, 12345, math.MaxUint32, 1e18, 1e19 - 1, 1e19, math.MaxUint64 / 10, math.MaxUint64 - 1, math.MaxUint64}
+	random := func() *Decimal {
+		d := new(Decimal)
+		if rng.Intn(3) == 0 {
+			d.Coeff.SetUint64(rng.Uint64() >> uint(rng.Intn(64)))
+		} else {
+			d.Coeff.SetUint64(coeffs[rng.Intn(len(coeffs))])
+		}
+		d.Exponent = int32(rng.Intn(41) - 20)
+		d.Negative = rng.Intn(2) == 0
+		return d
+	}
+	// exact returns x as a Rat.
+	exact := func(x *Decimal) *big.Rat {
+		r, err := x.Rat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	c := BaseContext.WithPrecision(0)
+	for i := 0; i < 10000; i++ {
+		x, y := random(), random()
+		xr, yr := exact(x), exact(y)
+
+		d := new(Decimal)
+		if _, err := c.Add(d, x, y); err != nil {
+			t.Fatal(err)
+		}
+		if e := new(big.Rat).Add(xr, yr); exact(d).Cmp(e) != 0 {
+			t.Fatalf("%s + %s: expected %s, got %s", x, y, e.FloatString(20), d)
+		}
+		if _, err := c.Sub(d, x, y); err != nil {
+			t.Fatal(err)
+		}
+		if e := new(big.Rat).Sub(xr, yr); exact(d).Cmp(e) != 0 {
+			t.Fatalf("%s - %s: expected %s, got %s", x, y, e.FloatString(20), d)
+		}
+		if _, err := c.Mul(d, x, y); err != nil {
+			t.Fatal(err)
+		}
+		if e := new(big.Rat).Mul(xr, yr); exact(d).Cmp(e) != 0 {
+			t.Fatalf("%s * %s: expected %s, got %s", x, y, e.FloatString(20), d)
+		}
+		if cmp, e := x.Cmp(y), xr.Cmp(yr); cmp != e {
+			t.Fatalf("cmp(%s, %s): expected %d, got %d", x, y, e, cmp)
+		}
+	}
+}
+
+func TestQuantizeUint64(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	coeffs := []uint64{0, 1, 5, 9, 15, 25, 12345, 1e18, 1e19 - 1, math.MaxUint64}
+	c := BaseContext.WithPrecision(0)
+	for i := 0; i < 10000; i++ {
+		v := new(Decimal)
+		if rng.Intn(2) == 0 {
+			v.Coeff.SetUint64(rng.Uint64() >> uint(rng.Intn(64)))
+		} else {
+			v.Coeff.SetUint64(coeffs[rng.Intn(len(coeffs))])
+		}
+		v.Exponent = int32(rng.Intn(41) - 20)
+		v.Negative = rng.Intn(2) == 0
+		exp := v.Exponent + int32(rng.Intn(41)-20)
+		c.Rounding = []string{RoundDown, RoundHalfUp, RoundHalfEven, RoundCeiling, RoundFloor, RoundHalfDown, RoundUp, Round05Up}[rng.Intn(8)]
+
+		d := new(Decimal)
+		res, ok := c.quantizeUint64(d, v, exp)
+		if !ok {
+			continue
+		}
+		// Pad the coefficient past a uint64 to take the general path.
+		padded := new(Decimal).Set(v)
+		padded.Coeff.Mul(&padded.Coeff, tableExp10(20, nil))
+		padded.Exponent -= 20
+		expect := new(Decimal)
+		eres := c.quantize(expect, padded, exp)
+		if exp <= v.Exponent {
+			// Padding changes which digits are discarded.
+			res &^= Rounded
+			eres &^= Rounded
+		}
+		if d.CmpTotal(expect) != 0 || res != eres {
+			t.Fatalf("%s %s to %d: expected %s (%s), got %s (%s)", c.Rounding, v, exp, expect, eres, d, res)
+		}
+	}
+}
+
+func TestSmallAllocs(t *testing.T) {
+	c := BaseContext.WithPrecision(34)
+	x := New(12345, -2)
+	y := New(678, -1)
+	z := New(123450, -3)
+	d := New(1, 0)
+	for _, tc := range []struct {
+		name string
+		f    func()
+	}{
+		{"add", func() { _, _ = c.Add(d, x, y) }},
+		{"sub", func() { _, _ = c.Sub(d, x, y) }},
+		{"mul", func() { _, _ = c.Mul(d, x, y) }},
+		{"mul in place", func() { d.Set(x); _, _ = c.Mul(d, d, y) }},
+		{"cmp", func() { _ = x.Cmp(z) }},
+		{"quantize", func() { _, _ = c.Quantize(d, x, -5) }},
+		{"quantize round", func() { _, _ = c.Quantize(d, x, 0) }},
+	} {
+		if n := testing.AllocsPerRun(100, tc.f); n != 0 {
+			t.Errorf("%s: %v allocations", tc.name, n)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "sort"
+
+// Compare returns -1, 0 or +1 as a is less than, equal to or greater than b.
+// It has the signature expected by slices.SortFunc and, unlike Cmp, is a
+// consistent order for any values: nil is less than any Decimal, a NaN is
+// less than any number and equal to any other NaN, and numbers are
+// compared by value as by Cmp, so that 1.0 and 1.00 are equal.
+func Compare(a, b *Decimal) int {
+	if a == nil || b == nil {
+		return compareNil(a, b)
+	}
+	aNaN := a.Form == NaN || a.Form == NaNSignaling
+	bNaN := b.Form == NaN || b.Form == NaNSignaling
+	switch {
+	case aNaN && bNaN:
+		return 0
+	case aNaN:
+		return -1
+	case bNaN:
+		return 1
+	}
+	return a.Cmp(b)
+}
+
+// CompareTotal is like Compare but orders decimals with CmpTotal, which
+// distinguishes 1.0 from 1.00 and orders NaNs by sign and form. nil is less
+// than any Decimal.
+func CompareTotal(a, b *Decimal) int {
+	if a == nil || b == nil {
+		return compareNil(a, b)
+	}
+	return a.CmpTotal(b)
+}
+
+// compareNil compares a and b, at least one of which is nil.
+func compareNil(a, b *Decimal) int {
+	switch {
+	case a == b:
+		return 0
+	case a == nil:
+		return -1
+	}
+	return 1
+}
+
+// byCompare implements sort.Interface with Compare.
+type byCompare []*Decimal
+
+func (x byCompare) Len() int           { return len(x) }
+func (x byCompare) Less(i, j int) bool { return Compare(x[i], x[j]) < 0 }
+func (x byCompare) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+
+// Sort sorts x in increasing order as defined by Compare. The order of
+// equal values, such as 1.0 and 1.00, is not specified.
+func Sort(x []*Decimal) {
+	sort.Sort(byCompare(x))
+}
+
+// IsSorted reports whether x is sorted in increasing order as defined by
+// Compare.
+func IsSorted(x []*Decimal) bool {
+	return sort.IsSorted(byCompare(x))
+}
+
+// SearchDecimals searches for d in x, which must be sorted as by Sort, and
+// returns the index of the first element not less than d as defined by
+// Compare. The result is len(x) if every element is less than d.
+func SearchDecimals(x []*Decimal, d *Decimal) int {
+	return sort.Search(len(x), func(i int) bool { return Compare(x[i], d) >= 0 })
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	// Values in increasing order by Compare; those in one group are equal.
+	groups := [][]string{
+		{"nil"},
+		{"NaN", "-sNaN", "-NaN"},
+		{"-Infinity"},
+		{"-2.5"},
+		{"-0", "0", "0.00"},
+		{"1", "1.0", "1.00"},
+		{"1E+100"},
+		{"Infinity"},
+	}
+	parse := func(s string) *Decimal {
+		if s == "nil" {
+			return nil
+		}
+		return newDecimal(t, testCtx, s)
+	}
+	for i, gi := range groups {
+		for j, gj := range groups {
+			expect := 0
+			if i < j {
+				expect = -1
+			} else if i > j {
+				expect = 1
+			}
+			for _, a := range gi {
+				for _, b := range gj {
+					if c := Compare(parse(a), parse(b)); c != expect {
+						t.Errorf("Compare(%s, %s): expected %d, got %d", a, b, expect, c)
+					}
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		a, b   string
+		expect int
+	}{
+		{a: "nil", b: "nil", expect: 0},
+		{a: "nil", b: "-NaN", expect: -1},
+		{a: "-NaN", b: "nil", expect: 1},
+		{a: "1.0", b: "1.00", expect: 1},
+		{a: "-0", b: "0", expect: -1},
+		{a: "NaN", b: "Infinity", expect: 1},
+		{a: "-NaN", b: "-Infinity", expect: -1},
+	}
+	for _, tc := range tests {
+		if c := CompareTotal(parse(tc.a), parse(tc.b)); c != tc.expect {
+			t.Errorf("CompareTotal(%s, %s): expected %d, got %d", tc.a, tc.b, tc.expect, c)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	var x []*Decimal
+	for _, s := range []string{"3", "-Infinity", "NaN", "0.5", "-1", "1E+2", "Infinity", "0"} {
+		x = append(x, newDecimal(t, testCtx, s))
+	}
+	x = append(x, nil)
+	if IsSorted(x) {
+		t.Fatal("expected unsorted")
+	}
+	Sort(x)
+	if !IsSorted(x) {
+		t.Fatal("expected sorted")
+	}
+	var got []string
+	for _, d := range x {
+		got = append(got, d.String())
+	}
+	expect := []string{"<nil>", "NaN", "-Infinity", "-1", "0", "0.5", "3", "1E+2", "Infinity"}
+	if len(got) != len(expect) {
+		t.Fatalf("expected %v, got %v", expect, got)
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			t.Fatalf("expected %v, got %v", expect, got)
+		}
+	}
+
+	searches := []struct {
+		d      string
+		expect int
+	}{
+		{d: "-Infinity", expect: 2},
+		{d: "0.00", expect: 4},
+		{d: "1", expect: 6},
+		{d: "3.0", expect: 6},
+		{d: "1E+3", expect: 8},
+		{d: "Infinity", expect: 8},
+		{d: "sNaN", expect: 1},
+	}
+	for _, tc := range searches {
+		if i := SearchDecimals(x, newDecimal(t, testCtx, tc.d)); i != tc.expect {
+			t.Errorf("%s: expected %d, got %d", tc.d, tc.expect, i)
+		}
+	}
+	if i := SearchDecimals(x, nil); i != 0 {
+		t.Errorf("nil: expected 0, got %d", i)
+	}
+}
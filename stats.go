@@ -0,0 +1,150 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "github.com/pkg/errors"
+
+// statsGuardDigits is the number of extra digits of precision kept in the
+// running values of Stats.
+const statsGuardDigits = 5
+
+// Stats accumulates the count, mean and variance of a stream of decimals
+// in one pass, with Welford's method, so that no float64 rounding creeps
+// into monitoring or financial figures. The running mean and sum of
+// squared deviations are kept with a few digits beyond the precision of
+// the Stats' Context, and each result is rounded with that Context when it
+// is read.
+//
+// The conditions returned by the results are those raised while adding
+// values, such as InvalidOperation for a signaling NaN, together with
+// those of the result itself, checked against c.Traps. If Add fails, its
+// error is also returned by every later call.
+//
+// A Stats is not safe for concurrent use.
+type Stats struct {
+	c          *Context
+	nc         Context
+	n          int64
+	mean, m2   Decimal
+	count      Decimal
+	delta, tmp Decimal
+	res        Condition
+	err        error
+}
+
+// Stats returns an empty Stats whose results are rounded with c.
+func (c *Context) Stats() *Stats {
+	s := &Stats{c: c, nc: *c.baseContext(c.Precision + statsGuardDigits)}
+	s.nc.Rounding = RoundHalfEven
+	s.nc.Traps = 0
+	return s
+}
+
+// Add adds x to the values of s.
+func (s *Stats) Add(x *Decimal) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.c.Precision == 0 {
+		s.err = errors.New(errZeroPrecisionStr)
+		return s.err
+	}
+	s.n++
+	ed := MakeErrDecimal(&s.nc)
+	// mean += (x - mean) / n; m2 += (x - old mean) * (x - new mean)
+	ed.Sub(&s.delta, x, &s.mean)
+	ed.Quo(&s.tmp, &s.delta, s.count.SetInt64(s.n))
+	ed.Add(&s.mean, &s.mean, &s.tmp)
+	ed.Sub(&s.tmp, x, &s.mean)
+	ed.Mul(&s.tmp, &s.tmp, &s.delta)
+	ed.Add(&s.m2, &s.m2, &s.tmp)
+	s.err = ed.Err()
+	s.res |= ed.Flags
+	return s.err
+}
+
+// Count returns the number of values added to s.
+func (s *Stats) Count() int64 {
+	return s.n
+}
+
+// Mean sets d to the arithmetic mean of the values. The mean of no values
+// is NaN, as is 0/0, with the DivisionUndefined condition.
+func (s *Stats) Mean(d *Decimal) (Condition, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.n == 0 {
+		d.Set(decimalNaN)
+		return s.c.goError(DivisionUndefined)
+	}
+	return s.c.roundResult(d, &s.mean, s.res)
+}
+
+// Variance sets d to the population variance of the values, the mean of
+// their squared deviations from the mean. It is NaN with the
+// DivisionUndefined condition for no values.
+func (s *Stats) Variance(d *Decimal) (Condition, error) {
+	return s.spread(d, s.n, false)
+}
+
+// SampleVariance sets d to the sample variance of the values, which
+// divides their squared deviations by one less than their count. It is NaN
+// with the DivisionUndefined condition for fewer than two values.
+func (s *Stats) SampleVariance(d *Decimal) (Condition, error) {
+	return s.spread(d, s.n-1, false)
+}
+
+// StdDev sets d to the population standard deviation of the values, the
+// square root of Variance.
+func (s *Stats) StdDev(d *Decimal) (Condition, error) {
+	return s.spread(d, s.n, true)
+}
+
+// SampleStdDev sets d to the sample standard deviation of the values, the
+// square root of SampleVariance.
+func (s *Stats) SampleStdDev(d *Decimal) (Condition, error) {
+	return s.spread(d, s.n-1, true)
+}
+
+// spread sets d to m2/n, or its square root if sqrt is true.
+func (s *Stats) spread(d *Decimal, n int64, sqrt bool) (Condition, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if n <= 0 {
+		d.Set(decimalNaN)
+		return s.c.goError(s.res | DivisionUndefined)
+	}
+	ed := MakeErrDecimal(&s.nc)
+	var v, count Decimal
+	ed.Quo(&v, &s.m2, count.SetInt64(n))
+	if sqrt {
+		ed.Sqrt(&v, &v)
+	}
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	return s.c.roundResult(d, &v, s.res|ed.Flags)
+}
+
+// Reset removes all values from s, keeping its storage for reuse.
+func (s *Stats) Reset() {
+	s.n = 0
+	s.mean.SetFinite(0, 0)
+	s.m2.SetFinite(0, 0)
+	s.res = 0
+	s.err = nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		x              []string
+		mean           string
+		variance       string
+		stddev         string
+		sampleVariance string
+		sampleStdDev   string
+		res            Condition
+	}{
+		{
+			// The running mean is inexact at times, so the results have
+			// as many digits as the precision allows.
+			x:        []string{"2", "4", "4", "4", "5", "5", "7", "9"},
+			mean:     "5.000000000",
+			variance: "4.000000000", stddev: "2.0000000",
+			sampleVariance: "4.571428571", sampleStdDev: "2.138089935",
+		},
+		{
+			// Large values with small differences, where a float64
+			// computation drifts.
+			x: []string{
+				"1000000.1", "1000000.2", "1000000.3", "1000000.4", "1000000.5",
+				"1000000.6", "1000000.7", "1000000.8", "1000000.9", "1000001.0",
+			},
+			mean:     "1000000.55",
+			variance: "0.0825", stddev: "0.2872281323",
+			sampleVariance: "0.09166666667", sampleStdDev: "0.3027650354",
+		},
+		{
+			x:        []string{"1.50"},
+			mean:     "1.50",
+			variance: "0.0000", stddev: "0.00",
+			sampleVariance: "NaN", sampleStdDev: "NaN",
+		},
+		{
+			x:        []string{"1", "NaN", "2"},
+			mean:     "NaN",
+			variance: "NaN", stddev: "NaN",
+			sampleVariance: "NaN", sampleStdDev: "NaN",
+		},
+		{
+			x:        []string{"1", "Infinity"},
+			mean:     "Infinity",
+			variance: "NaN", stddev: "NaN",
+			sampleVariance: "NaN", sampleStdDev: "NaN",
+			res: InvalidOperation,
+		},
+		{
+			x:        []string{"sNaN"},
+			mean:     "NaN",
+			variance: "NaN", stddev: "NaN",
+			sampleVariance: "NaN", sampleStdDev: "NaN",
+			res: InvalidOperation,
+		},
+	}
+	c := BaseContext.WithPrecision(10)
+	c.Traps = 0
+	for _, tc := range tests {
+		s := c.Stats()
+		for _, x := range tc.x {
+			if err := s.Add(newDecimal(t, testCtx, x)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := s.Count(); n != int64(len(tc.x)) {
+			t.Errorf("%v: expected count %d, got %d", tc.x, len(tc.x), n)
+		}
+		for _, r := range []struct {
+			name   string
+			f      func(d *Decimal) (Condition, error)
+			expect string
+		}{
+			{"Mean", s.Mean, tc.mean},
+			{"Variance", s.Variance, tc.variance},
+			{"StdDev", s.StdDev, tc.stddev},
+			{"SampleVariance", s.SampleVariance, tc.sampleVariance},
+			{"SampleStdDev", s.SampleStdDev, tc.sampleStdDev},
+		} {
+			d := new(Decimal)
+			res, err := r.f(d)
+			if err != nil {
+				t.Fatalf("%v: %s: %+v", tc.x, r.name, err)
+			}
+			if got := d.String(); got != r.expect {
+				t.Errorf("%v: %s: expected %s, got %s", tc.x, r.name, r.expect, got)
+			}
+			if res&InvalidOperation != tc.res {
+				t.Errorf("%v: %s: expected %s, got %s", tc.x, r.name, tc.res, res)
+			}
+		}
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	c := BaseContext.WithPrecision(10)
+	s := c.Stats()
+	d := new(Decimal)
+	if _, err := s.Mean(d); err == nil {
+		t.Fatal("expected DivisionUndefined to be trapped")
+	}
+	c.Traps = 0
+	for _, f := range []func(d *Decimal) (Condition, error){s.Mean, s.Variance, s.StdDev, s.SampleVariance} {
+		res, err := f(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Form != NaN || res != DivisionUndefined {
+			t.Errorf("expected NaN (division undefined), got %s (%s)", d, res)
+		}
+	}
+
+	// Reset returns s to the empty state.
+	if err := s.Add(New(3, 0)); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset()
+	if err := s.Add(New(5, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Mean(d); err != nil || d.String() != "5" || s.Count() != 1 {
+		t.Fatalf("expected 5 after reset, got %s, %v", d, err)
+	}
+
+	s = BaseContext.Stats()
+	if err := s.Add(New(1, 0)); err == nil {
+		t.Fatal("expected error for zero precision")
+	}
+	if _, err := s.Mean(d); err == nil {
+		t.Fatal("expected the error of Add")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build apd_stringcache
+// +build apd_stringcache
+
+package apd
+
+import (
+	"math/big"
+	"sync/atomic"
+	"unsafe"
+)
+
+// stringCache holds the result of the last String call on a Decimal, so
+// that formatting an unchanged value again is a comparison instead of a
+// conversion. It is enabled by the apd_stringcache build tag, which adds a
+// pointer to every Decimal.
+//
+// Decimal's fields are exported and can be changed without going through a
+// method, so instead of being invalidated the cached string is stored with
+// the value it was made from and only used while that still matches.
+type stringCache struct {
+	p unsafe.Pointer // *cachedString
+}
+
+// cachedString is a String result and the value it was computed from. It
+// is never modified once stored, so it can be shared by copies of a Decimal
+// and read concurrently.
+type cachedString struct {
+	s        string
+	form     Form
+	negative bool
+	exponent int32
+	coeff    []big.Word
+}
+
+// loadString returns the cached String result for d, if it is still valid.
+func (d *Decimal) loadString() (string, bool) {
+	c := (*cachedString)(atomic.LoadPointer(&d.cache.p))
+	if c == nil || c.form != d.Form || c.negative != d.Negative ||
+		c.exponent != d.Exponent {
+		return "", false
+	}
+	bits := d.Coeff.Bits()
+	if len(bits) != len(c.coeff) {
+		return "", false
+	}
+	for i, w := range bits {
+		if w != c.coeff[i] {
+			return "", false
+		}
+	}
+	return c.s, true
+}
+
+// storeString caches s as the String result for d's current value.
+func (d *Decimal) storeString(s string) {
+	c := &cachedString{
+		s:        s,
+		form:     d.Form,
+		negative: d.Negative,
+		exponent: d.Exponent,
+		coeff:    append([]big.Word(nil), d.Coeff.Bits()...),
+	}
+	atomic.StorePointer(&d.cache.p, unsafe.Pointer(c))
+}
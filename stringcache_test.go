@@ -0,0 +1,31 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build apd_stringcache
+// +build apd_stringcache
+
+package apd
+
+import "testing"
+
+func TestStringCacheHit(t *testing.T) {
+	d := newDecimal(t, testCtx, "-1234567890123456789012345678901234567890E-20")
+	s := d.String()
+	if n := testing.AllocsPerRun(100, func() { _ = d.String() }); n != 0 {
+		t.Errorf("%v allocations", n)
+	}
+	if got := d.String(); got != s {
+		t.Errorf("expected %s, got %s", s, got)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import "math/big"
+
+// Sum accumulates the exact sum of decimals, so that a total of many
+// rounded inputs, such as the entries of a ledger, is rounded only once
+// when it is read with Round, and is the correctly rounded total. The
+// coefficient of the sum grows as needed to hold every digit of the
+// inputs. The zero value is an empty sum, which is 0.
+//
+// A Sum is not safe for concurrent use.
+type Sum struct {
+	sum Decimal
+	n   int
+	// res holds the conditions raised by the additions, for Round.
+	res Condition
+	tmp big.Int
+}
+
+// Add adds x to s. An error is returned only if the sum would exceed the
+// package's exponent limits, in which case s is unchanged.
+func (s *Sum) Add(x *Decimal) error {
+	return s.add(x, false)
+}
+
+// Sub subtracts x from s. Errors are as for Add.
+func (s *Sum) Sub(x *Decimal) error {
+	return s.add(x, true)
+}
+
+func (s *Sum) add(x *Decimal, subtract bool) error {
+	if s.n == 0 {
+		// Start from x rather than 0 so that a sum of one element keeps its
+		// exponent and sign, as in Context.Sum.
+		set, res, _ := exactContext.setIfNaN(&s.sum, x)
+		if !set {
+			s.sum.Set(x)
+			if subtract {
+				s.sum.Negative = !s.sum.Negative
+			}
+		}
+		s.res |= res
+		s.n++
+		return nil
+	}
+	res, err := exactContext.addTmp(&s.sum, &s.sum, x, subtract, &s.tmp)
+	if err != nil {
+		return err
+	}
+	s.res |= res
+	s.n++
+	return nil
+}
+
+// Len returns the number of values added to or subtracted from s since it
+// was created or last reset.
+func (s *Sum) Len() int {
+	return s.n
+}
+
+// Round sets d to the sum rounded with c. It returns the conditions raised
+// by the additions, such as InvalidOperation for the sum of infinities of
+// opposite signs, together with those of the rounding, checked against
+// c.Traps. s is not changed, so more values may be added afterward.
+func (s *Sum) Round(c *Context, d *Decimal) (Condition, error) {
+	return c.roundResult(d, &s.sum, s.res)
+}
+
+// Reset empties s, keeping its storage for reuse.
+func (s *Sum) Reset() {
+	s.sum.SetFinite(0, 0)
+	s.n = 0
+	s.res = 0
+}
@@ -0,0 +1,129 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	c := BaseContext.WithPrecision(5)
+	c.Traps = 0
+
+	// One million rounded inputs of 0.4 each, on top of a large first
+	// entry: a running total at precision 5 never moves from 10000.
+	var s Sum
+	running := New(10000, 0)
+	if err := s.Add(running); err != nil {
+		t.Fatal(err)
+	}
+	x := New(4, -1)
+	for i := 0; i < 1000000; i++ {
+		if err := s.Add(x); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Add(running, running, x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := new(Decimal)
+	res, err := s.Round(c, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := d.String(); s != "4.1000E+5" || res != Rounded {
+		t.Fatalf("expected 4.1000E+5 (rounded), got %s (%s)", s, res)
+	}
+	if s := running.String(); s != "10000" {
+		t.Fatalf("expected the running total to stay at 10000, got %s", s)
+	}
+	if n := s.Len(); n != 1000001 {
+		t.Fatalf("expected 1000001 values, got %d", n)
+	}
+
+	tests := []struct {
+		add, sub []string
+		expect   string
+		res      Condition
+	}{
+		{expect: "0"},
+		{add: []string{"1E+3"}, expect: "1E+3"},
+		{add: []string{"-0"}, expect: "-0"},
+		{sub: []string{"0"}, expect: "-0"},
+		{add: []string{"1.50", "2.5"}, sub: []string{"4"}, expect: "0.00"},
+		{add: []string{"123456", "0.5"}, expect: "1.2346E+5", res: Inexact | Rounded},
+		{add: []string{"Infinity", "1"}, expect: "Infinity"},
+		{add: []string{"Infinity"}, sub: []string{"Infinity"}, expect: "NaN", res: InvalidOperation},
+		{add: []string{"1", "sNaN", "2"}, expect: "NaN", res: InvalidOperation},
+		{add: []string{"sNaN"}, expect: "NaN", res: InvalidOperation},
+	}
+	for _, tc := range tests {
+		s.Reset()
+		for _, v := range tc.add {
+			if err := s.Add(newDecimal(t, testCtx, v)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, v := range tc.sub {
+			if err := s.Sub(newDecimal(t, testCtx, v)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		res, err := s.Round(c, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.String(); got != tc.expect || res != tc.res {
+			t.Errorf("%v - %v: expected %s (%s), got %s (%s)", tc.add, tc.sub, tc.expect, tc.res, got, res)
+		}
+	}
+
+	c.Traps = InvalidOperation
+	s.Reset()
+	_ = s.Add(newDecimal(t, testCtx, "Infinity"))
+	_ = s.Add(newDecimal(t, testCtx, "-Infinity"))
+	if _, err := s.Round(c, d); err == nil {
+		t.Fatal("expected InvalidOperation to be trapped")
+	}
+}
+
+// TestSumMatchesSumSlice verifies that Sum agrees with the exact SumSlice
+// on random inputs of varying magnitude.
+func TestSumMatchesSumSlice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	x := make([]Decimal, 1000)
+	var s Sum
+	for i := range x {
+		x[i].SetFinite(rng.Int63n(1e12)-5e11, int32(rng.Intn(20)-10))
+		if err := s.Add(&x[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var expect, got Decimal
+	expectRes, err := c.SumSlice(&expect, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.Round(c, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CmpTotal(&expect) != 0 || res != expectRes {
+		t.Fatalf("expected %s (%s), got %s (%s)", &expect, expectRes, &got, res)
+	}
+}
@@ -14,7 +14,10 @@
 
 package apd
 
-import "math/big"
+import (
+	"math/big"
+	"sync/atomic"
+)
 
 // digitsLookupTable is used to map binary digit counts to their corresponding
 // decimal border values. The map relies on the proof that (without leading zeros)
@@ -55,6 +58,9 @@ func init() {
 
 // NumDigits returns the number of decimal digits of d.Coeff.
 func (d *Decimal) NumDigits() int64 {
+	if d == nil {
+		panic(&NilDecimalError{Method: "NumDigits"})
+	}
 	return NumDigits(&d.Coeff)
 }
 
@@ -89,15 +95,10 @@ func NumDigits(b *big.Int) int64 {
 		return val.digits + 1
 	}
 
+	// b has n or n+1 digits. Powers of ten up to pow10CacheSize are cached,
+	// so the comparison doesn't allocate for those sizes.
 	n := int64(float64(bl) / digitsToBitsRatio)
-	a := new(big.Int)
-	e := tableExp10(n, a)
-	if b.Sign() < 0 {
-		a.Abs(b)
-	} else {
-		a = b
-	}
-	if a.Cmp(e) >= 0 {
+	if b.CmpAbs(tableExp10(n, nil)) >= 0 {
 		n++
 	}
 	return n
@@ -125,6 +126,18 @@ func setBigWithPow(bi *big.Int, tmpInt *big.Int, pow int64) {
 	bi.Exp(bigTen, tmpInt.SetInt64(pow), nil)
 }
 
+// pow10CacheSize is the magnitude of the maximum power of 10 exponent that is
+// kept in pow10Cache. Powers above powerTenTableSize and up to this size are
+// computed on first use and shared by all later callers; larger ones are
+// computed on every call.
+const pow10CacheSize = 1024
+
+// pow10Cache holds lazily computed *big.Int powers of 10, indexed by exponent
+// minus powerTenTableSize+1. Racing goroutines may each compute and store the
+// same entry, but stored values are never mutated, so the cache is safe for
+// concurrent use.
+var pow10Cache [pow10CacheSize - powerTenTableSize]atomic.Value
+
 // tableExp10 returns 10^x for x >= 0, looked up from a table when
 // possible. This returned value must not be mutated. tmp is used as an
 // intermediate variable, but may be nil.
@@ -132,6 +145,16 @@ func tableExp10(x int64, tmp *big.Int) *big.Int {
 	if x <= powerTenTableSize {
 		return &pow10LookupTable[x]
 	}
+	if x <= pow10CacheSize {
+		v := &pow10Cache[x-powerTenTableSize-1]
+		if b, ok := v.Load().(*big.Int); ok {
+			return b
+		}
+		b := new(big.Int)
+		setBigWithPow(b, tmp, x)
+		v.Store(b)
+		return b
+	}
 	b := new(big.Int)
 	setBigWithPow(b, tmp, x)
 	return b
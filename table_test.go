@@ -19,6 +19,7 @@ import (
 	"math/big"
 	"math/rand"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -90,6 +91,22 @@ func TestNumDigits(t *testing.T) {
 	runTest("-1", '0')
 }
 
+func TestNumDigitsAllocs(t *testing.T) {
+	for _, digits := range []int{1, 19, 39, 40, 100, 500, pow10CacheSize} {
+		b := new(big.Int).Set(tableExp10(int64(digits), nil))
+		b.Sub(b, bigOne)
+		neg := new(big.Int).Neg(b)
+		for _, x := range []*big.Int{b, neg} {
+			if n := NumDigits(x); n != int64(digits) {
+				t.Errorf("%d: got %d digits", digits, n)
+			}
+			if n := testing.AllocsPerRun(100, func() { _ = NumDigits(x) }); n != 0 {
+				t.Errorf("%d: %v allocations", digits, n)
+			}
+		}
+	}
+}
+
 func TestDigitsLookupTable(t *testing.T) {
 	// Make sure all elements in table make sense.
 	min := new(big.Int)
@@ -165,3 +182,29 @@ func TestTableExp10(t *testing.T) {
 		}
 	}
 }
+
+func TestTableExp10Cache(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for x := int64(powerTenTableSize - 2); x <= pow10CacheSize+2; x++ {
+				_ = tableExp10(x, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, x := range []int64{powerTenTableSize, powerTenTableSize + 1, 500, pow10CacheSize, pow10CacheSize + 1} {
+		expect := "1" + strings.Repeat("0", int(x))
+		if s := tableExp10(x, nil).String(); s != expect {
+			t.Errorf("%d: got %s", x, s)
+		}
+	}
+	if tableExp10(500, nil) != tableExp10(500, nil) {
+		t.Error("expected cached value to be shared")
+	}
+	if n := testing.AllocsPerRun(100, func() { _ = tableExp10(pow10CacheSize, nil) }); n != 0 {
+		t.Errorf("%v allocations", n)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// trigGuardDigits is the number of extra digits of precision used while
+// computing trigonometric functions.
+const trigGuardDigits = 5
+
+var (
+	decimal45  = New(45, 0)
+	decimal90  = New(90, 0)
+	decimal180 = New(180, 0)
+)
+
+// maxReducePrecision is the largest working precision at which Sin, Cos and
+// SinCos reduce their argument modulo pi/2. It is Quo's precision limit.
+const maxReducePrecision = 5000
+
+// Sin sets d to the sine of x, which is in radians. Reducing x modulo pi/2
+// needs about c.Precision plus the number of integer digits of x digits of
+// pi. If that exceeds 5000, as for x = 1E+5000, or if x is so close to a
+// multiple of pi/2 that the reduction needs more, d is set to NaN and an
+// error is returned.
+func (c *Context) Sin(d, x *Decimal) (Condition, error) {
+	return c.sinCos(d, nil, x)
+}
+
+// Cos sets d to the cosine of x, which is in radians. x is limited as for
+// Sin.
+func (c *Context) Cos(d, x *Decimal) (Condition, error) {
+	return c.sinCos(nil, d, x)
+}
+
+// SinCos sets sin to the sine and cos to the cosine of x, which is in
+// radians. It is faster than calling Sin and Cos separately because the
+// argument is only reduced once. x is limited as for Sin.
+func (c *Context) SinCos(sin, cos, x *Decimal) (Condition, error) {
+	return c.sinCos(sin, cos, x)
+}
+
+// SinDeg sets d to the sine of x, which is in degrees. x is reduced exactly
+// modulo 90 before it is converted to radians, so, for example, SinDeg of
+// 180 is exactly 0 even though Sin of a rounded pi is not.
+func (c *Context) SinDeg(d, x *Decimal) (Condition, error) {
+	return c.sinCosDeg(d, nil, x)
+}
+
+// CosDeg sets d to the cosine of x, which is in degrees. As with SinDeg, x is
+// reduced exactly before it is converted to radians.
+func (c *Context) CosDeg(d, x *Decimal) (Condition, error) {
+	return c.sinCosDeg(nil, d, x)
+}
+
+// DegToRad sets d to x converted from degrees to radians.
+func (c *Context) DegToRad(d, x *Decimal) (Condition, error) {
+	return c.convertAngle(d, x, false)
+}
+
+// RadToDeg sets d to x converted from radians to degrees.
+func (c *Context) RadToDeg(d, x *Decimal) (Condition, error) {
+	return c.convertAngle(d, x, true)
+}
+
+// convertAngle sets d to x*pi/180, or x*180/pi if toDeg is set.
+func (c *Context) convertAngle(d, x *Decimal, toDeg bool) (Condition, error) {
+	if set, res, err := c.setIfNaN(d, x); set {
+		return res, err
+	}
+	if x.Form == Infinite || x.IsZero() {
+		d.Set(x)
+		return 0, nil
+	}
+	if c.Precision == 0 {
+		return 0, errors.New(errZeroPrecisionStr)
+	}
+	nc := c.baseContext(c.Precision + trigGuardDigits)
+	nc.Rounding = RoundHalfEven
+	z := new(Decimal)
+	pi := decimalPi.get(nc.Precision)
+	ed := MakeErrDecimal(nc)
+	if toDeg {
+		ed.Mul(z, x, decimal180)
+		ed.Quo(z, z, pi)
+	} else {
+		ed.Mul(z, x, pi)
+		ed.Quo(z, z, decimal180)
+	}
+	if err := ed.Err(); err != nil {
+		return 0, err
+	}
+	res := c.round(d, z)
+	res |= Inexact | Rounded
+	return c.goError(res)
+}
+
+// sinCos implements Sin, Cos and SinCos. Either of sin and cos may be nil, in
+// which case that result is not computed.
+func (c *Context) sinCos(sin, cos, x *Decimal) (Condition, error) {
+	if set, res, err := c.sinCosSpecials(sin, cos, x); set {
+		return res, err
+	}
+	r, quadrant, err := c.reduceHalfPi(x)
+	if err != nil {
+		for _, d := range []*Decimal{sin, cos} {
+			if d != nil {
+				d.Set(decimalNaN)
+			}
+		}
+		return 0, err
+	}
+	return c.sinCosQuadrant(sin, cos, r, quadrant)
+}
+
+// sinCosDeg is like sinCos, but for x in degrees.
+func (c *Context) sinCosDeg(sin, cos, x *Decimal) (Condition, error) {
+	if set, res, err := c.sinCosSpecials(sin, cos, x); set {
+		return res, err
+	}
+	// x = q*90 + r exactly, with |r| <= 45.
+	a, b, s, err := upscale(x, decimal90)
+	if err != nil {
+		return 0, errors.Wrap(err, "sinCosDeg")
+	}
+	q := new(big.Int)
+	r := new(Decimal)
+	q.QuoRem(a, b, &r.Coeff)
+	r.Exponent = s
+	r.Negative = x.Negative && r.Coeff.Sign() != 0
+	if x.Negative {
+		q.Neg(q)
+	}
+	if new(Decimal).Abs(r).Cmp(decimal45) > 0 {
+		if r.Negative {
+			_, err = BaseContext.Add(r, r, decimal90)
+			q.Sub(q, bigOne)
+		} else {
+			_, err = BaseContext.Sub(r, r, decimal90)
+			q.Add(q, bigOne)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	quadrant := int(new(big.Int).And(q, big.NewInt(3)).Int64())
+
+	if !r.IsZero() {
+		nc := c.baseContext(c.Precision + trigGuardDigits)
+		nc.Rounding = RoundHalfEven
+		if _, err := nc.DegToRad(r, r); err != nil {
+			return 0, err
+		}
+	}
+	return c.sinCosQuadrant(sin, cos, r, quadrant)
+}
+
+// sinCosSpecials handles NaN, infinite and zero arguments for sinCos and
+// sinCosDeg, along with zero precision.
+func (c *Context) sinCosSpecials(sin, cos, x *Decimal) (bool, Condition, error) {
+	if sin != nil {
+		if set, res, err := c.setIfNaN(sin, x); set {
+			if cos != nil {
+				cos.Set(sin)
+			}
+			return set, res, err
+		}
+	} else if set, res, err := c.setIfNaN(cos, x); set {
+		return set, res, err
+	}
+	if x.Form == Infinite {
+		for _, d := range []*Decimal{sin, cos} {
+			if d != nil {
+				d.Set(decimalNaN)
+			}
+		}
+		res, err := c.goError(InvalidOperation)
+		return true, res, err
+	}
+	if x.IsZero() {
+		if sin != nil {
+			sin.Set(x)
+		}
+		if cos != nil {
+			cos.SetInt64(1)
+		}
+		return true, 0, nil
+	}
+	if c.Precision == 0 {
+		return true, 0, errors.New(errZeroPrecisionStr)
+	}
+	return false, 0, nil
+}
+
+// sinCosQuadrant sets sin and cos, either of which may be nil, to the sine
+// and cosine of q*pi/2 + r, where quadrant is q mod 4 and r is in radians.
+// If r is exactly zero, so are the results.
+func (c *Context) sinCosQuadrant(sin, cos, r *Decimal, quadrant int) (Condition, error) {
+	nc := c.baseContext(c.Precision + trigGuardDigits)
+	nc.Rounding = RoundHalfEven
+	// sin(x) and cos(x) are +-sin(r) or +-cos(r) depending on q.
+	var s, co *Decimal
+	odd := quadrant%2 == 1
+	if (sin != nil && !odd) || (cos != nil && odd) {
+		s = new(Decimal)
+		if err := nc.sinSeries(s, r); err != nil {
+			return 0, err
+		}
+	}
+	if (sin != nil && odd) || (cos != nil && !odd) {
+		co = new(Decimal)
+		if err := nc.cosSeries(co, r); err != nil {
+			return 0, err
+		}
+	}
+	// The sign is applied before rounding so that RoundCeiling and
+	// RoundFloor round in the right direction. s and co may be used for
+	// both results, so they are negated into a copy.
+	signed := func(z *Decimal, neg bool) *Decimal {
+		if !neg || z.IsZero() {
+			return z
+		}
+		return new(Decimal).Neg(z)
+	}
+	var res Condition
+	if sin != nil {
+		z := [4]*Decimal{s, co, s, co}[quadrant]
+		res |= c.round(sin, signed(z, quadrant >= 2))
+	}
+	if cos != nil {
+		z := [4]*Decimal{co, s, co, s}[quadrant]
+		res |= c.round(cos, signed(z, quadrant == 1 || quadrant == 2))
+	}
+	if !r.IsZero() {
+		res |= Inexact | Rounded
+	}
+	return c.goError(res)
+}
+
+// reduceHalfPi returns r and q mod 4 such that x = q*pi/2 + r, where q is an
+// integer and |r| <= pi/4, or r = x if |x| < 1. r has c.Precision+trigGuardDigits correct digits:
+// the precision of the reduction is raised as needed to make up for the
+// digits that cancel when x is close to a multiple of pi/2.
+func (c *Context) reduceHalfPi(x *Decimal) (r *Decimal, quadrant int, err error) {
+	r = new(Decimal)
+	// The series converge quickly enough for |x| < 1 without reduction.
+	if adjustedExponent(x) < 0 {
+		r.Set(x)
+		return r, 0, nil
+	}
+	want := int64(c.Precision) + trigGuardDigits
+	ax := adjustedExponent(x)
+	workp := want + ax + 1
+	q := new(Decimal)
+	halfPi := new(Decimal)
+	for {
+		if workp > maxReducePrecision {
+			return nil, 0, errors.Errorf("argument %s too large to reduce at precision %d", x, c.Precision)
+		}
+		nc := c.baseContext(uint32(workp))
+		nc.Rounding = RoundHalfEven
+		ed := MakeErrDecimal(nc)
+		ed.Mul(halfPi, decimalPi.get(nc.Precision), decimalHalf)
+		ed.Quo(q, x, halfPi)
+		ed.RoundToIntegralValue(q, q)
+		ed.Mul(r, q, halfPi)
+		ed.Sub(r, x, r)
+		if err := ed.Err(); err != nil {
+			return nil, 0, err
+		}
+		// r's absolute error is around 10**(ax-workp), so the number of
+		// correct digits is the gap between that and r's magnitude.
+		if !r.IsZero() {
+			if lost := want - (adjustedExponent(r) - (ax - workp)); lost <= 0 {
+				break
+			} else {
+				workp += lost
+			}
+		} else {
+			workp *= 2
+		}
+	}
+	qb, err := integerValue(new(big.Int), q)
+	if err != nil {
+		return nil, 0, err
+	}
+	quadrant = int(qb.Bit(0) + 2*qb.Bit(1))
+	if q.Negative {
+		quadrant = (4 - quadrant) % 4
+	}
+	return r, quadrant, nil
+}
+
+// sinSeries sets d to sin(x) using the power series
+//
+//   sin(x) = x - x**3/3! + x**5/5! - ...
+//
+// which converges rapidly for |x| <= pi/2.
+func (c *Context) sinSeries(d, x *Decimal) error {
+	// See the comment in sinhSeries.
+	if 2*adjustedExponent(x) < -int64(c.Precision)-1 {
+		d.Set(x)
+		return nil
+	}
+	ed := MakeErrDecimal(c)
+	x2 := new(Decimal)
+	term := new(Decimal).Set(x)
+	sum := new(Decimal).Set(x)
+	n := new(Decimal)
+	ed.Mul(x2, x, x)
+	ed.Neg(x2, x2)
+	for k := int64(1); ; k++ {
+		// term *= -x**2 / ((2k) * (2k+1))
+		n.SetInt64((2 * k) * (2*k + 1))
+		ed.Mul(term, term, x2)
+		ed.Quo(term, term, n)
+		ed.Add(sum, sum, term)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+		if term.IsZero() || adjustedExponent(term) < adjustedExponent(sum)-int64(c.Precision)-1 {
+			break
+		}
+	}
+	d.Set(sum)
+	return nil
+}
+
+// cosSeries sets d to cos(x) using the power series
+//
+//   cos(x) = 1 - x**2/2! + x**4/4! - ...
+//
+// which converges rapidly for |x| <= pi/2.
+func (c *Context) cosSeries(d, x *Decimal) error {
+	ed := MakeErrDecimal(c)
+	// As in sinhSeries, x**2/2 can't affect the result if x is tiny, and
+	// x**2 could underflow. The result is then 1 minus a tiny amount, as in
+	// Tanh, so that it is rounded correctly under every rounding mode.
+	if 2*adjustedExponent(x) < -int64(c.Precision)-1 {
+		d.SetFinite(1, 0)
+		ed.Sub(d, d, New(1, -int32(c.Precision)))
+		return ed.Err()
+	}
+	x2 := new(Decimal)
+	term := New(1, 0)
+	sum := New(1, 0)
+	n := new(Decimal)
+	ed.Mul(x2, x, x)
+	ed.Neg(x2, x2)
+	for k := int64(1); ; k++ {
+		// term *= -x**2 / ((2k-1) * (2k))
+		n.SetInt64((2*k - 1) * (2 * k))
+		ed.Mul(term, term, x2)
+		ed.Quo(term, term, n)
+		ed.Add(sum, sum, term)
+		if err := ed.Err(); err != nil {
+			return err
+		}
+		if term.IsZero() || adjustedExponent(term) < adjustedExponent(sum)-int64(c.Precision)-1 {
+			break
+		}
+	}
+	d.Set(sum)
+	return nil
+}
@@ -0,0 +1,234 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package apd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSinCos(t *testing.T) {
+	tests := []struct {
+		x        string
+		sin, cos string
+	}{
+		{x: "0", sin: "0", cos: "1"},
+		{x: "-0", sin: "-0", cos: "1"},
+		{x: "1E-10", sin: "1.000000000000000E-10", cos: "1.000000000000000"},
+		{x: "-1E-60000", sin: "-1.000000000000000E-60000", cos: "1.000000000000000"},
+		{x: "0.5", sin: "0.4794255386042030", cos: "0.8775825618903727"},
+		{x: "0.8", sin: "0.7173560908995228", cos: "0.6967067093471654"},
+		{x: "1", sin: "0.8414709848078965", cos: "0.5403023058681397"},
+		{x: "-1", sin: "-0.8414709848078965", cos: "0.5403023058681397"},
+		{x: "1.5707963267948966", sin: "1.000000000000000", cos: "1.923132169163975E-17"},
+		{x: "3.14159265358979323846", sin: "2.643383279502884E-21", cos: "-1.000000000000000"},
+		{x: "10", sin: "-0.5440211108893698", cos: "-0.8390715290764525"},
+		{x: "-100", sin: "0.5063656411097588", cos: "0.8623188722876839"},
+		{x: "355", sin: "-0.00003014435335948845", cos: "-0.9999999995456590"},
+		{x: "710", sin: "0.00006028870669158527", cos: "0.9999999981826359"},
+		{x: "1E+20", sin: "-0.6452512852657808", cos: "0.7639704044417283"},
+		{x: "1E+100", sin: "-0.3723761236612767", cos: "-0.9280819050746553"},
+		{x: "Infinity", sin: "NaN", cos: "NaN"},
+		{x: "NaN", sin: "NaN", cos: "NaN"},
+	}
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			check := func(name string, d *Decimal, expect string) {
+				t.Helper()
+				e := newDecimal(t, testCtx, expect)
+				if d.CmpTotal(e) != 0 && (d.Cmp(e) != 0 || d.Negative != e.Negative) {
+					t.Errorf("%s: expected %s, got %s", name, e, d)
+				}
+			}
+			sin, cos := new(Decimal), new(Decimal)
+			if _, err := c.Sin(sin, x); err != nil {
+				t.Fatal(err)
+			}
+			check("sin", sin, tc.sin)
+			if _, err := c.Cos(cos, x); err != nil {
+				t.Fatal(err)
+			}
+			check("cos", cos, tc.cos)
+			if _, err := c.SinCos(sin, cos, x); err != nil {
+				t.Fatal(err)
+			}
+			check("sincos: sin", sin, tc.sin)
+			check("sincos: cos", cos, tc.cos)
+		})
+	}
+}
+
+func TestSinCosRounding(t *testing.T) {
+	// sin(4) = -0.75680..., cos(2) = -0.41614... and cos(1E-60000) is just
+	// below 1.
+	tests := []struct {
+		x        string
+		rounding string
+		sin, cos string
+	}{
+		{x: "4", rounding: RoundCeiling, sin: "-0.7568", cos: "-0.6536"},
+		{x: "4", rounding: RoundFloor, sin: "-0.7569", cos: "-0.6537"},
+		{x: "2", rounding: RoundCeiling, sin: "0.9093", cos: "-0.4161"},
+		{x: "2", rounding: RoundFloor, sin: "0.9092", cos: "-0.4162"},
+		{x: "-2", rounding: RoundCeiling, sin: "-0.9092", cos: "-0.4161"},
+		{x: "1E-60000", rounding: RoundFloor, cos: "0.9999"},
+		{x: "1E-60000", rounding: RoundCeiling, sin: "1E-60000", cos: "1.000"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.x+"/"+tc.rounding, func(t *testing.T) {
+			c := BaseContext.WithPrecision(4)
+			c.Rounding = tc.rounding
+			sin, cos := new(Decimal), new(Decimal)
+			res, err := c.SinCos(sin, cos, newDecimal(t, testCtx, tc.x))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := sin.String(); tc.sin != "" && s != tc.sin {
+				t.Errorf("sin: expected %s, got %s", tc.sin, s)
+			}
+			if s := cos.String(); s != tc.cos {
+				t.Errorf("cos: expected %s, got %s", tc.cos, s)
+			}
+			if !res.Inexact() {
+				t.Errorf("expected inexact, got %s", res)
+			}
+		})
+	}
+}
+
+func TestSinCosLargeArgument(t *testing.T) {
+	c := BaseContext.WithPrecision(10)
+	// The reduction needs about 4900 digits of pi, which is within the limit.
+	d := new(Decimal)
+	if _, err := c.Sin(d, New(1, 4900)); err != nil {
+		t.Fatal(err)
+	}
+	if d.Form != Finite || new(Decimal).Abs(d).Cmp(decimalOne) > 0 {
+		t.Fatalf("unexpected sin(1E+4900) = %s", d)
+	}
+
+	x := New(1, 20000)
+	sin, cos := New(5, 0), New(5, 0)
+	if _, err := c.SinCos(sin, cos, x); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("expected argument error, got %v", err)
+	}
+	if sin.Form != NaN || cos.Form != NaN {
+		t.Fatalf("expected NaN results, got %s, %s", sin, cos)
+	}
+	d.SetInt64(5)
+	if _, err := c.Cos(d, x); err == nil || d.Form != NaN {
+		t.Fatalf("expected NaN with error, got %s, %v", d, err)
+	}
+}
+
+func TestSinCosDeg(t *testing.T) {
+	tests := []struct {
+		x        string
+		sin, cos string
+		exact    bool
+	}{
+		{x: "0", sin: "0", cos: "1", exact: true},
+		{x: "90", sin: "1", cos: "0", exact: true},
+		{x: "180", sin: "0", cos: "-1", exact: true},
+		{x: "-270", sin: "1", cos: "0", exact: true},
+		{x: "3.6E+3", sin: "0", cos: "1", exact: true},
+		{x: "30", sin: "0.5000000000000000", cos: "0.8660254037844386"},
+		{x: "-30", sin: "-0.5000000000000000", cos: "0.8660254037844386"},
+		{x: "45", sin: "0.7071067811865475", cos: "0.7071067811865475"},
+		{x: "60", sin: "0.8660254037844386", cos: "0.5000000000000000"},
+		{x: "405", sin: "0.7071067811865475", cos: "0.7071067811865475"},
+		{x: "-765", sin: "-0.7071067811865475", cos: "0.7071067811865475"},
+		{x: "0.001", sin: "0.00001745329251905720", cos: "0.9999999998476913"},
+		{x: "123.456", sin: "0.8343094333148066", cos: "-0.5512964442855824"},
+		{x: "1E+20", sin: "-0.9848077530122081", cos: "0.1736481776669303"},
+		{x: "Infinity", sin: "NaN", cos: "NaN", exact: true},
+	}
+	c := BaseContext.WithPrecision(16)
+	c.Traps = 0
+	for _, tc := range tests {
+		t.Run(tc.x, func(t *testing.T) {
+			x := newDecimal(t, testCtx, tc.x)
+			for _, op := range []struct {
+				name   string
+				f      func(d, x *Decimal) (Condition, error)
+				expect string
+			}{
+				{"sin", c.SinDeg, tc.sin},
+				{"cos", c.CosDeg, tc.cos},
+			} {
+				d := new(Decimal)
+				res, err := op.f(d, x)
+				if err != nil {
+					t.Fatalf("%s: %+v", op.name, err)
+				}
+				if s := d.String(); s != op.expect {
+					t.Errorf("%s: expected %s, got %s", op.name, op.expect, s)
+				}
+				if tc.exact == res.Inexact() {
+					t.Errorf("%s: unexpected flags %s", op.name, res)
+				}
+			}
+		})
+	}
+}
+
+func TestDegToRad(t *testing.T) {
+	tests := []struct {
+		deg, rad string
+	}{
+		{deg: "0", rad: "0"},
+		{deg: "1", rad: "0.01745329251994330"},
+		{deg: "-30", rad: "-0.5235987755982989"},
+		{deg: "60", rad: "1.047197551196598"},
+		{deg: "1E+20", rad: "1.745329251994330E+18"},
+		{deg: "Infinity", rad: "Infinity"},
+	}
+	c := BaseContext.WithPrecision(16)
+	for _, tc := range tests {
+		t.Run(tc.deg, func(t *testing.T) {
+			d := new(Decimal)
+			if _, err := c.DegToRad(d, newDecimal(t, testCtx, tc.deg)); err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.rad {
+				t.Errorf("expected %s, got %s", tc.rad, s)
+			}
+		})
+	}
+
+	tests = []struct {
+		deg, rad string
+	}{
+		{rad: "0", deg: "0"},
+		{rad: "1", deg: "57.29577951308232"},
+		{rad: "-0.001", deg: "-0.05729577951308232"},
+		{rad: "123.456", deg: "7073.507755567091"},
+		{rad: "-Infinity", deg: "-Infinity"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.rad, func(t *testing.T) {
+			d := new(Decimal)
+			if _, err := c.RadToDeg(d, newDecimal(t, testCtx, tc.rad)); err != nil {
+				t.Fatal(err)
+			}
+			if s := d.String(); s != tc.deg {
+				t.Errorf("expected %s, got %s", tc.deg, s)
+			}
+		})
+	}
+}